@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// reachTimeout 检查服务连通性的超时时间
+const reachTimeout = 5 * time.Second
+
+// checkConfig 校验配置文件, 存在错误时返回 1, 只有警告时返回 0
+func checkConfig(path string) int {
+	fmt.Printf("检查配置文件: %s\n\n", path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fail("读取配置文件失败: %v", err)
+		return 1
+	}
+
+	// 1 初始化所有配置项
+	c, err := config.Load(path)
+	if err != nil {
+		fail("%v", err)
+		return 1
+	}
+	pass("所有配置项校验通过")
+
+	// 2 未知配置项
+	unknown, err := config.UnknownKeys(data)
+	if err != nil {
+		fail("%v", err)
+		return 1
+	}
+	if len(unknown) == 0 {
+		pass("没有未知的配置项")
+	}
+	for _, key := range unknown {
+		warn("未知的配置项, 不会生效, 请检查拼写: %s", key)
+	}
+
+	// 3 路径映射重叠
+	overlaps := c.Emby.Strm.OverlappingMappings()
+	if len(overlaps) == 0 {
+		pass("CDN 路径映射没有重叠")
+	}
+	for _, o := range overlaps {
+		warn("路径映射重叠: %s", o)
+	}
+
+	// 4 服务连通性
+	warnings := len(unknown) + len(overlaps)
+	if !checkReachable("Emby", c.Emby.Host, "/emby/System/Info/Public") {
+		warnings++
+	}
+	if strings.TrimSpace(c.Openlist.Host) != "" && !checkReachable("OpenList", c.Openlist.Host, "/ping") {
+		warnings++
+	}
+
+	fmt.Println()
+	if warnings > 0 {
+		fmt.Println(colors.ToYellow("配置可以使用, 但存在警告"))
+	} else {
+		fmt.Println(colors.ToGreen("配置检查完成"))
+	}
+	return 0
+}
+
+// checkReachable 检查服务是否可以访问, 能收到任意 http 响应即视为可访问
+func checkReachable(name, host, uri string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), reachTimeout)
+	defer cancel()
+
+	url := strings.TrimSuffix(host, "/") + uri
+	resp, err := https.Get(url).Context(ctx).Do()
+	if err != nil {
+		warn("%s 无法访问 [%s]: %v", name, host, err)
+		return false
+	}
+	resp.Body.Close()
+	pass("%s 可以访问 [%s], 响应码: %d", name, host, resp.StatusCode)
+	return true
+}
+
+// pass 输出检查通过信息
+func pass(format string, v ...any) {
+	fmt.Println(colors.ToGreen("[通过] ") + fmt.Sprintf(format, v...))
+}
+
+// warn 输出警告信息
+func warn(format string, v ...any) {
+	fmt.Println(colors.ToYellow("[警告] ") + fmt.Sprintf(format, v...))
+}
+
+// fail 输出错误信息
+func fail(format string, v ...any) {
+	fmt.Println(colors.ToRed("[错误] ") + fmt.Sprintf(format, v...))
+}
@@ -0,0 +1,56 @@
+// 命令行子命令, 用于在不启动服务的情况下检查配置
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+// 子命令名称
+const (
+	CmdCheckConfig = "check-config" // 校验配置文件
+	CmdMap         = "map"          // 查看本地路径的映射结果
+)
+
+// IsCommand 判断参数是否为子命令
+func IsCommand(name string) bool {
+	return name == CmdCheckConfig || name == CmdMap
+}
+
+// Run 执行子命令, 返回进程退出码
+func Run(name string, args []string) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	dr := fs.String("dr", ".", "程序数据根目录")
+	fs.Usage = func() {
+		switch name {
+		case CmdCheckConfig:
+			fmt.Fprintf(fs.Output(), "用法: %s %s [-dr 数据根目录]\n\n校验配置文件, 检查未知配置项、重叠的路径映射以及 Emby/OpenList 的连通性\n\n", filepath.Base(os.Args[0]), name)
+		case CmdMap:
+			fmt.Fprintf(fs.Output(), "用法: %s %s [-dr 数据根目录] <本地路径>\n\n输出本地路径命中的 CDN 路径映射和最终的 CDN 直链\n\n", filepath.Base(os.Args[0]), name)
+		}
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// 子命令只输出检查结果, 不输出配置初始化过程中的普通日志
+	logs.Configure(logs.Options{Level: logs.LevelWarn, Redact: true, Stdout: true})
+
+	path := filepath.Join(*dr, "config.yml")
+	switch name {
+	case CmdCheckConfig:
+		return checkConfig(path)
+	case CmdMap:
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return 2
+		}
+		return mapPath(path, fs.Arg(0))
+	}
+	return 2
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// mapPath 输出本地路径的映射结果, 未命中任何路径映射时返回 1
+func mapPath(path, localPath string) int {
+	c, err := config.Load(path)
+	if err != nil {
+		fail("%v", err)
+		return 1
+	}
+
+	res, err := c.Emby.Strm.Resolve(localPath, "")
+	if err != nil {
+		fail("%v", err)
+		return 1
+	}
+
+	cdn := res.Cdn
+	fmt.Printf("本地路径: %s\n", localPath)
+	fmt.Printf("命中 CDN: %s (%s, %s)\n", cdn.Name, cdn.Type, cdn.Base)
+	fmt.Printf("路径映射: %s => %s\n", res.Mapping.LocalPrefix, res.Mapping.RemotePrefix)
+	fmt.Printf("远程路径: %s\n", res.RemotePath)
+	fmt.Printf("CDN 直链: %s\n", colors.ToGreen(res.Url))
+
+	if cdn.BindClientIp {
+		fmt.Println(colors.ToGray("* 该 CDN 开启了 bind-client-ip, 实际请求时签名会绑定客户端 IP"))
+	}
+	if cdn.OneTimeToken {
+		fmt.Println(colors.ToGray("* 该 CDN 开启了 one-time-token, 客户端收到的是代理签发的一次性链接"))
+	}
+	if cdn.ProxyStream {
+		fmt.Println(colors.ToGray("* 该 CDN 开启了 proxy-stream, 资源由代理回传, 客户端不会直接访问 CDN"))
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/accesslog"
+)
+
+// AccessLog 访问日志配置
+//
+// 访问日志独立于控制台日志, 只输出到文件
+type AccessLog struct {
+	// Enable 是否启用访问日志
+	Enable bool `yaml:"enable"`
+	// Format 日志格式 combined|json
+	Format string `yaml:"format"`
+
+	LogRotate `yaml:",inline"`
+
+	// writer 轮转的日志文件
+	writer *logs.RotateWriter
+}
+
+// Init 配置初始化
+func (al *AccessLog) Init() error {
+	if !al.Enable {
+		return nil
+	}
+	if strs.AnyEmpty(al.Format) {
+		al.Format = accesslog.FormatJson
+	}
+	if al.Format != accesslog.FormatJson && al.Format != accesslog.FormatCombined {
+		return fmt.Errorf("access-log.format 配置错误: [%s], 有效值: [%s %s]", al.Format, accesslog.FormatJson, accesslog.FormatCombined)
+	}
+	w, err := al.LogRotate.writer("logs/access.log")
+	if err != nil {
+		return fmt.Errorf("access-log 配置错误: %v", err)
+	}
+	al.writer = w
+	return nil
+}
+
+// Writer 访问日志文件
+func (al *AccessLog) Writer() *logs.RotateWriter {
+	return al.writer
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// Admin 管理端口配置
+//
+// 管理端口独立于 emby 代理端口, 用于暴露监控指标等运维接口
+type Admin struct {
+	// Enable 是否启用管理端口
+	Enable bool `yaml:"enable"`
+	// Addr 监听地址, 默认只监听本机
+	Addr string `yaml:"addr"`
+	// Username 管理面板登录用户名, 默认 admin
+	Username string `yaml:"username"`
+	// Password 管理面板登录密码, 为空时不启用管理面板
+	Password string `yaml:"password"`
+}
+
+// Init 配置初始化
+func (a *Admin) Init() error {
+	if !a.Enable {
+		return nil
+	}
+	if strs.AnyEmpty(a.Addr) {
+		a.Addr = "127.0.0.1:8097"
+	}
+	if strs.AnyEmpty(a.Username) {
+		a.Username = "admin"
+	}
+	if _, _, err := net.SplitHostPort(a.Addr); err != nil {
+		return fmt.Errorf("admin.addr 配置错误: [%s], %v", a.Addr, err)
+	}
+	return nil
+}
+
+// DashboardEnabled 是否启用管理面板
+func (a *Admin) DashboardEnabled() bool {
+	return a.Enable && a.Password != ""
+}
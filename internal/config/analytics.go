@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// Analytics 播放统计配置
+type Analytics struct {
+	// Enable 是否记录播放会话
+	Enable bool `yaml:"enable"`
+	// Path 数据文件路径, 相对路径基于数据根目录
+	Path string `yaml:"path"`
+	// RetentionDays 会话记录的保留天数
+	RetentionDays int `yaml:"retention-days"`
+}
+
+// Init 配置初始化
+func (a *Analytics) Init() error {
+	if !a.Enable {
+		return nil
+	}
+	if strs.AnyEmpty(a.Path) {
+		a.Path = filepath.Join(DataDir, "analytics.db")
+	}
+	if !filepath.IsAbs(a.Path) {
+		a.Path = filepath.Join(BasePath, a.Path)
+	}
+	if a.RetentionDays == 0 {
+		a.RetentionDays = 90
+	}
+	if a.RetentionDays < 0 {
+		return fmt.Errorf("analytics.retention-days 配置错误: %d", a.RetentionDays)
+	}
+	return nil
+}
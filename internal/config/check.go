@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnknownKeys 检查配置文件中无法识别的配置项
+//
+// yaml 解析时会直接忽略未知的配置项, 拼写错误的配置项不会生效也不会报错,
+// 返回值格式: 第 N 行: 配置项路径
+func UnknownKeys(data []byte) ([]string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	var res []string
+	checkKeys(root.Content[0], reflect.TypeOf(Config{}), "", &res)
+	return res, nil
+}
+
+// checkKeys 按配置结构体的 yaml 标签检查节点中的配置项
+func checkKeys(node *yaml.Node, t reflect.Type, prefix string, res *[]string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			if k.Value == "<<" {
+				continue
+			}
+			key := joinKey(prefix, k.Value)
+			ft, ok := fields[k.Value]
+			if !ok {
+				*res = append(*res, fmt.Sprintf("第 %d 行: %s", k.Line, key))
+				continue
+			}
+			checkKeys(v, ft, key, res)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), res)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkKeys(node.Content[i+1], t.Elem(), joinKey(prefix, node.Content[i].Value), res)
+		}
+	}
+}
+
+// yamlFields 获取结构体中所有可以配置的字段, 包括内联结构体的字段
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if opts == "inline" {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// joinKey 拼接配置项路径
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// OverlappingMappings 检查不同 CDN 之间互相覆盖的路径映射
+//
+// 路径按 CDN 的配置顺序匹配, 重叠部分的路径只会命中排在前面的 CDN
+func (s *Strm) OverlappingMappings() []string {
+	var res []string
+	for ci := range s.Cdns {
+		for cj := ci + 1; cj < len(s.Cdns); cj++ {
+			for _, a := range s.Cdns[ci].PathMappings {
+				for _, b := range s.Cdns[cj].PathMappings {
+					if !matchPathPrefix(a.LocalPrefix, b.LocalPrefix) && !matchPathPrefix(b.LocalPrefix, a.LocalPrefix) {
+						continue
+					}
+					res = append(res, fmt.Sprintf("[%s] %s 与 [%s] %s 重叠, 重叠部分的路径只会映射到 [%s]",
+						s.Cdns[ci].Name, a.LocalPrefix, s.Cdns[cj].Name, b.LocalPrefix, s.Cdns[ci].Name))
+				}
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+// TestUnknownKeys 测试未知配置项检查
+func TestUnknownKeys(t *testing.T) {
+	data := []byte(`
+emby:
+  host: http://127.0.0.1:8096
+  proxy-eror-strategy: origin
+  strm:
+    cdns:
+      - name: main
+        path-mappings:
+          - local-prefix: /media
+            remote: /
+log:
+  levels:
+    emby: warn
+  file:
+    enable: true
+    path: logs/a.log
+    max-szie: 10
+trace:
+  headers:
+    Authorization: xxx
+unknown-section: 1
+`)
+	got, err := UnknownKeys(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"第 4 行: emby.proxy-eror-strategy",
+		"第 10 行: emby.strm.cdns[0].path-mappings[0].remote",
+		"第 17 行: log.file.max-szie",
+		"第 21 行: unknown-section",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("UnknownKeys() = %v, want %v", got, want)
+	}
+
+	// 示例配置文件中不应存在未知配置项
+	example, err := os.ReadFile("../../config.example.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := UnknownKeys(example); err != nil || len(got) > 0 {
+		t.Errorf("示例配置存在未知配置项: %v, err: %v", got, err)
+	}
+}
+
+// TestStrm_OverlappingMappings 测试路径映射重叠检查
+func TestStrm_OverlappingMappings(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "相同前缀", a: "/media", b: "/media", want: true},
+		{name: "包含关系", a: "/media", b: "/media/movies", want: true},
+		{name: "反向包含", a: "/media/movies", b: "/media", want: true},
+		{name: "相似前缀", a: "/media", b: "/media2", want: false},
+		{name: "不相关", a: "/movies", b: "/series", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Strm{Cdns: []CdnConfig{
+				{Name: "a", PathMappings: []PathMapping{{LocalPrefix: tt.a}}},
+				{Name: "b", PathMappings: []PathMapping{{LocalPrefix: tt.b}}},
+			}}
+			if got := len(s.OverlappingMappings()) > 0; got != tt.want {
+				t.Errorf("OverlappingMappings() 重叠 = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
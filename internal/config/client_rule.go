@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
+)
+
+// RedirectMode 播放资源的重定向方式
+type RedirectMode string
+
+const (
+	RedirectMode302         RedirectMode = "302"          // 302 临时重定向
+	RedirectMode307         RedirectMode = "307"          // 307 临时重定向, 保留请求方法
+	RedirectModeProxyStream RedirectMode = "proxy-stream" // 由代理回传资源
+)
+
+// validRedirectMode 用于校验用户配置的重定向方式是否合法
+var validRedirectMode = map[RedirectMode]struct{}{
+	RedirectMode302: {}, RedirectMode307: {}, RedirectModeProxyStream: {},
+}
+
+// ClientRule 客户端规则, 根据客户端特征决定代理行为
+//
+// 所有配置了的匹配条件都满足时, 规则才生效; 没有配置任何匹配条件的规则匹配所有客户端
+type ClientRule struct {
+	// Name 规则名称（用于日志标识）
+	Name string `yaml:"name"`
+	// UserAgent 匹配 User-Agent 请求头的正则
+	UserAgent string `yaml:"user-agent"`
+	// Client 匹配 X-Emby-Client 的正则
+	Client string `yaml:"client"`
+	// DeviceName 匹配 X-Emby-Device-Name 的正则
+	DeviceName string `yaml:"device-name"`
+
+	// RedirectMode 播放资源的重定向方式 (302/307/proxy-stream), 默认 302
+	RedirectMode RedirectMode `yaml:"redirect-mode"`
+	// RewritePlaybackInfo 是否改写 PlaybackInfo 响应, 默认 true
+	RewritePlaybackInfo *bool `yaml:"rewrite-playback-info"`
+	// DisableTranscoding 是否禁用转码, 默认 true
+	DisableTranscoding *bool `yaml:"disable-transcoding"`
+	// DeviceProfile 请求 PlaybackInfo 时使用的 DeviceProfile
+	//
+	// common: 内置通用配置（默认）; client: 保留客户端原始配置, 并合并内置的 DirectPlay 配置;
+	// 其他值: 读取数据根目录下 device-profiles/{名称}.json 文件
+	DeviceProfile string `yaml:"device-profile"`
+
+	// userAgent 编译后的 User-Agent 正则
+	userAgent *regexp.Regexp
+	// client 编译后的 X-Emby-Client 正则
+	client *regexp.Regexp
+	// deviceName 编译后的 X-Emby-Device-Name 正则
+	deviceName *regexp.Regexp
+	// deviceProfilePayload 从文件中加载的 PlaybackInfo 请求体
+	deviceProfilePayload string
+}
+
+const (
+	DeviceProfileCommon = "common" // 内置通用 DeviceProfile
+	DeviceProfileClient = "client" // 保留客户端原始 DeviceProfile
+
+	// DeviceProfilesDir 存放自定义 DeviceProfile 文件的目录名称
+	DeviceProfilesDir = "device-profiles"
+)
+
+// defaultClientRule 没有任何规则匹配时使用的默认规则
+var defaultClientRule = &ClientRule{Name: "default", RedirectMode: RedirectMode302, DeviceProfile: DeviceProfileCommon}
+
+// Init 配置初始化
+func (cr *ClientRule) Init() error {
+	var err error
+	compile := func(name, raw string) *regexp.Regexp {
+		if err != nil || strings.TrimSpace(raw) == "" {
+			return nil
+		}
+		reg, cErr := regexp.Compile(raw)
+		if cErr != nil {
+			err = fmt.Errorf("%s 正则编译失败: [%s], %v", name, raw, cErr)
+		}
+		return reg
+	}
+	cr.userAgent = compile("user-agent", cr.UserAgent)
+	cr.client = compile("client", cr.Client)
+	cr.deviceName = compile("device-name", cr.DeviceName)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(string(cr.RedirectMode)) == "" {
+		cr.RedirectMode = RedirectMode302
+	}
+	cr.RedirectMode = RedirectMode(strings.TrimSpace(string(cr.RedirectMode)))
+	if _, ok := validRedirectMode[cr.RedirectMode]; !ok {
+		return fmt.Errorf("redirect-mode 配置错误, 有效值: %v", maps.Keys(validRedirectMode))
+	}
+
+	cr.DeviceProfile = strings.TrimSpace(cr.DeviceProfile)
+	if cr.DeviceProfile == "" {
+		cr.DeviceProfile = DeviceProfileCommon
+	}
+	if cr.DeviceProfile != DeviceProfileCommon && cr.DeviceProfile != DeviceProfileClient {
+		payload, err := loadDeviceProfile(cr.DeviceProfile)
+		if err != nil {
+			return fmt.Errorf("device-profile 加载失败: %v", err)
+		}
+		cr.deviceProfilePayload = payload
+	}
+
+	return nil
+}
+
+// DeviceProfilePayload 获取从文件中加载的 PlaybackInfo 请求体
+//
+// 规则没有使用自定义 DeviceProfile 文件时, 第二个参数返回 false
+func (cr *ClientRule) DeviceProfilePayload() (string, bool) {
+	return cr.deviceProfilePayload, cr.deviceProfilePayload != ""
+}
+
+// loadDeviceProfile 从数据根目录中加载指定名称的 DeviceProfile 文件
+//
+// 文件内容可以是 DeviceProfile 对象本身, 也可以是包含 DeviceProfile 属性的完整请求体
+func loadDeviceProfile(name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("非法的名称: %s", name)
+	}
+	fp := filepath.Join(BasePath, DeviceProfilesDir, name+".json")
+	bytes, err := os.ReadFile(fp)
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %v", err)
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &body); err != nil {
+		return "", fmt.Errorf("解析文件失败 [%s]: %v", fp, err)
+	}
+	if _, ok := body["DeviceProfile"]; ok {
+		return string(bytes), nil
+	}
+	wrapped, err := json.Marshal(map[string]json.RawMessage{"DeviceProfile": bytes})
+	if err != nil {
+		return "", fmt.Errorf("包装 DeviceProfile 失败 [%s]: %v", fp, err)
+	}
+	return string(wrapped), nil
+}
+
+// Match 判断客户端是否匹配当前规则
+func (cr *ClientRule) Match(userAgent, client, deviceName string) bool {
+	if cr.userAgent != nil && !cr.userAgent.MatchString(userAgent) {
+		return false
+	}
+	if cr.client != nil && !cr.client.MatchString(client) {
+		return false
+	}
+	if cr.deviceName != nil && !cr.deviceName.MatchString(deviceName) {
+		return false
+	}
+	return true
+}
+
+// ShouldRewritePlaybackInfo 是否改写 PlaybackInfo 响应
+func (cr *ClientRule) ShouldRewritePlaybackInfo() bool {
+	return cr.RewritePlaybackInfo == nil || *cr.RewritePlaybackInfo
+}
+
+// ShouldDisableTranscoding 是否禁用转码
+func (cr *ClientRule) ShouldDisableTranscoding() bool {
+	return cr.DisableTranscoding == nil || *cr.DisableTranscoding
+}
+
+// MatchClientRule 按顺序匹配客户端规则, 没有规则匹配时返回默认规则
+func (e *Emby) MatchClientRule(userAgent, client, deviceName string) *ClientRule {
+	for _, rule := range e.ClientRules {
+		if rule.Match(userAgent, client, deviceName) {
+			return rule
+		}
+	}
+	return defaultClientRule
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEmby_MatchClientRule 测试客户端规则匹配
+func TestEmby_MatchClientRule(t *testing.T) {
+	no := false
+	e := &Emby{
+		ClientRules: []*ClientRule{
+			{Name: "infuse", UserAgent: `(?i)infuse`, RedirectMode: RedirectModeProxyStream},
+			{Name: "web", Client: `(?i)^emby web$`, DeviceName: `(?i)chrome`, DisableTranscoding: &no},
+		},
+	}
+	for _, rule := range e.ClientRules {
+		if err := rule.Init(); err != nil {
+			t.Fatalf("规则初始化失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		userAgent  string
+		client     string
+		deviceName string
+		wantRule   string
+	}{
+		{name: "UA 匹配", userAgent: "Infuse/7.7", wantRule: "infuse"},
+		{name: "所有条件匹配", userAgent: "Mozilla/5.0", client: "Emby Web", deviceName: "Chrome macOS", wantRule: "web"},
+		{name: "部分条件不匹配", userAgent: "Mozilla/5.0", client: "Emby Web", deviceName: "Firefox", wantRule: "default"},
+		{name: "无规则匹配", userAgent: "VLC/3.0", wantRule: "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := e.MatchClientRule(tt.userAgent, tt.client, tt.deviceName)
+			if rule.Name != tt.wantRule {
+				t.Errorf("MatchClientRule() = %s, want %s", rule.Name, tt.wantRule)
+			}
+		})
+	}
+
+	if e.ClientRules[1].ShouldDisableTranscoding() {
+		t.Error("web 规则应允许转码")
+	}
+	if !defaultClientRule.ShouldDisableTranscoding() || !defaultClientRule.ShouldRewritePlaybackInfo() {
+		t.Error("默认规则应改写 PlaybackInfo 并禁用转码")
+	}
+}
+
+// TestClientRule_Init 测试客户端规则校验
+func TestClientRule_Init(t *testing.T) {
+	if err := (&ClientRule{UserAgent: "("}).Init(); err == nil {
+		t.Error("非法正则应该初始化失败")
+	}
+	if err := (&ClientRule{RedirectMode: "301"}).Init(); err == nil {
+		t.Error("非法重定向方式应该初始化失败")
+	}
+	rule := &ClientRule{}
+	if err := rule.Init(); err != nil || rule.RedirectMode != RedirectMode302 {
+		t.Errorf("默认重定向方式应为 302, err: %v", err)
+	}
+}
+
+// TestLoadDeviceProfile 测试从数据目录加载 DeviceProfile 文件
+func TestLoadDeviceProfile(t *testing.T) {
+	origin := BasePath
+	BasePath = t.TempDir()
+	defer func() { BasePath = origin }()
+
+	dir := filepath.Join(BasePath, DeviceProfilesDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"bare.json": `{"MaxStreamingBitrate":20000000}`,
+		"full.json": `{"DeviceProfile":{"MaxStreamingBitrate":20000000}}`,
+		"bad.json":  `{`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"bare", "full"} {
+		rule := &ClientRule{DeviceProfile: name}
+		if err := rule.Init(); err != nil {
+			t.Fatalf("[%s] 规则初始化失败: %v", name, err)
+		}
+		payload, ok := rule.DeviceProfilePayload()
+		if !ok || !strings.Contains(payload, `"DeviceProfile":{"MaxStreamingBitrate":20000000}`) {
+			t.Errorf("[%s] 请求体不符合预期: %s", name, payload)
+		}
+	}
+
+	for _, name := range []string{"bad", "missing", "../bare"} {
+		if err := (&ClientRule{DeviceProfile: name}).Init(); err == nil {
+			t.Errorf("[%s] 应该初始化失败", name)
+		}
+	}
+
+	rule := &ClientRule{}
+	if err := rule.Init(); err != nil || rule.DeviceProfile != DeviceProfileCommon {
+		t.Errorf("默认 DeviceProfile 应为 common, err: %v", err)
+	}
+	if _, ok := rule.DeviceProfilePayload(); ok {
+		t.Error("common 不应加载文件")
+	}
+}
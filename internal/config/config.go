@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sync/atomic"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 	"gopkg.in/yaml.v3"
 )
@@ -23,36 +25,92 @@ type Config struct {
 	Log *Log `yaml:"log"`
 	// ItemsCounts 媒体库数量统计配置
 	ItemsCounts *ItemsCounts `yaml:"items-counts"`
+	// RateLimit 请求限流配置
+	RateLimit *RateLimit `yaml:"rate-limit"`
+	// Network 网络配置
+	Network *Network `yaml:"network"`
+	// Routes 自定义路由规则
+	Routes Routes `yaml:"routes"`
+	// Admin 管理端口配置
+	Admin *Admin `yaml:"admin"`
+	// AccessLog 访问日志配置
+	AccessLog *AccessLog `yaml:"access-log"`
+	// Analytics 播放统计配置
+	Analytics *Analytics `yaml:"analytics"`
+	// Trace 链路追踪配置
+	Trace *Trace `yaml:"trace"`
 }
 
-// C 全局唯一配置对象
-var C *Config
+// current 当前生效的全局配置, 重新加载时整体替换
+var current atomic.Pointer[Config]
 
-// BasePath 配置文件所在的基础路径
+// C 获取当前生效的全局配置
+//
+// 配置重新加载后返回新的配置对象, 已取得的旧对象不会被修改
+func C() *Config {
+	return current.Load()
+}
+
+// Set 替换全局配置
+func Set(c *Config) {
+	current.Store(c)
+}
+
+// BasePath 配置文件所在的基础路径, 首次加载配置时确定, 重新加载不会修改
 var BasePath string
 
+// DataDir 程序运行数据存放目录名称, 位于 BasePath 下
+const DataDir = "data"
+
 type Initializer interface {
 	// Init 配置初始化
 	Init() error
 }
 
-// ReadFromFile 从指定文件中读取配置
+// Applier 配置生效, 在所有配置项校验通过后调用, 用于设置日志、链路追踪等全局状态
+type Applier interface {
+	// Apply 应用配置
+	Apply() error
+}
+
+// FilePath 当前使用的配置文件路径
+var FilePath string
+
+// ReadFromFile 从指定文件中读取配置, 并作为全局配置生效
 func ReadFromFile(path string) error {
+	c, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := apply(c, C()); err != nil {
+		return err
+	}
+	Set(c)
+	FilePath = path
+	return nil
+}
+
+// Load 从指定文件中读取并初始化配置, 不会修改全局配置对象, 也不会应用配置
+func Load(path string) (*Config, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("读取配置文件失败: %v", err)
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
 	}
 
-	if err = initBasePath(path); err != nil {
-		return fmt.Errorf("初始化 BasePath 失败: %v", err)
+	if BasePath == "" {
+		base, err := basePathOf(path)
+		if err != nil {
+			return nil, fmt.Errorf("初始化 BasePath 失败: %v", err)
+		}
+		BasePath = base
 	}
 
-	C = new(Config)
-	if err := yaml.Unmarshal(bytes, C); err != nil {
-		return fmt.Errorf("解析配置文件失败: %v", err)
+	c := new(Config)
+	if err := yaml.Unmarshal(bytes, c); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
-	cVal := reflect.ValueOf(C).Elem()
+	cVal := reflect.ValueOf(c).Elem()
 	for i := 0; i < cVal.NumField(); i++ {
 		field := cVal.Field(i)
 
@@ -65,38 +123,67 @@ func ReadFromFile(path string) error {
 		// 配置项初始化
 		if i, ok := field.Interface().(Initializer); ok {
 			if err := i.Init(); err != nil {
-				return fmt.Errorf("初始化配置文件失败: %v", err)
+				return nil, fmt.Errorf("初始化配置文件失败: %v", err)
 			}
 		}
 	}
 
-	return nil
+	return c, nil
 }
 
 // ServerInternalRequestHost 服务内部自请求 host
 func ServerInternalRequestHost() string {
 	p := "http://127.0.0.1:" + webport.HTTP
-	if C == nil {
+	c := C()
+	if c == nil {
 		return p
 	}
 
 	// 只开启了 https 端口
-	if C.Ssl.Enable && C.Ssl.SinglePort {
+	if c.Ssl.Enable && c.Ssl.SinglePort {
 		p = "https://127.0.0.1:" + webport.HTTPS
 	}
 	return p
 }
 
-// initBasePath 初始化 BasePath
-func initBasePath(path string) error {
-	if filepath.IsAbs(path) {
-		BasePath = filepath.Dir(path)
-		return nil
-	}
+// basePathOf 计算配置文件所在的目录
+func basePathOf(path string) (string, error) {
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return err
+		return "", err
+	}
+	return filepath.Dir(absPath), nil
+}
+
+// apply 按字段顺序应用配置
+//
+// 某一项应用失败时, 按 old 重新应用已经切换的配置项, 避免全局状态只切换了一半
+func apply(c, old *Config) error {
+	cVal := reflect.ValueOf(c).Elem()
+	for i := 0; i < cVal.NumField(); i++ {
+		a, ok := cVal.Field(i).Interface().(Applier)
+		if !ok {
+			continue
+		}
+		if err := a.Apply(); err != nil {
+			rollback(old, i)
+			return fmt.Errorf("应用配置失败: %v", err)
+		}
 	}
-	BasePath = filepath.Dir(absPath)
 	return nil
 }
+
+// rollback 按 old 重新应用下标不超过 n 的配置项, old 为空时不处理
+func rollback(old *Config, n int) {
+	if old == nil {
+		return
+	}
+	oVal := reflect.ValueOf(old).Elem()
+	for i := 0; i <= n; i++ {
+		if a, ok := oVal.Field(i).Interface().(Applier); ok {
+			if err := a.Apply(); err != nil {
+				logs.Error("回滚配置失败: %v", err)
+			}
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/cdnauth"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 )
 
@@ -46,8 +47,30 @@ type Emby struct {
 	ProxyErrorStrategy PeStrategy `yaml:"proxy-error-strategy"`
 	// ImagesQuality 图片质量
 	ImagesQuality int `yaml:"images-quality"`
+	// ImageCache 图片缓存配置
+	ImageCache *ImageCache `yaml:"image-cache"`
+	// ImageCdn 图片 CDN 配置
+	ImageCdn *ImageCdn `yaml:"image-cdn"`
 	// Strm strm 配置
 	Strm *Strm `yaml:"strm"`
+	// ClientRules 客户端规则, 按顺序匹配, 决定重定向方式以及 PlaybackInfo 改写行为
+	ClientRules []*ClientRule `yaml:"client-rules"`
+	// ProxyStreamClients 无法跟随 302 的客户端 UA 正则, 匹配的客户端由代理回传 CDN 资源
+	//
+	// 兼容旧配置, 初始化时会转换为 redirect-mode 为 proxy-stream 的客户端规则
+	ProxyStreamClients []string `yaml:"proxy-stream-clients"`
+	// Transcode 选择性转码策略
+	Transcode *Transcode `yaml:"transcode"`
+	// SourcePreferences 多版本 MediaSource 偏好规则, 按顺序决定优先级
+	SourcePreferences []*SourcePreference `yaml:"source-preferences"`
+	// VirtualLibraries 媒体库虚拟化规则, 对指定用户隐藏、重命名或合并媒体库
+	VirtualLibraries []*VirtualLibrary `yaml:"virtual-libraries"`
+	// ResponseRewrites 响应改写规则, 按顺序对匹配路由的 json 响应执行改写
+	ResponseRewrites []*ResponseRewrite `yaml:"response-rewrites"`
+	// StreamPreference 音轨字幕偏好记忆配置
+	StreamPreference *StreamPreference `yaml:"stream-preference"`
+	// Subtitles 字幕处理配置
+	Subtitles *Subtitles `yaml:"subtitles"`
 }
 
 func (e *Emby) Init() error {
@@ -72,6 +95,83 @@ func (e *Emby) Init() error {
 		return fmt.Errorf("emby.images-quality 配置错误: %d, 允许配置范围: [1, 100]", e.ImagesQuality)
 	}
 
+	for _, raw := range e.ProxyStreamClients {
+		e.ClientRules = append(e.ClientRules, &ClientRule{
+			Name:         "proxy-stream-clients",
+			UserAgent:    raw,
+			RedirectMode: RedirectModeProxyStream,
+		})
+	}
+	for i, rule := range e.ClientRules {
+		if rule == nil {
+			return fmt.Errorf("emby.client-rules[%d] 不能为空", i)
+		}
+		if strs.AnyEmpty(rule.Name) {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if err := rule.Init(); err != nil {
+			return fmt.Errorf("emby.client-rules[%d] 配置错误: %v", i, err)
+		}
+	}
+
+	if e.Transcode == nil {
+		e.Transcode = new(Transcode)
+	}
+	if err := e.Transcode.Init(); err != nil {
+		return fmt.Errorf("emby.transcode 配置错误: %v", err)
+	}
+
+	for i, sp := range e.SourcePreferences {
+		if sp == nil {
+			return fmt.Errorf("emby.source-preferences[%d] 不能为空", i)
+		}
+		if err := sp.Init(); err != nil {
+			return fmt.Errorf("emby.source-preferences[%d] 配置错误: %v", i, err)
+		}
+	}
+
+	for i, vl := range e.VirtualLibraries {
+		if vl == nil {
+			return fmt.Errorf("emby.virtual-libraries[%d] 不能为空", i)
+		}
+		if err := vl.Init(); err != nil {
+			return fmt.Errorf("emby.virtual-libraries[%d] 配置错误: %v", i, err)
+		}
+	}
+
+	for i, rr := range e.ResponseRewrites {
+		if rr == nil {
+			return fmt.Errorf("emby.response-rewrites[%d] 不能为空", i)
+		}
+		if strs.AnyEmpty(rr.Name) {
+			rr.Name = fmt.Sprintf("rewrite-%d", i)
+		}
+		if err := rr.Init(); err != nil {
+			return fmt.Errorf("emby.response-rewrites[%d] 配置错误: %v", i, err)
+		}
+	}
+
+	if e.StreamPreference == nil {
+		e.StreamPreference = new(StreamPreference)
+	}
+	if err := e.StreamPreference.Init(); err != nil {
+		return fmt.Errorf("emby.stream-preference 配置错误: %v", err)
+	}
+
+	if e.Subtitles == nil {
+		e.Subtitles = new(Subtitles)
+	}
+	if err := e.Subtitles.Init(); err != nil {
+		return fmt.Errorf("emby.subtitles 配置错误: %v", err)
+	}
+
+	if e.ImageCache == nil {
+		e.ImageCache = new(ImageCache)
+	}
+	if err := e.ImageCache.Init(); err != nil {
+		return fmt.Errorf("emby.image-cache 配置错误: %v", err)
+	}
+
 	if e.Strm == nil {
 		e.Strm = new(Strm)
 	}
@@ -79,6 +179,13 @@ func (e *Emby) Init() error {
 		return fmt.Errorf("emby.strm 配置错误: %v", err)
 	}
 
+	if e.ImageCdn == nil {
+		e.ImageCdn = new(ImageCdn)
+	}
+	if err := e.ImageCdn.Init(e.Strm); err != nil {
+		return fmt.Errorf("emby.image-cdn 配置错误: %v", err)
+	}
+
 	return nil
 }
 
@@ -120,6 +227,16 @@ type CdnConfig struct {
 	Uid string `yaml:"uid"`
 	// PathMappings 该 CDN 下的路径映射列表
 	PathMappings []PathMapping `yaml:"path-mappings"`
+	// BindClientIp 是否将客户端 IP 绑定到签名中（仅 goedge/tencent 生效, 需 CDN 侧配置相应校验规则）
+	BindClientIp bool `yaml:"bind-client-ip"`
+	// ClientIpArg 绑定客户端 IP 时, 附加到 URL 中的参数名（默认 "ip"）
+	ClientIpArg string `yaml:"client-ip-arg"`
+	// OneTimeToken 是否下发由代理签发的一次性链接, 代替直接返回 CDN 直链
+	OneTimeToken bool `yaml:"one-time-token"`
+	// TokenTtl 一次性链接的有效期, 单位: 秒（默认 60）
+	TokenTtl int `yaml:"token-ttl"`
+	// ProxyStream 是否由代理回传该 CDN 的资源, 而不是 302 重定向
+	ProxyStream bool `yaml:"proxy-stream"`
 }
 
 // Strm strm 配置
@@ -176,6 +293,22 @@ func (s *Strm) Init() error {
 			s.Cdns[ci].Uid = "0"
 		}
 
+		// 客户端 IP 绑定
+		if cdn.BindClientIp && cdn.Type == CdnAuthTypeNone {
+			return fmt.Errorf("strm.cdns[%d].bind-client-ip 仅支持 goedge/tencent 鉴权类型", ci)
+		}
+		if strs.AnyEmpty(cdn.ClientIpArg) {
+			s.Cdns[ci].ClientIpArg = "ip"
+		}
+
+		// 一次性链接
+		if cdn.TokenTtl < 0 {
+			return fmt.Errorf("strm.cdns[%d].token-ttl 不能为负数", ci)
+		}
+		if cdn.TokenTtl == 0 {
+			s.Cdns[ci].TokenTtl = 60
+		}
+
 		// 验证路径映射
 		if len(cdn.PathMappings) == 0 {
 			return fmt.Errorf("strm.cdns[%d].path-mappings 不能为空", ci)
@@ -198,6 +331,21 @@ func (s *Strm) Init() error {
 	return nil
 }
 
+// mapPathMisses 本地路径映射失败次数
+var mapPathMisses = metrics.NewCounter("ge2o_map_path_misses_total", "本地路径未匹配到任何 CDN 映射规则的次数")
+
+// MapResult 路径映射结果
+type MapResult struct {
+	// Cdn 命中的 CDN 配置
+	Cdn *CdnConfig
+	// Mapping 命中的路径映射
+	Mapping PathMapping
+	// RemotePath CDN 上的路径（原始路径，未编码、未签名）
+	RemotePath string
+	// Url 最终的 CDN 直链
+	Url string
+}
+
 // MapPath 将本地路径映射为 CDN 直链（支持鉴权）
 // 示例：
 //
@@ -210,8 +358,34 @@ func (s *Strm) Init() error {
 //	  - cdn.private-key: xxxx
 //	输出: https://cdn.example.com/%E7%94%B5%E5%BD%B1/xxx.mp4?sign=1234567890-abc123-md5hash
 func (s *Strm) MapPath(localPath string) (string, error) {
-	// 遍历所有 CDN 配置
+	res, err := s.Resolve(localPath, "")
+	if err != nil {
+		return "", err
+	}
+	return res.Url, nil
+}
+
+// HasMapping 判断本地路径是否存在匹配的路径映射
+//
+// 只做前缀匹配, 不生成直链, 也不计入映射失败次数
+func (s *Strm) HasMapping(localPath string) bool {
 	for _, cdn := range s.Cdns {
+		for _, mapping := range cdn.PathMappings {
+			if matchPathPrefix(localPath, mapping.LocalPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Resolve 将本地路径映射为 CDN 直链, 并返回命中的 CDN 信息
+//
+// clientIp 不为空且 CDN 开启了 bind-client-ip 时, 签名会绑定客户端 IP
+func (s *Strm) Resolve(localPath, clientIp string) (MapResult, error) {
+	// 遍历所有 CDN 配置
+	for ci := range s.Cdns {
+		cdn := &s.Cdns[ci]
 		// 遍历该 CDN 下的所有路径映射
 		for _, mapping := range cdn.PathMappings {
 			// 检查路径是否匹配（需要严格匹配前缀）
@@ -226,17 +400,21 @@ func (s *Strm) MapPath(localPath string) (string, error) {
 			cdnPath := mapping.RemotePrefix + relativePath
 
 			// 根据鉴权类型生成最终 URL
-			finalUrl, err := generateAuthUrl(cdn, cdnPath)
+			if !cdn.BindClientIp {
+				clientIp = ""
+			}
+			finalUrl, err := generateAuthUrl(*cdn, cdnPath, clientIp)
 			if err != nil {
-				return "", fmt.Errorf("生成鉴权 URL 失败: %v", err)
+				return MapResult{}, fmt.Errorf("生成鉴权 URL 失败: %v", err)
 			}
 
 			logs.Info("路径映射 [%s]: [%s] -> [%s]", cdn.Name, localPath, finalUrl)
-			return finalUrl, nil
+			return MapResult{Cdn: cdn, Mapping: mapping, RemotePath: cdnPath, Url: finalUrl}, nil
 		}
 	}
 
-	return "", fmt.Errorf("未找到匹配的路径映射规则: %s", localPath)
+	mapPathMisses.Inc()
+	return MapResult{}, fmt.Errorf("未找到匹配的路径映射规则: %s", localPath)
 }
 
 // matchPathPrefix 严格匹配路径前缀
@@ -261,7 +439,9 @@ func matchPathPrefix(path, prefix string) bool {
 }
 
 // generateAuthUrl 根据 CDN 配置生成带鉴权的 URL
-func generateAuthUrl(cdn CdnConfig, cdnPath string) (string, error) {
+//
+// clientIp 不为空时, 生成绑定客户端 IP 的签名
+func generateAuthUrl(cdn CdnConfig, cdnPath, clientIp string) (string, error) {
 	switch cdn.Type {
 	case CdnAuthTypeNone:
 		// 无鉴权，直接拼接
@@ -269,12 +449,12 @@ func generateAuthUrl(cdn CdnConfig, cdnPath string) (string, error) {
 
 	case CdnAuthTypeGoEdge:
 		// GoEdge 鉴权
-		signedPath := cdnauth.GenerateGoEdgeSign(cdnPath, cdn.PrivateKey, cdn.RandLength)
+		signedPath := cdnauth.GenerateGoEdgeSignWithIp(cdnPath, cdn.PrivateKey, cdn.RandLength, clientIp, cdn.ClientIpArg)
 		return cdn.Base + signedPath, nil
 
 	case CdnAuthTypeTencent:
 		// 腾讯云鉴权
-		signedPath := cdnauth.GenerateTencentSign(cdnPath, cdn.PrivateKey, cdn.Uid, cdn.RandLength)
+		signedPath := cdnauth.GenerateTencentSignWithIp(cdnPath, cdn.PrivateKey, cdn.Uid, cdn.RandLength, clientIp, cdn.ClientIpArg)
 		return cdn.Base + signedPath, nil
 
 	default:
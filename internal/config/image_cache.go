@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// ImageCacheDir 图片缓存目录名称, 位于数据目录下
+const ImageCacheDir = "images"
+
+// ImageCache 图片缓存配置
+//
+// 启用后, 图片原图缓存在磁盘中, 按请求的尺寸生成缩放后的图片并缓存
+type ImageCache struct {
+	// Enable 是否启用
+	Enable bool `yaml:"enable"`
+	// MaxSize 磁盘缓存上限, 单位: MB, 默认 1024
+	MaxSize int `yaml:"max-size"`
+	// Webp 客户端支持时是否输出 WebP 格式, WebP 图片由 Emby 生成后缓存
+	Webp bool `yaml:"webp"`
+}
+
+// Init 配置初始化
+func (ic *ImageCache) Init() error {
+	if !ic.Enable {
+		return nil
+	}
+	if ic.MaxSize == 0 {
+		ic.MaxSize = 1024
+	}
+	if ic.MaxSize < 0 {
+		return fmt.Errorf("max-size 配置错误: %d, 不能为负数", ic.MaxSize)
+	}
+	return nil
+}
+
+// Dir 获取图片缓存目录的绝对路径
+func (ic *ImageCache) Dir() string {
+	return filepath.Join(BasePath, DataDir, ImageCacheDir)
+}
+
+// Quota 获取磁盘缓存上限, 单位: 字节
+func (ic *ImageCache) Quota() int64 {
+	return int64(ic.MaxSize) * 1024 * 1024
+}
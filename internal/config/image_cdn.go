@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// ImageCdn 图片 CDN 配置
+//
+// 启用后, 图片请求根据 emby 中记录的图片路径映射为 CDN 直链,
+// 复用 strm 中指定 CDN 的鉴权配置, 路径映射单独配置
+type ImageCdn struct {
+	// Enable 是否启用
+	Enable bool `yaml:"enable"`
+	// Cdn 使用的 CDN 名称, 对应 strm.cdns 中的 name
+	Cdn string `yaml:"cdn"`
+	// PathMappings 图片路径映射列表
+	PathMappings []PathMapping `yaml:"path-mappings"`
+	// ApiKey 查询图片路径使用的 emby api_key, 为空则使用客户端请求中的 api_key
+	ApiKey string `yaml:"api-key"`
+
+	// strm 由图片路径映射构造的 strm 配置, 用于复用映射和鉴权逻辑
+	strm *Strm
+}
+
+// Init 配置初始化, 需要在 strm 配置初始化之后调用
+func (ic *ImageCdn) Init(s *Strm) error {
+	if !ic.Enable {
+		return nil
+	}
+	if strs.AnyEmpty(ic.Cdn) {
+		return errors.New("cdn 不能为空")
+	}
+	if len(ic.PathMappings) == 0 {
+		return errors.New("path-mappings 不能为空")
+	}
+
+	var cdn *CdnConfig
+	for ci := range s.Cdns {
+		if s.Cdns[ci].Name == strings.TrimSpace(ic.Cdn) {
+			cdn = &s.Cdns[ci]
+			break
+		}
+	}
+	if cdn == nil {
+		return fmt.Errorf("cdn 配置错误: 未在 strm.cdns 中找到 [%s]", ic.Cdn)
+	}
+
+	for mi, mapping := range ic.PathMappings {
+		if strs.AnyEmpty(mapping.LocalPrefix) {
+			return fmt.Errorf("path-mappings[%d].local-prefix 不能为空", mi)
+		}
+		if strs.AnyEmpty(mapping.RemotePrefix) {
+			return fmt.Errorf("path-mappings[%d].remote-prefix 不能为空", mi)
+		}
+		ic.PathMappings[mi].LocalPrefix = strings.TrimRight(mapping.LocalPrefix, "/")
+		ic.PathMappings[mi].RemotePrefix = strings.TrimRight(mapping.RemotePrefix, "/")
+	}
+
+	// 图片请求频繁, 不使用一次性链接和代理回传
+	imgCdn := *cdn
+	imgCdn.PathMappings = ic.PathMappings
+	imgCdn.OneTimeToken = false
+	imgCdn.ProxyStream = false
+	ic.strm = &Strm{Cdns: []CdnConfig{imgCdn}}
+	return nil
+}
+
+// Resolve 将图片的本地路径映射为 CDN 直链
+func (ic *ImageCdn) Resolve(localPath, clientIp string) (MapResult, error) {
+	if ic.strm == nil {
+		return MapResult{}, errors.New("图片 CDN 未启用")
+	}
+	return ic.strm.Resolve(localPath, clientIp)
+}
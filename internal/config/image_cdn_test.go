@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+// TestImageCdn_Resolve 测试图片路径映射复用 strm 中的 CDN 配置
+func TestImageCdn_Resolve(t *testing.T) {
+	s := &Strm{Cdns: []CdnConfig{{
+		Name:         "main",
+		Type:         CdnAuthTypeNone,
+		Base:         "https://cdn.example.com/",
+		OneTimeToken: true,
+		PathMappings: []PathMapping{{LocalPrefix: "/mnt/media", RemotePrefix: "/media"}},
+	}}}
+	if err := s.Init(); err != nil {
+		t.Fatalf("strm 初始化失败: %v", err)
+	}
+
+	ic := &ImageCdn{
+		Enable:       true,
+		Cdn:          "main",
+		PathMappings: []PathMapping{{LocalPrefix: "/config/metadata/", RemotePrefix: "/metadata/"}},
+	}
+	if err := ic.Init(s); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	res, err := ic.Resolve("/config/metadata/library/ab/poster.jpg", "")
+	if err != nil {
+		t.Fatalf("映射失败: %v", err)
+	}
+	if want := "https://cdn.example.com/metadata/library/ab/poster.jpg"; res.Url != want {
+		t.Errorf("Resolve() = %s, want %s", res.Url, want)
+	}
+	if res.Cdn.OneTimeToken {
+		t.Errorf("图片映射不应使用一次性链接")
+	}
+
+	if _, err := ic.Resolve("/mnt/media/movie/poster.jpg", ""); err == nil {
+		t.Errorf("图片映射不应使用 strm 的路径映射")
+	}
+
+	if err := (&ImageCdn{Enable: true, Cdn: "missing", PathMappings: ic.PathMappings}).Init(s); err == nil {
+		t.Errorf("未知的 CDN 名称应初始化失败")
+	}
+}
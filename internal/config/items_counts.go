@@ -2,14 +2,66 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
+// ItemsCountsMode Items/Counts 统计模式
+type ItemsCountsMode string
+
+const (
+	ItemsCountsModeStatic  ItemsCountsMode = "static"  // 使用配置中的固定数量
+	ItemsCountsModeDynamic ItemsCountsMode = "dynamic" // 从 emby 获取真实数量后调整
+)
+
+// ItemsCountsFields Emby 响应中的统计字段
+var ItemsCountsFields = []string{
+	"MovieCount", "SeriesCount", "EpisodeCount", "GameCount", "ArtistCount",
+	"ProgramCount", "GameSystemCount", "TrailerCount", "SongCount", "AlbumCount",
+	"MusicVideoCount", "BoxSetCount", "BookCount", "ItemCount",
+}
+
+// CountAdjust 动态模式下单个统计字段的调整规则
+//
+// 配置了 Value 时直接使用该值, 否则按 真实数量 * Multiplier + Offset 计算
+type CountAdjust struct {
+	// Value 固定数量
+	Value *int `yaml:"value"`
+	// Multiplier 倍数, 默认 1
+	Multiplier float64 `yaml:"multiplier"`
+	// Offset 偏移量, 可以为负数
+	Offset int `yaml:"offset"`
+}
+
+// Apply 对真实数量进行调整, 结果不小于 0
+func (ca CountAdjust) Apply(real int) int {
+	if ca.Value != nil {
+		return max(*ca.Value, 0)
+	}
+	multiplier := ca.Multiplier
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	return max(int(math.Round(float64(real)*multiplier))+ca.Offset, 0)
+}
+
 // ItemsCounts Items/Counts 接口配置
 type ItemsCounts struct {
 	// Enable 是否启用自定义媒体库数量统计
 	Enable bool `yaml:"enable"`
+	// Mode 统计模式 (static/dynamic), 默认 static
+	Mode ItemsCountsMode `yaml:"mode"`
+	// Overrides 动态模式下各字段的调整规则, key 为 Emby 响应中的字段名, 如 MovieCount
+	Overrides map[string]CountAdjust `yaml:"overrides"`
+	// CacheTtl 动态模式下真实数量的缓存时间, 单位: 秒, 默认 600
+	CacheTtl int `yaml:"cache-ttl"`
+	// PerUser 动态模式下是否按请求用户可见的媒体库分别统计
+	PerUser bool `yaml:"per-user"`
+	// ApiKey 动态模式下统计全局数量使用的 emby 服务器 api_key, 不按用户统计时必填
+	ApiKey string `yaml:"api-key"`
 	// MovieCount 电影数量
 	MovieCount int `yaml:"movie-count"`
 	// SeriesCount 剧集数量
@@ -50,6 +102,17 @@ func (ic *ItemsCounts) Init() error {
 
 	logs.Info("Items/Counts 自定义统计: 已启用")
 
+	if ic.Mode == "" {
+		ic.Mode = ItemsCountsModeStatic
+	}
+	switch ic.Mode {
+	case ItemsCountsModeStatic:
+	case ItemsCountsModeDynamic:
+		return ic.initDynamic()
+	default:
+		return fmt.Errorf("items-counts.mode 配置错误: %s, 有效值: [static dynamic]", ic.Mode)
+	}
+
 	// 验证数量配置不能为负数
 	if ic.MovieCount < 0 {
 		return fmt.Errorf("items-counts.movie-count 不能为负数: %d", ic.MovieCount)
@@ -99,6 +162,53 @@ func (ic *ItemsCounts) Init() error {
 	return nil
 }
 
+// initDynamic 动态模式配置初始化
+func (ic *ItemsCounts) initDynamic() error {
+	if ic.CacheTtl < 0 {
+		return fmt.Errorf("items-counts.cache-ttl 不能为负数: %d", ic.CacheTtl)
+	}
+	if ic.CacheTtl == 0 {
+		ic.CacheTtl = 600
+	}
+	if !ic.PerUser && strings.TrimSpace(ic.ApiKey) == "" {
+		return fmt.Errorf("items-counts.api-key 配置错误: 不按用户统计时, 需要配置 emby 服务器 api_key 用于统计全局数量")
+	}
+	for field, adjust := range ic.Overrides {
+		if !slices.Contains(ItemsCountsFields, field) {
+			return fmt.Errorf("items-counts.overrides 配置错误: 未知字段 [%s], 有效值: %v", field, ItemsCountsFields)
+		}
+		if adjust.Multiplier < 0 {
+			return fmt.Errorf("items-counts.overrides.%s.multiplier 不能为负数: %v", field, adjust.Multiplier)
+		}
+	}
+	logs.Info("Items/Counts 动态统计: 缓存 %d 秒, 按用户统计: %v, 调整字段: %d 个", ic.CacheTtl, ic.PerUser, len(ic.Overrides))
+	return nil
+}
+
+// Adjust 动态模式下对从 emby 获取的真实数量进行调整
+//
+// ItemCount 未配置调整规则时, 会加上其他字段调整前后的差值, 保持总数一致
+func (ic *ItemsCounts) Adjust(real map[string]int) map[string]int {
+	res := make(map[string]int, len(real))
+	diff := 0
+	for field, count := range real {
+		res[field] = count
+		adjust, ok := ic.Overrides[field]
+		if !ok || field == "ItemCount" {
+			continue
+		}
+		res[field] = adjust.Apply(count)
+		diff += res[field] - count
+	}
+
+	if adjust, ok := ic.Overrides["ItemCount"]; ok {
+		res["ItemCount"] = adjust.Apply(real["ItemCount"])
+	} else if _, ok := real["ItemCount"]; ok {
+		res["ItemCount"] = max(real["ItemCount"]+diff, 0)
+	}
+	return res
+}
+
 // ToJSON 生成 Emby 格式的 JSON 响应
 func (ic *ItemsCounts) ToJSON() map[string]int {
 	return map[string]int{
@@ -169,3 +169,52 @@ func TestItemsCounts_AutoCalculation(t *testing.T) {
 
 	t.Logf("自动计算结果: %d", ic.ItemCount)
 }
+
+// TestItemsCounts_Adjust 测试动态模式下的数量调整
+func TestItemsCounts_Adjust(t *testing.T) {
+	fixed := 42
+	ic := ItemsCounts{
+		Enable: true,
+		Mode:   ItemsCountsModeDynamic,
+		ApiKey: "key",
+		Overrides: map[string]CountAdjust{
+			"MovieCount":   {Multiplier: 2},
+			"SeriesCount":  {Offset: -100},
+			"EpisodeCount": {Value: &fixed},
+		},
+	}
+	if err := ic.Init(); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+	if ic.CacheTtl != 600 {
+		t.Errorf("CacheTtl = %d, expect 600", ic.CacheTtl)
+	}
+
+	real := map[string]int{"MovieCount": 10, "SeriesCount": 30, "EpisodeCount": 300, "SongCount": 5, "ItemCount": 345}
+	got := ic.Adjust(real)
+	want := map[string]int{"MovieCount": 20, "SeriesCount": 0, "EpisodeCount": 42, "SongCount": 5, "ItemCount": 345 + 10 - 30 - 258}
+	for field, count := range want {
+		if got[field] != count {
+			t.Errorf("%s = %d, expect %d", field, got[field], count)
+		}
+	}
+
+	ic.Overrides["ItemCount"] = CountAdjust{Offset: 1}
+	if got := ic.Adjust(real)["ItemCount"]; got != 346 {
+		t.Errorf("ItemCount = %d, expect 346", got)
+	}
+
+	bad := ItemsCounts{Enable: true, Mode: ItemsCountsModeDynamic, ApiKey: "key", Overrides: map[string]CountAdjust{"FooCount": {}}}
+	if err := bad.Init(); err == nil {
+		t.Errorf("未知字段应初始化失败")
+	}
+
+	noKey := ItemsCounts{Enable: true, Mode: ItemsCountsModeDynamic}
+	if err := noKey.Init(); err == nil {
+		t.Errorf("不按用户统计时缺少 api-key 应初始化失败")
+	}
+	noKey.PerUser = true
+	if err := noKey.Init(); err != nil {
+		t.Errorf("按用户统计时不需要 api-key: %v", err)
+	}
+}
@@ -1,18 +1,134 @@
 package config
 
-import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
 
 // Log 日志配置
 type Log struct {
 	DisableColor bool `yaml:"disable-color"` // 是否禁用彩色日志输出
+	// Level 全局最低输出级别, 默认 tip, 即输出所有日志
+	Level string `yaml:"level"`
+	// Format 输出格式 console|text|json
+	Format string `yaml:"format"`
+	// Levels 子系统的最低输出级别, 子系统名称为日志所在的包名, 如 emby, cache
+	Levels map[string]string `yaml:"levels"`
+	// DisableRedact 是否关闭 api_key 脱敏
+	DisableRedact bool `yaml:"disable-redact"`
+	// File 日志文件配置
+	File *LogFile `yaml:"file"`
+
+	opts logs.Options // 配置初始化转换之后的日志选项
+}
+
+// LogFile 日志文件配置
+type LogFile struct {
+	// Enable 是否输出到文件
+	Enable bool `yaml:"enable"`
+	// DisableStdout 输出到文件时, 是否不再输出到控制台
+	DisableStdout bool `yaml:"disable-stdout"`
+
+	LogRotate `yaml:",inline"`
+}
+
+// LogRotate 日志文件轮转配置
+type LogRotate struct {
+	// Path 日志文件路径, 相对路径基于数据根目录
+	Path string `yaml:"path"`
+	// MaxSize 单个文件的最大大小 (MB)
+	MaxSize int `yaml:"max-size"`
+	// MaxAge 旧文件的保留天数
+	MaxAge int `yaml:"max-age"`
+	// MaxBackups 旧文件的保留数量
+	MaxBackups int `yaml:"max-backups"`
 }
 
 // Init 配置初始化
 func (lc *Log) Init() error {
+	switch lc.Format {
+	case "", logs.FormatConsole, logs.FormatText, logs.FormatJson:
+	default:
+		return fmt.Errorf("log.format 配置错误: [%s], 有效值: [%s %s %s]", lc.Format, logs.FormatConsole, logs.FormatText, logs.FormatJson)
+	}
+	opts := logs.Options{Format: lc.Format, Redact: !lc.DisableRedact, Stdout: true}
+	if strs.AnyEmpty(lc.Level) {
+		lc.Level = "tip"
+	}
+	level, err := logs.ParseLevel(lc.Level)
+	if err != nil {
+		return fmt.Errorf("log.level 配置错误: %v", err)
+	}
+	opts.Level = level
+
+	opts.Levels = make(map[string]slog.Level, len(lc.Levels))
+	for subsystem, name := range lc.Levels {
+		level, err := logs.ParseLevel(name)
+		if err != nil {
+			return fmt.Errorf("log.levels.%s 配置错误: %v", subsystem, err)
+		}
+		opts.Levels[subsystem] = level
+	}
+
+	if lc.File == nil {
+		lc.File = new(LogFile)
+	}
+	if lc.File.Enable {
+		w, err := lc.File.writer("logs/ge2o.log")
+		if err != nil {
+			return fmt.Errorf("log.file 配置错误: %v", err)
+		}
+		opts.File = w
+		opts.Stdout = !lc.File.DisableStdout
+	}
+
+	lc.opts = opts
+	return nil
+}
+
+// Apply 应用日志配置
+func (lc *Log) Apply() error {
 	colors.SetEnabler(lc)
+	if err := logs.Configure(lc.opts); err != nil {
+		return fmt.Errorf("log 配置错误: %v", err)
+	}
 	return nil
 }
 
+// writer 初始化默认值, 并创建轮转文件
+func (lf *LogRotate) writer(defaultPath string) (*logs.RotateWriter, error) {
+	if strs.AnyEmpty(lf.Path) {
+		lf.Path = filepath.Join(DataDir, defaultPath)
+	}
+	if !filepath.IsAbs(lf.Path) {
+		lf.Path = filepath.Join(BasePath, lf.Path)
+	}
+	if lf.MaxSize == 0 {
+		lf.MaxSize = 50
+	}
+	if lf.MaxAge == 0 {
+		lf.MaxAge = 7
+	}
+	if lf.MaxBackups == 0 {
+		lf.MaxBackups = 5
+	}
+	if lf.MaxSize < 0 || lf.MaxAge < 0 || lf.MaxBackups < 0 {
+		return nil, fmt.Errorf("max-size, max-age, max-backups 不能为负数")
+	}
+	return &logs.RotateWriter{
+		Path:       lf.Path,
+		MaxSize:    int64(lf.MaxSize) * 1024 * 1024,
+		MaxAge:     time.Duration(lf.MaxAge) * 24 * time.Hour,
+		MaxBackups: lf.MaxBackups,
+	}, nil
+}
+
 // EnableColor 标记是否启用颜色输出
 func (lc *Log) EnableColor() bool {
 	return !lc.DisableColor
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// defaultTrustedCidrs 未配置可信网段时使用的默认值, 即常见的私有网段
+var defaultTrustedCidrs = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"::1/128", "fc00::/7", "fe80::/10",
+}
+
+// Network 网络配置
+type Network struct {
+	// TrustedCidrs 可信网段, 支持 ip 和 cidr
+	//
+	// 网段内的客户端视为局域网客户端, 不参与限流;
+	// 只有来自网段内的请求, 才会从 X-Forwarded-For 等请求头中解析客户端 ip;
+	// 为空时使用常见私有网段, 并信任所有来源传递的客户端 ip, 与旧版本保持一致
+	TrustedCidrs []string `yaml:"trusted-cidrs"`
+
+	// trustedNets 解析后的可信网段
+	trustedNets []*net.IPNet
+}
+
+// Init 配置初始化
+func (n *Network) Init() error {
+	cidrs := n.TrustedCidrs
+	if len(cidrs) == 0 {
+		cidrs = defaultTrustedCidrs
+	}
+	n.trustedNets = make([]*net.IPNet, 0, len(cidrs))
+	for i, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if ip := net.ParseIP(cidr); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("network.trusted-cidrs 配置错误: [%s], 不是有效的 ip 或 cidr", cidrs[i])
+		}
+		n.trustedNets = append(n.trustedNets, ipNet)
+	}
+	return nil
+}
+
+// IsTrusted 判断客户端 ip 是否处于可信网段中, 无法解析的 ip 视为不可信
+func (n *Network) IsTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range n.trustedNets {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustAllProxies 判断是否信任所有来源传递的客户端 ip, 即未配置可信网段
+func (n *Network) TrustAllProxies() bool {
+	return len(n.TrustedCidrs) == 0
+}
+
+// TrustedProxies 允许传递客户端 ip 的代理地址
+func (n *Network) TrustedProxies() []string {
+	if n.TrustAllProxies() {
+		return []string{"0.0.0.0/0", "::/0"}
+	}
+	proxies := make([]string, 0, len(n.trustedNets))
+	for _, ipNet := range n.trustedNets {
+		proxies = append(proxies, ipNet.String())
+	}
+	return proxies
+}
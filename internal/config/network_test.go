@@ -0,0 +1,57 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestNetwork_IsTrusted 测试可信网段判断
+func TestNetwork_IsTrusted(t *testing.T) {
+	tests := []struct {
+		name  string
+		cidrs []string
+		ip    string
+		want  bool
+	}{
+		{name: "默认私有网段", ip: "192.168.1.2", want: true},
+		{name: "默认本机", ip: "127.0.0.1", want: true},
+		{name: "默认外网", ip: "8.8.8.8", want: false},
+		{name: "配置单个 ip", cidrs: []string{"203.0.113.7"}, ip: "203.0.113.7", want: true},
+		{name: "配置后不再使用默认网段", cidrs: []string{"203.0.113.0/24"}, ip: "192.168.1.2", want: false},
+		{name: "无法解析的 ip", ip: "unknown", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Network{TrustedCidrs: tt.cidrs}
+			if err := n.Init(); err != nil {
+				t.Fatalf("初始化失败: %v", err)
+			}
+			if got := n.IsTrusted(tt.ip); got != tt.want {
+				t.Errorf("IsTrusted(%q) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	if err := (&Network{TrustedCidrs: []string{"192.168.1"}}).Init(); err == nil {
+		t.Error("非法网段应该初始化失败")
+	}
+}
+
+// TestNetwork_TrustedProxies 测试未配置可信网段时信任所有代理
+func TestNetwork_TrustedProxies(t *testing.T) {
+	n := new(Network)
+	if err := n.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := n.TrustedProxies(); !slices.Equal(got, []string{"0.0.0.0/0", "::/0"}) {
+		t.Errorf("未配置时应信任所有代理, got: %v", got)
+	}
+
+	n = &Network{TrustedCidrs: []string{"127.0.0.1", "172.17.0.0/16"}}
+	if err := n.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := n.TrustedProxies(); !slices.Equal(got, []string{"127.0.0.1/32", "172.17.0.0/16"}) {
+		t.Errorf("TrustedProxies() = %v", got)
+	}
+}
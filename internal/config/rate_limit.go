@@ -0,0 +1,56 @@
+package config
+
+import "fmt"
+
+// RateLimit 请求限流配置
+type RateLimit struct {
+	// Enable 是否启用限流
+	Enable bool `yaml:"enable"`
+	// Stream 播放资源接口的限流预算
+	Stream *RateLimitBudget `yaml:"stream"`
+	// Images 图片接口的限流预算
+	Images *RateLimitBudget `yaml:"images"`
+	// Default 其余接口的限流预算
+	Default *RateLimitBudget `yaml:"default"`
+}
+
+// RateLimitBudget 令牌桶预算
+type RateLimitBudget struct {
+	// Burst 令牌桶容量, 即允许的瞬时请求数
+	Burst int `yaml:"burst"`
+	// Refill 每秒补充的令牌数
+	Refill float64 `yaml:"refill"`
+}
+
+// Init 配置初始化
+func (rl *RateLimit) Init() error {
+	if !rl.Enable {
+		return nil
+	}
+
+	initBudget := func(name string, b **RateLimitBudget, burst int, refill float64) error {
+		if *b == nil {
+			*b = &RateLimitBudget{Burst: burst, Refill: refill}
+		}
+		if (*b).Burst < 0 || (*b).Refill < 0 {
+			return fmt.Errorf("rate-limit.%s 配置错误: burst 和 refill 不能为负数", name)
+		}
+		return nil
+	}
+	if err := initBudget("stream", &rl.Stream, 30, 2); err != nil {
+		return err
+	}
+	if err := initBudget("images", &rl.Images, 200, 50); err != nil {
+		return err
+	}
+	if err := initBudget("default", &rl.Default, 100, 20); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Unlimited 预算为零值时, 表示不作限制
+func (b *RateLimitBudget) Unlimited() bool {
+	return b.Burst == 0
+}
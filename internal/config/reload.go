@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"gopkg.in/yaml.v3"
+)
+
+// restartKeys 修改后需要重启才能生效的配置项, 匹配配置项本身及其所有子项
+var restartKeys = []string{
+	"ssl",
+	"admin.enable",
+	"admin.addr",
+	"access-log",
+	"analytics",
+	"log.file",
+	"cache.enable",
+	"rate-limit.enable",
+	"network",
+	"emby.host",
+	"openlist.local-tree-gen.enable",
+	"openlist.local-tree-gen.ffmpeg-enable",
+}
+
+// sensitiveSuffixes 名称以这些后缀结尾的配置项, 输出变更日志时需要隐藏取值, 如 api-key, private-key
+var sensitiveSuffixes = []string{"key", "token", "password"}
+
+// sensitiveKeys 输出变更日志时需要隐藏取值的配置项名称, 其下所有子项同样隐藏
+var sensitiveKeys = []string{"headers"}
+
+// Change 一个配置项的变更
+type Change struct {
+	// Key 配置项路径, 如 emby.strm.cdns[0].base
+	Key string
+	// Old, New 变更前后的取值, 为空表示配置项不存在
+	Old, New string
+}
+
+// String 输出变更描述, 敏感配置项的取值会被隐藏
+func (ch Change) String() string {
+	old, new := ch.Old, ch.New
+	if isSensitive(ch.Key) {
+		old, new = logs.Mask(old), logs.Mask(new)
+	}
+	return fmt.Sprintf("%s: [%s] => [%s]", ch.Key, old, new)
+}
+
+// NeedRestart 判断配置项修改后是否需要重启
+func (ch Change) NeedRestart() bool {
+	return slices.ContainsFunc(restartKeys, func(k string) bool { return matchKey(ch.Key, k) })
+}
+
+var (
+	// reloadMu 保证同一时间只有一个重新加载流程
+	reloadMu sync.Mutex
+	// reloadHooks 配置重新加载成功后的回调
+	reloadHooks []func(old, c *Config)
+)
+
+// OnReload 注册配置重新加载成功后的回调, 回调执行时全局配置已经替换为新配置
+//
+// 需要在程序初始化阶段注册
+func OnReload(hook func(old, c *Config)) {
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// Reload 重新读取当前配置文件
+//
+// 新配置校验通过, 且没有修改需要重启的配置项时, 才会整体替换全局配置,
+// 并通知各个模块; 否则保持当前配置不变
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if FilePath == "" {
+		return fmt.Errorf("未指定配置文件")
+	}
+	c, err := Load(FilePath)
+	if err != nil {
+		return err
+	}
+
+	changes, err := Diff(C(), c)
+	if err != nil {
+		return fmt.Errorf("对比配置失败: %v", err)
+	}
+	if len(changes) == 0 {
+		logs.Info("配置文件没有变化")
+		return nil
+	}
+
+	var restart []string
+	for _, ch := range changes {
+		if ch.NeedRestart() {
+			restart = append(restart, ch.String())
+		}
+	}
+	if len(restart) > 0 {
+		return fmt.Errorf("以下配置项修改后需要重启才能生效, 本次不会重新加载: %s", strings.Join(restart, "; "))
+	}
+
+	old := C()
+	if err := apply(c, old); err != nil {
+		return err
+	}
+	Set(c)
+	for _, ch := range changes {
+		logs.Info("配置变更 %s", ch)
+	}
+	for _, hook := range reloadHooks {
+		hook(old, c)
+	}
+	logs.Success("配置重新加载完成, 共 %d 项变更", len(changes))
+	return nil
+}
+
+// Diff 对比两份配置, 按配置项路径排序返回所有变更
+func Diff(old, new *Config) ([]Change, error) {
+	oldVals, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	newVals, err := flatten(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for k, ov := range oldVals {
+		if nv, ok := newVals[k]; !ok || nv != ov {
+			changes = append(changes, Change{Key: k, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range newVals {
+		if _, ok := oldVals[k]; !ok {
+			changes = append(changes, Change{Key: k, New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// flatten 将配置转换为 配置项路径 => 取值 的映射
+func flatten(c *Config) (map[string]string, error) {
+	res := map[string]string{}
+	if c == nil {
+		return res, nil
+	}
+	bytes, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := yaml.Unmarshal(bytes, &tree); err != nil {
+		return nil, err
+	}
+
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch val := v.(type) {
+		case map[string]any:
+			for k, sub := range val {
+				key := k
+				if prefix != "" {
+					key = prefix + "." + k
+				}
+				walk(key, sub)
+			}
+		case []any:
+			for i, sub := range val {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), sub)
+			}
+		case nil:
+		default:
+			res[prefix] = fmt.Sprint(val)
+		}
+	}
+	walk("", tree)
+	return res, nil
+}
+
+// matchKey 判断配置项路径 key 是否为 target 本身或其子项
+func matchKey(key, target string) bool {
+	if !strings.HasPrefix(key, target) {
+		return false
+	}
+	rest := key[len(target):]
+	return rest == "" || rest[0] == '.' || rest[0] == '['
+}
+
+// isSensitive 判断配置项路径中是否包含敏感配置项
+func isSensitive(key string) bool {
+	for _, seg := range strings.FieldsFunc(key, func(r rune) bool { return r == '.' || r == '[' }) {
+		if slices.Contains(sensitiveKeys, seg) {
+			return true
+		}
+		if slices.ContainsFunc(sensitiveSuffixes, func(suffix string) bool { return strings.HasSuffix(seg, suffix) }) {
+			return true
+		}
+	}
+	return false
+}
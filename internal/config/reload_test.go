@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// testConfigYaml 测试使用的最小配置
+const testConfigYaml = `
+emby:
+  host: http://127.0.0.1:8096
+  strm:
+    cdns:
+      - name: main
+        type: none
+        base: https://cdn.example.com
+        private-key: %KEY%
+        path-mappings:
+          - local-prefix: /media
+            remote-prefix: /
+cache:
+  expired: %EXPIRED%
+ssl:
+  enable: false
+admin:
+  addr: %ADMIN%
+`
+
+// writeTestConfig 写入测试配置文件
+func writeTestConfig(t *testing.T, path, key, expired, admin string) {
+	t.Helper()
+	content := strings.NewReplacer("%KEY%", key, "%EXPIRED%", expired, "%ADMIN%", admin).Replace(testConfigYaml)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReload 测试配置重新加载
+func TestReload(t *testing.T) {
+	oldC, oldPath, oldBase, oldHooks := C(), FilePath, BasePath, reloadHooks
+	t.Cleanup(func() {
+		Set(oldC)
+		FilePath, BasePath, reloadHooks = oldPath, oldBase, oldHooks
+	})
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeTestConfig(t, path, "secret-a", "1h", "127.0.0.1:8097")
+	if err := ReadFromFile(path); err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+
+	var notified *Config
+	reloadHooks = nil
+	OnReload(func(_, c *Config) { notified = c })
+
+	// 可以热重载的配置项
+	writeTestConfig(t, path, "secret-b", "2h", "127.0.0.1:8097")
+	before := C()
+	if err := Reload(); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if C() == before || notified != C() {
+		t.Fatal("重新加载后未替换配置或未通知订阅者")
+	}
+	if C().Cache.Expired != "2h" || C().Emby.Strm.Cdns[0].PrivateKey != "secret-b" {
+		t.Errorf("重新加载后配置不符合预期: %+v", C().Cache)
+	}
+
+	// 需要重启的配置项
+	writeTestConfig(t, path, "secret-b", "2h", "0.0.0.0:8097")
+	before = C()
+	err := Reload()
+	if err == nil || !strings.Contains(err.Error(), "admin.addr") {
+		t.Fatalf("修改管理端口应该被拒绝, err: %v", err)
+	}
+	if C() != before {
+		t.Error("拒绝重新加载后不应替换配置")
+	}
+
+	// 校验失败
+	if err := os.WriteFile(path, []byte("emby:\n  host: \"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reload(); err == nil || C() != before {
+		t.Errorf("非法配置应该被拒绝, err: %v", err)
+	}
+}
+
+// TestDiff 测试配置对比
+func TestDiff(t *testing.T) {
+	old := &Config{
+		Emby:     &Emby{Host: "http://a", Strm: &Strm{Cdns: []CdnConfig{{Name: "main", PrivateKey: "secret-a"}}}, ImageCdn: &ImageCdn{ApiKey: "apikey-a"}},
+		Openlist: &Openlist{Token: "token-a"},
+	}
+	new := &Config{
+		Emby:     &Emby{Host: "http://b", Strm: &Strm{Cdns: []CdnConfig{{Name: "main", PrivateKey: "secret-b"}, {Name: "backup"}}}, ImageCdn: &ImageCdn{ApiKey: "apikey-b"}},
+		Openlist: &Openlist{Token: "token-b"},
+	}
+
+	changes, err := Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]Change{}
+	for _, ch := range changes {
+		got[ch.Key] = ch
+	}
+
+	tests := []struct {
+		key         string
+		needRestart bool
+		hidden      string
+	}{
+		{key: "emby.host", needRestart: true},
+		{key: "emby.strm.cdns[0].private-key", hidden: "secret-b"},
+		{key: "emby.strm.cdns[1].name"},
+		{key: "emby.image-cdn.api-key", hidden: "apikey-b"},
+		{key: "openlist.token", hidden: "token-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			ch, ok := got[tt.key]
+			if !ok {
+				t.Fatalf("缺少变更 %s, 所有变更: %v", tt.key, changes)
+			}
+			if ch.NeedRestart() != tt.needRestart {
+				t.Errorf("NeedRestart() = %v, want %v", ch.NeedRestart(), tt.needRestart)
+			}
+			if tt.hidden != "" && strings.Contains(ch.String(), tt.hidden) {
+				t.Errorf("敏感配置未隐藏: %s", ch)
+			}
+		})
+	}
+	if _, ok := got["emby.strm.cdns[0].name"]; ok {
+		t.Error("未变化的配置项不应出现在变更中")
+	}
+}
+
+// TestApplyRollback 测试配置应用失败时回滚已经切换的全局状态
+func TestApplyRollback(t *testing.T) {
+	old := &Config{Log: &Log{opts: logs.Options{Format: logs.FormatConsole, Stdout: true}}, Trace: &Trace{}}
+	if err := apply(old, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 日志颜色已切换, 但日志格式非法
+	bad := &Config{Log: &Log{DisableColor: true, opts: logs.Options{Format: "bad"}}, Trace: &Trace{}}
+	if err := apply(bad, old); err == nil {
+		t.Fatal("非法配置应该应用失败")
+	}
+	if colors.ToGreen("x") == "x" {
+		t.Error("应用失败后应该回滚日志颜色配置")
+	}
+}
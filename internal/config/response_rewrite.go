@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// RewriteOpType 响应改写操作类型
+type RewriteOpType string
+
+const (
+	RewriteOpSet     RewriteOpType = "set"     // 设置值
+	RewriteOpDelete  RewriteOpType = "delete"  // 删除节点
+	RewriteOpRename  RewriteOpType = "rename"  // 重命名对象属性
+	RewriteOpReplace RewriteOpType = "replace" // 正则替换字符串值
+)
+
+// validRewriteOp 用于校验用户配置的操作类型是否合法
+var validRewriteOp = map[RewriteOpType]struct{}{
+	RewriteOpSet: {}, RewriteOpDelete: {}, RewriteOpRename: {}, RewriteOpReplace: {},
+}
+
+// RewriteOp 响应改写操作
+type RewriteOp struct {
+	// Op 操作类型
+	Op RewriteOpType `yaml:"op"`
+	// Path 操作的节点路径, 如 Items[*].MediaSources[*].Name
+	Path string `yaml:"path"`
+	// Value 设置的值, 仅 set 生效, 支持对象和数组
+	Value any `yaml:"value"`
+	// To 新的属性名, 仅 rename 生效
+	To string `yaml:"to"`
+	// Pattern 匹配字符串值的正则, 仅 replace 生效
+	Pattern string `yaml:"pattern"`
+	// Replacement 替换内容, 支持 $1 引用分组, 仅 replace 生效
+	Replacement string `yaml:"replacement"`
+
+	// path 解析后的节点路径
+	path jsons.Path
+	// valueJson set 值序列化后的 json, 每次改写时重新解析, 避免多个节点共用对象
+	valueJson string
+	// pattern 编译后的正则
+	pattern *regexp.Regexp
+}
+
+// Init 配置初始化
+func (op *RewriteOp) Init() error {
+	op.Op = RewriteOpType(strings.TrimSpace(string(op.Op)))
+	if _, ok := validRewriteOp[op.Op]; !ok {
+		return fmt.Errorf("op 配置错误: [%s], 有效值: %v", op.Op, maps.Keys(validRewriteOp))
+	}
+
+	path, err := jsons.ParsePath(op.Path)
+	if err != nil {
+		return fmt.Errorf("path 配置错误: %v", err)
+	}
+	op.path = path
+
+	switch op.Op {
+	case RewriteOpSet:
+		bytes, err := json.Marshal(op.Value)
+		if err != nil {
+			return fmt.Errorf("value 配置错误: %v", err)
+		}
+		op.valueJson = string(bytes)
+	case RewriteOpRename:
+		if strs.AnyEmpty(op.To) {
+			return errors.New("to 不能为空")
+		}
+		if _, ok := path.LastKey(); !ok {
+			return errors.New("rename 操作的 path 必须以对象属性结尾")
+		}
+	case RewriteOpReplace:
+		reg, err := regexp.Compile(op.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern 正则编译失败: [%s], %v", op.Pattern, err)
+		}
+		op.pattern = reg
+	}
+	return nil
+}
+
+// Apply 对 json 执行改写操作, 返回修改的节点数
+func (op *RewriteOp) Apply(item *jsons.Item) int {
+	switch op.Op {
+	case RewriteOpSet:
+		return item.SetPath(op.path, func() *jsons.Item {
+			val, _ := jsons.New(op.valueJson)
+			return val
+		})
+	case RewriteOpDelete:
+		return item.DelPath(op.path)
+	case RewriteOpRename:
+		return item.RenamePath(op.path, op.To)
+	case RewriteOpReplace:
+		count := 0
+		item.RangePath(op.path, func(value *jsons.Item) {
+			str, ok := value.Ti().String()
+			if !ok || !op.pattern.MatchString(str) {
+				return
+			}
+			value.Ti().Set(op.pattern.ReplaceAllString(str, op.Replacement))
+			count++
+		})
+		return count
+	}
+	return 0
+}
+
+// ResponseRewrite 响应改写规则
+//
+// 请求匹配 route 时, 对 emby 返回的 json 响应按顺序执行改写操作
+type ResponseRewrite struct {
+	// Name 规则名称, 用于日志标识
+	Name string `yaml:"name"`
+	// Route 匹配请求 uri 的正则
+	Route string `yaml:"route"`
+	// Methods 生效的请求方法, 为空则对所有方法生效
+	Methods []string `yaml:"methods"`
+	// Ops 改写操作列表
+	Ops []*RewriteOp `yaml:"ops"`
+
+	// route 编译后的路由正则
+	route *regexp.Regexp
+}
+
+// Init 配置初始化
+func (rr *ResponseRewrite) Init() error {
+	reg, err := regexp.Compile(rr.Route)
+	if err != nil || strs.AnyEmpty(rr.Route) {
+		return fmt.Errorf("route 配置错误: [%s], %v", rr.Route, err)
+	}
+	rr.route = reg
+	for i, m := range rr.Methods {
+		rr.Methods[i] = strings.ToUpper(strings.TrimSpace(m))
+	}
+
+	if len(rr.Ops) == 0 {
+		return errors.New("ops 不能为空")
+	}
+	for i, op := range rr.Ops {
+		if op == nil {
+			return fmt.Errorf("ops[%d] 不能为空", i)
+		}
+		if err := op.Init(); err != nil {
+			return fmt.Errorf("ops[%d] 配置错误: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Matches 判断请求是否需要改写
+func (rr *ResponseRewrite) Matches(method, uri string) bool {
+	if len(rr.Methods) > 0 && !slices.Contains(rr.Methods, strings.ToUpper(method)) {
+		return false
+	}
+	return rr.route.MatchString(uri)
+}
+
+// Apply 对 json 执行所有改写操作, 返回修改的节点数
+func (rr *ResponseRewrite) Apply(item *jsons.Item) int {
+	count := 0
+	for _, op := range rr.Ops {
+		count += op.Apply(item)
+	}
+	return count
+}
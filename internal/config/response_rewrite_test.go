@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+)
+
+// TestResponseRewrite_Apply 测试响应改写规则
+func TestResponseRewrite_Apply(t *testing.T) {
+	rr := &ResponseRewrite{
+		Route:   `(?i)/users/[^/]+/items/\d+`,
+		Methods: []string{"get"},
+		Ops: []*RewriteOp{
+			{Op: RewriteOpSet, Path: "UserData.Played", Value: false},
+			{Op: RewriteOpSet, Path: "Extra", Value: map[string]any{"A": []any{1, "b"}}},
+			{Op: RewriteOpDelete, Path: "MediaSources[*].Path"},
+			{Op: RewriteOpRename, Path: "Overview", To: "Taglines"},
+			{Op: RewriteOpReplace, Path: "MediaSources[*].Name", Pattern: `^\((.+)\) .*$`, Replacement: "$1"},
+		},
+	}
+	if err := rr.Init(); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	if !rr.Matches("GET", "/emby/Users/u1/Items/123?X-Emby-Token=x") || rr.Matches("POST", "/emby/Users/u1/Items/123") {
+		t.Errorf("Matches() 结果不符合预期")
+	}
+
+	item, err := jsons.New(`{"UserData":{"Played":true},"Overview":"o","MediaSources":[{"Name":"(1080p) HEVC","Path":"/a"},{"Name":"4K","Path":"/b"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := rr.Apply(item); count != 6 {
+		t.Errorf("Apply() = %d, want 6", count)
+	}
+	want := `{"Extra":{"A":[1,"b"]},"MediaSources":[{"Name":"1080p"},{"Name":"4K"}],"Taglines":"o","UserData":{"Played":false}}`
+	if got := strings.TrimSpace(item.String()); got != want {
+		t.Errorf("Apply() = %s, want %s", got, want)
+	}
+
+	bad := []*RewriteOp{
+		{Op: "move", Path: "A"},
+		{Op: RewriteOpRename, Path: "A[0]", To: "B"},
+		{Op: RewriteOpReplace, Path: "A", Pattern: "("},
+	}
+	for _, op := range bad {
+		if err := op.Init(); err == nil {
+			t.Errorf("非法的操作 %+v 应初始化失败", op)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// RouteAction 自定义路由的处理动作
+type RouteAction string
+
+const (
+	RouteActionProxyOrigin  RouteAction = "proxy-origin"  // 直接回源
+	RouteActionRedirectStrm RouteAction = "redirect-strm" // 按播放资源处理, 重定向到直链
+	RouteActionReject       RouteAction = "reject"        // 拒绝请求
+	RouteActionStatic       RouteAction = "static"        // 返回固定响应
+	RouteActionRewrite      RouteAction = "rewrite"       // 改写请求路径后继续匹配内置路由
+)
+
+// validRouteAction 用于校验用户配置的处理动作是否合法
+var validRouteAction = map[RouteAction]struct{}{
+	RouteActionProxyOrigin: {}, RouteActionRedirectStrm: {}, RouteActionReject: {},
+	RouteActionStatic: {}, RouteActionRewrite: {},
+}
+
+// RouteRule 自定义路由规则
+type RouteRule struct {
+	// Pattern 匹配请求 uri 的正则
+	Pattern string `yaml:"pattern"`
+	// Action 处理动作
+	Action RouteAction `yaml:"action"`
+	// Status 响应码, reject 默认 403, static 默认 200
+	Status int `yaml:"status"`
+	// Body 响应体, 仅 static 生效
+	Body string `yaml:"body"`
+	// ContentType 响应类型, 仅 static 生效, 默认 application/json
+	ContentType string `yaml:"content-type"`
+	// Headers 附加的响应头, 仅 static 生效
+	Headers map[string]string `yaml:"headers"`
+	// Rewrite 改写后的完整 uri 模板, 支持 $1 引用 pattern 中的分组, 仅 rewrite 生效
+	Rewrite string `yaml:"rewrite"`
+
+	// pattern 编译后的正则
+	pattern *regexp.Regexp
+}
+
+// Init 配置初始化
+func (rr *RouteRule) Init() error {
+	reg, err := regexp.Compile(rr.Pattern)
+	if err != nil || strs.AnyEmpty(rr.Pattern) {
+		return fmt.Errorf("pattern 配置错误: [%s], %v", rr.Pattern, err)
+	}
+	rr.pattern = reg
+
+	rr.Action = RouteAction(strings.TrimSpace(string(rr.Action)))
+	if _, ok := validRouteAction[rr.Action]; !ok {
+		return fmt.Errorf("action 配置错误: [%s], 有效值: %v", rr.Action, maps.Keys(validRouteAction))
+	}
+
+	if rr.Status != 0 && (rr.Status < 100 || rr.Status > 599) {
+		return fmt.Errorf("status 配置错误: %d", rr.Status)
+	}
+	switch rr.Action {
+	case RouteActionReject:
+		if rr.Status == 0 {
+			rr.Status = http.StatusForbidden
+		}
+	case RouteActionStatic:
+		if rr.Status == 0 {
+			rr.Status = http.StatusOK
+		}
+		if strs.AnyEmpty(rr.ContentType) {
+			rr.ContentType = "application/json; charset=utf-8"
+		}
+	case RouteActionRewrite:
+		if !strings.HasPrefix(rr.Rewrite, "/") {
+			return errors.New("rewrite 必须以 / 开头")
+		}
+	}
+	return nil
+}
+
+// Match 判断请求 uri 是否匹配规则
+func (rr *RouteRule) Match(uri string) bool {
+	return rr.pattern.MatchString(uri)
+}
+
+// RewriteUri 按规则改写请求 uri
+//
+// 使用第一处匹配的分组展开 rewrite 模板, 展开结果作为完整的新 uri, 不保留 uri 中未匹配的部分
+func (rr *RouteRule) RewriteUri(uri string) string {
+	match := rr.pattern.FindStringSubmatchIndex(uri)
+	if match == nil {
+		return uri
+	}
+	return string(rr.pattern.ExpandString(nil, rr.Rewrite, uri, match))
+}
+
+// Routes 自定义路由规则, 优先于内置路由按顺序匹配
+type Routes []*RouteRule
+
+// Init 配置初始化
+func (rs Routes) Init() error {
+	for i, rr := range rs {
+		if rr == nil {
+			return fmt.Errorf("routes[%d] 不能为空", i)
+		}
+		if err := rr.Init(); err != nil {
+			return fmt.Errorf("routes[%d] 配置错误: %v", i, err)
+		}
+	}
+	return nil
+}
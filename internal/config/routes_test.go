@@ -0,0 +1,59 @@
+package config
+
+import "testing"
+
+// TestRoutes_Init 测试自定义路由规则初始化
+func TestRoutes_Init(t *testing.T) {
+	tests := []struct {
+		name       string
+		rule       RouteRule
+		wantErr    bool
+		wantStatus int
+	}{
+		{name: "拒绝默认 403", rule: RouteRule{Pattern: `(?i)/system/logs`, Action: RouteActionReject}, wantStatus: 403},
+		{name: "固定响应默认 200", rule: RouteRule{Pattern: `(?i)/system/info/public`, Action: RouteActionStatic, Body: "{}"}, wantStatus: 200},
+		{name: "回源", rule: RouteRule{Pattern: `(?i)/web/`, Action: RouteActionProxyOrigin}},
+		{name: "非法动作", rule: RouteRule{Pattern: `/a`, Action: "drop"}, wantErr: true},
+		{name: "非法正则", rule: RouteRule{Pattern: `(`, Action: RouteActionReject}, wantErr: true},
+		{name: "空正则", rule: RouteRule{Action: RouteActionReject}, wantErr: true},
+		{name: "非法响应码", rule: RouteRule{Pattern: `/a`, Action: RouteActionReject, Status: 1000}, wantErr: true},
+		{name: "改写路径不以 / 开头", rule: RouteRule{Pattern: `/a`, Action: RouteActionRewrite, Rewrite: "b"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Routes{&tt.rule}.Init()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Init() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.rule.Status != tt.wantStatus {
+				t.Errorf("Status = %d, want %d", tt.rule.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestRouteRule_RewriteUri 测试改写请求路径
+func TestRouteRule_RewriteUri(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		rewrite string
+		uri     string
+		want    string
+	}{
+		{name: "完整匹配", pattern: `(?i)^/jellyfin/(.*)$`, rewrite: "/emby/$1", uri: "/jellyfin/Items/1?a=b", want: "/emby/Items/1?a=b"},
+		{name: "部分匹配时替换整个 uri", pattern: `/old/(\d+)`, rewrite: "/emby/Items/$1", uri: "/api/old/12?a=b", want: "/emby/Items/12"},
+		{name: "具名分组", pattern: `/v/(?P<id>\w+)`, rewrite: "/emby/Videos/${id}/stream", uri: "/prefix/v/abc", want: "/emby/Videos/abc/stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := &RouteRule{Pattern: tt.pattern, Action: RouteActionRewrite, Rewrite: tt.rewrite}
+			if err := rr.Init(); err != nil {
+				t.Fatal(err)
+			}
+			if got := rr.RewriteUri(tt.uri); got != tt.want {
+				t.Errorf("RewriteUri(%q) = %s, want %s", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
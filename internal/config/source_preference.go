@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/maps"
+)
+
+// SourcePreferenceType 多版本偏好规则类型
+type SourcePreferenceType string
+
+const (
+	SpTypeMapped    SourcePreferenceType = "mapped"     // 优先可映射为 CDN 直链的版本
+	SpTypeMaxHeight SourcePreferenceType = "max-height" // 优先分辨率高度不超过 max-height 的版本
+	SpTypeHdr       SourcePreferenceType = "hdr"        // 优先 HDR 版本
+	SpTypeSdr       SourcePreferenceType = "sdr"        // 优先 SDR 版本
+)
+
+// validSpType 用于校验用户配置的偏好规则类型是否合法
+var validSpType = map[SourcePreferenceType]struct{}{
+	SpTypeMapped: {}, SpTypeMaxHeight: {}, SpTypeHdr: {}, SpTypeSdr: {},
+}
+
+// SourcePreference 多版本 MediaSource 偏好规则
+//
+// 规则按配置顺序决定优先级, 越靠前的规则对排序结果影响越大
+type SourcePreference struct {
+	// Type 规则类型
+	Type SourcePreferenceType `yaml:"type"`
+	// MaxHeight 分辨率高度上限, 仅 max-height 类型生效
+	MaxHeight int `yaml:"max-height"`
+	// RemoteOnly 是否仅对外网客户端生效
+	RemoteOnly bool `yaml:"remote-only"`
+	// Clients 生效的客户端正则, 匹配 X-Emby-Client 或 User-Agent, 为空则对所有客户端生效
+	Clients string `yaml:"clients"`
+
+	// clients 编译后的客户端正则
+	clients *regexp.Regexp
+}
+
+// Init 配置初始化
+func (sp *SourcePreference) Init() error {
+	sp.Type = SourcePreferenceType(strings.TrimSpace(string(sp.Type)))
+	if _, ok := validSpType[sp.Type]; !ok {
+		return fmt.Errorf("type 配置错误: [%s], 有效值: %v", sp.Type, maps.Keys(validSpType))
+	}
+	if sp.Type == SpTypeMaxHeight && sp.MaxHeight <= 0 {
+		return fmt.Errorf("max-height 配置错误: %d, 必须大于 0", sp.MaxHeight)
+	}
+	if strings.TrimSpace(sp.Clients) != "" {
+		reg, err := regexp.Compile(sp.Clients)
+		if err != nil {
+			return fmt.Errorf("clients 正则编译失败: [%s], %v", sp.Clients, err)
+		}
+		sp.clients = reg
+	}
+	return nil
+}
+
+// Applies 判断规则是否对当前客户端生效
+func (sp *SourcePreference) Applies(client, userAgent string, remote bool) bool {
+	if sp.RemoteOnly && !remote {
+		return false
+	}
+	if sp.clients != nil && !sp.clients.MatchString(client) && !sp.clients.MatchString(userAgent) {
+		return false
+	}
+	return true
+}
+
+// SourcePreferenceRemoteSensitive 判断多版本偏好规则是否与客户端网络有关
+func (e *Emby) SourcePreferenceRemoteSensitive() bool {
+	for _, sp := range e.SourcePreferences {
+		if sp.RemoteOnly {
+			return true
+		}
+	}
+	return false
+}
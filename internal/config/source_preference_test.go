@@ -0,0 +1,68 @@
+package config
+
+import "testing"
+
+// TestSourcePreference 测试多版本偏好规则的校验与生效条件
+func TestSourcePreference(t *testing.T) {
+	invalid := []*SourcePreference{
+		{Type: "4k"},
+		{Type: SpTypeMaxHeight},
+		{Type: SpTypeHdr, Clients: "("},
+	}
+	for _, sp := range invalid {
+		if err := sp.Init(); err == nil {
+			t.Errorf("规则 %+v 应该初始化失败", *sp)
+		}
+	}
+
+	remote := &SourcePreference{Type: SpTypeMaxHeight, MaxHeight: 1080, RemoteOnly: true}
+	hdr := &SourcePreference{Type: SpTypeHdr, Clients: `(?i)infuse`}
+	for _, sp := range []*SourcePreference{remote, hdr} {
+		if err := sp.Init(); err != nil {
+			t.Fatalf("初始化失败: %v", err)
+		}
+	}
+
+	if remote.Applies("", "", false) || !remote.Applies("", "", true) {
+		t.Error("remote-only 规则生效条件不符合预期")
+	}
+	if !hdr.Applies("", "Infuse/7.7", false) || hdr.Applies("Emby Web", "Mozilla/5.0", true) {
+		t.Error("clients 规则生效条件不符合预期")
+	}
+
+	e := &Emby{SourcePreferences: []*SourcePreference{hdr}}
+	if e.SourcePreferenceRemoteSensitive() {
+		t.Error("没有 remote-only 规则时不应区分内外网")
+	}
+	e.SourcePreferences = append(e.SourcePreferences, remote)
+	if !e.SourcePreferenceRemoteSensitive() {
+		t.Error("存在 remote-only 规则时应区分内外网")
+	}
+}
+
+// TestStrm_HasMapping 测试 mapped 规则使用的路径映射判断
+func TestStrm_HasMapping(t *testing.T) {
+	s := &Strm{Cdns: []CdnConfig{{
+		Name:         "main",
+		Type:         CdnAuthTypeNone,
+		Base:         "https://cdn.example.com",
+		PathMappings: []PathMapping{{LocalPrefix: "/mnt/media", RemotePrefix: "/media"}},
+	}}}
+	if err := s.Init(); err != nil {
+		t.Fatalf("strm 初始化失败: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/mnt/media/movie/a.mkv", true},
+		{"/mnt/media2/movie/a.mkv", false},
+		{"/data/movie/a.mkv", false},
+	}
+	for _, tt := range tests {
+		if got := s.HasMapping(tt.path); got != tt.want {
+			t.Errorf("HasMapping(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// StreamPreferenceFile 音轨字幕偏好的持久化文件名称
+const StreamPreferenceFile = "stream-preferences.json"
+
+// StreamPreference 音轨字幕偏好记忆配置
+//
+// 按用户、剧集记录客户端选择的音轨和字幕, 并应用到同一剧集所有分集的 PlaybackInfo 中
+type StreamPreference struct {
+	// Enable 是否启用
+	Enable bool `yaml:"enable"`
+}
+
+// Init 配置初始化
+func (sp *StreamPreference) Init() error {
+	if !sp.Enable {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(BasePath, DataDir), os.ModePerm); err != nil {
+		return fmt.Errorf("初始化数据目录失败: %v", err)
+	}
+	return nil
+}
+
+// FilePath 获取偏好持久化文件的绝对路径
+func (sp *StreamPreference) FilePath() string {
+	return filepath.Join(BasePath, DataDir, StreamPreferenceFile)
+}
@@ -0,0 +1,23 @@
+package config
+
+// Subtitles 字幕处理配置
+type Subtitles struct {
+	// NormalizeEncoding 是否将字幕编码统一转换为 UTF-8
+	NormalizeEncoding bool `yaml:"normalize-encoding"`
+	// ConvertFormat 是否按请求地址的后缀转换字幕格式 (srt 与 vtt 互转, ass 去除样式后转换为 vtt 或 srt)
+	ConvertFormat bool `yaml:"convert-format"`
+	// CdnDelivery 是否将外挂字幕的路径映射为 CDN 直链, 并重定向客户端到直链
+	//
+	// 字幕格式与请求格式一致, 且能够成功映射时才会生效, 否则仍按上述配置处理
+	CdnDelivery bool `yaml:"cdn-delivery"`
+}
+
+// Init 配置初始化
+func (s *Subtitles) Init() error {
+	return nil
+}
+
+// NeedProcess 判断是否需要对字幕内容进行处理
+func (s *Subtitles) NeedProcess() bool {
+	return s.NormalizeEncoding || s.ConvertFormat
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
+)
+
+// Trace 链路追踪配置
+type Trace struct {
+	// Enable 是否启用链路追踪
+	Enable bool `yaml:"enable"`
+	// Endpoint OTLP/HTTP 导出地址
+	Endpoint string `yaml:"endpoint"`
+	// Headers 导出请求附加的请求头
+	Headers map[string]string `yaml:"headers"`
+	// ServiceName 上报的服务名称
+	ServiceName string `yaml:"service-name"`
+	// SampleRatio 采样比例 (0, 1]
+	SampleRatio float64 `yaml:"sample-ratio"`
+}
+
+// Init 配置初始化
+func (t *Trace) Init() error {
+	if !t.Enable {
+		return nil
+	}
+	if strs.AnyEmpty(t.Endpoint) {
+		t.Endpoint = "http://localhost:4318/v1/traces"
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("trace.endpoint 配置错误: [%s], 需要 http(s) 地址", t.Endpoint)
+	}
+	if strs.AnyEmpty(t.ServiceName) {
+		t.ServiceName = "go-emby2openlist"
+	}
+	if t.SampleRatio == 0 {
+		t.SampleRatio = 1
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("trace.sample-ratio 配置错误: %v, 取值范围 (0, 1]", t.SampleRatio)
+	}
+	return nil
+}
+
+// Apply 应用链路追踪配置, 未启用时关闭导出器
+func (t *Trace) Apply() error {
+	if !t.Enable {
+		return traces.Configure(traces.Options{})
+	}
+	err := traces.Configure(traces.Options{
+		Endpoint:    t.Endpoint,
+		Headers:     t.Headers,
+		ServiceName: t.ServiceName,
+		SampleRatio: t.SampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("trace 配置错误: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Transcode 选择性转码策略
+//
+// 启用后, 满足任意一个放行条件的请求保留转码能力, 其余请求强制直链播放
+type Transcode struct {
+	// Enable 是否启用选择性转码
+	Enable bool `yaml:"enable"`
+	// AllowUsers 允许转码的 emby 用户 id
+	AllowUsers []string `yaml:"allow-users"`
+	// AllowClients 允许转码的客户端正则, 匹配 X-Emby-Client 或 User-Agent
+	AllowClients string `yaml:"allow-clients"`
+	// AllowRemote 是否允许外网客户端转码
+	AllowRemote bool `yaml:"allow-remote"`
+	// MaxBitrate 码率上限, 单位 Mbps, 媒体码率超过上限时允许转码, 0 表示不限制
+	MaxBitrate int `yaml:"max-bitrate"`
+
+	// allowClients 编译后的客户端正则
+	allowClients *regexp.Regexp
+}
+
+// Init 配置初始化
+func (t *Transcode) Init() error {
+	if !t.Enable {
+		return nil
+	}
+
+	if strings.TrimSpace(t.AllowClients) != "" {
+		reg, err := regexp.Compile(t.AllowClients)
+		if err != nil {
+			return fmt.Errorf("allow-clients 正则编译失败: [%s], %v", t.AllowClients, err)
+		}
+		t.allowClients = reg
+	}
+
+	if t.MaxBitrate < 0 {
+		return fmt.Errorf("max-bitrate 配置错误: %d, 不能为负数", t.MaxBitrate)
+	}
+	return nil
+}
+
+// RemoteSensitive 判断转码策略是否与客户端网络有关
+func (t *Transcode) RemoteSensitive() bool {
+	return t.Enable && t.AllowRemote
+}
+
+// AllowRequest 判断请求是否满足与媒体无关的转码放行条件
+//
+// userId 需要是 api_key 所属的用户, client 和 userAgent 任意一个匹配 allow-clients 即可放行,
+// remote 表示客户端是否来自外网
+func (t *Transcode) AllowRequest(userId, client, userAgent string, remote bool) bool {
+	if !t.Enable {
+		return false
+	}
+	if userId != "" && slices.Contains(t.AllowUsers, userId) {
+		return true
+	}
+	if t.allowClients != nil && (t.allowClients.MatchString(client) || t.allowClients.MatchString(userAgent)) {
+		return true
+	}
+	return t.AllowRemote && remote
+}
+
+// ExceedsBitrate 判断媒体码率 (bps) 是否超过配置的上限
+func (t *Transcode) ExceedsBitrate(bitrate int) bool {
+	return t.Enable && t.MaxBitrate > 0 && bitrate > t.MaxBitrate*1000*1000
+}
@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+// TestTranscode_AllowRequest 测试选择性转码的放行条件
+func TestTranscode_AllowRequest(t *testing.T) {
+	policy := &Transcode{
+		Enable:       true,
+		AllowUsers:   []string{"u1"},
+		AllowClients: `(?i)^emby for android`,
+		AllowRemote:  true,
+		MaxBitrate:   20,
+	}
+	if err := policy.Init(); err != nil {
+		t.Fatalf("初始化失败: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userId string
+		client string
+		ua     string
+		remote bool
+		want   bool
+	}{
+		{name: "用户放行", userId: "u1", want: true},
+		{name: "客户端放行", client: "Emby for Android", want: true},
+		{name: "外网放行", remote: true, want: true},
+		{name: "局域网强制直链", userId: "u2", client: "Emby Web", want: false},
+		{name: "未知用户强制直链", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.AllowRequest(tt.userId, tt.client, tt.ua, tt.remote); got != tt.want {
+				t.Errorf("AllowRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !policy.ExceedsBitrate(80_000_000) || policy.ExceedsBitrate(10_000_000) {
+		t.Error("码率上限判断不符合预期")
+	}
+	if (&Transcode{}).AllowRequest("u1", "", "", true) {
+		t.Error("未启用时不应放行")
+	}
+}
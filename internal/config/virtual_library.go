@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// VirtualLibrary 媒体库虚拟化规则
+//
+// 只改写代理返回给客户端的响应, 不修改 emby 中的权限配置
+type VirtualLibrary struct {
+	// Library 媒体库名称或 id
+	Library string `yaml:"library"`
+	// Users 生效的 emby 用户 id, 为空则对所有用户生效
+	Users []string `yaml:"users"`
+	// Hide 是否对用户隐藏该媒体库
+	Hide bool `yaml:"hide"`
+	// Rename 展示给用户的媒体库名称
+	Rename string `yaml:"rename"`
+	// MergeInto 合并到的目标媒体库名称或 id, 合并后该媒体库不再单独展示
+	MergeInto string `yaml:"merge-into"`
+}
+
+// Init 配置初始化
+func (vl *VirtualLibrary) Init() error {
+	vl.Library = strings.TrimSpace(vl.Library)
+	vl.MergeInto = strings.TrimSpace(vl.MergeInto)
+	if strs.AnyEmpty(vl.Library) {
+		return errors.New("library 不能为空")
+	}
+	if vl.Hide && vl.MergeInto != "" {
+		return errors.New("hide 与 merge-into 不能同时配置")
+	}
+	if vl.MergeInto == vl.Library {
+		return errors.New("merge-into 不能与 library 相同")
+	}
+	if !vl.Hide && vl.MergeInto == "" && strs.AnyEmpty(vl.Rename) {
+		return errors.New("hide, rename, merge-into 至少需要配置一项")
+	}
+	return nil
+}
+
+// AppliesTo 判断规则是否对指定用户生效
+func (vl *VirtualLibrary) AppliesTo(userId string) bool {
+	return len(vl.Users) == 0 || slices.Contains(vl.Users, userId)
+}
+
+// Matches 判断媒体库是否为规则指定的媒体库
+func (vl *VirtualLibrary) Matches(id, name string) bool {
+	return vl.Library == id || vl.Library == name
+}
+
+// MergesInto 判断规则是否将媒体库合并到指定的目标媒体库
+func (vl *VirtualLibrary) MergesInto(id, name string) bool {
+	return vl.MergeInto != "" && (vl.MergeInto == id || vl.MergeInto == name)
+}
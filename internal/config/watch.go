@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 配置文件变化后等待的时间, 合并编辑器保存时产生的多次事件
+const watchDebounce = time.Second
+
+// Watch 监听配置文件变化和 SIGHUP 信号, 触发时自动重新加载配置
+func Watch() error {
+	if FilePath == "" {
+		return fmt.Errorf("未指定配置文件")
+	}
+	absPath, err := filepath.Abs(FilePath)
+	if err != nil {
+		return fmt.Errorf("获取配置文件路径失败: %v", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监听失败: %v", err)
+	}
+	// 监听所在目录, 编辑器通过替换文件保存时也能收到事件
+	if err := watcher.Add(filepath.Dir(absPath)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监听配置文件失败: %v", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+
+	go func() {
+		defer watcher.Close()
+		debounce := time.NewTimer(watchDebounce)
+		debounce.Stop()
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) != absPath || !ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				debounce.Reset(watchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logs.Warn("监听配置文件异常: %v", err)
+			case <-debounce.C:
+				ReloadLogged("配置文件变化")
+			case <-sigChan:
+				ReloadLogged("收到 SIGHUP 信号")
+			}
+		}
+	}()
+	return nil
+}
+
+// ReloadLogged 重新加载配置, 并输出触发来源和失败原因
+func ReloadLogged(source string) error {
+	logs.Info("%s, 重新加载配置文件: %s", source, FilePath)
+	err := Reload()
+	if err != nil {
+		logs.Error("重新加载配置失败, 继续使用当前配置: %v", err)
+	}
+	return err
+}
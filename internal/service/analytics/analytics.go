@@ -0,0 +1,435 @@
+// 播放统计, 记录经过代理的播放会话, 并提供统计报表
+package analytics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/randoms"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketSessions = []byte("sessions")
+	bucketErrors   = []byte("errors")
+)
+
+// SessionIdleTimeout 会话超过这个时间没有任何上报, 视为已结束
+const SessionIdleTimeout = 30 * time.Minute
+
+// flushInterval 会话记录批量写入数据文件的间隔
+const flushInterval = 5 * time.Second
+
+// ErrDisabled 未启用播放统计
+var ErrDisabled = errors.New("未启用播放统计")
+
+// Session 一次播放会话
+type Session struct {
+	Id            string    `json:"id"`
+	UserId        string    `json:"user_id,omitempty"`
+	ItemId        string    `json:"item_id"`
+	MediaSourceId string    `json:"media_source_id,omitempty"`
+	Version       string    `json:"version,omitempty"`
+	Cdn           string    `json:"cdn,omitempty"`
+	Size          int64     `json:"size,omitempty"`
+	RunTimeTicks  int64     `json:"run_time_ticks,omitempty"`
+	Start         time.Time `json:"start"`
+	LastSeen      time.Time `json:"last_seen"`
+	Stop          time.Time `json:"stop,omitzero"`
+	PositionTicks int64     `json:"position_ticks"`
+	Reports       int       `json:"reports"`
+}
+
+// WatchDuration 会话的观看时长, 以首次和最后一次上报的间隔计算
+func (s Session) WatchDuration() time.Duration {
+	end := s.LastSeen
+	if !s.Stop.IsZero() {
+		end = s.Stop
+	}
+	return max(end.Sub(s.Start), 0)
+}
+
+// ErrorEvent 一次 CDN 重定向失败
+type ErrorEvent struct {
+	Time   time.Time `json:"time"`
+	Cdn    string    `json:"cdn"`
+	ItemId string    `json:"item_id,omitempty"`
+	Reason string    `json:"reason"`
+}
+
+// MediaMeta PlaybackInfo 中的媒体版本信息, 用于补全会话
+type MediaMeta struct {
+	MediaSourceId string
+	Name          string
+	Size          int64
+	RunTimeTicks  int64
+}
+
+// tokenUser 客户端 token 对应的用户 id
+type tokenUser struct {
+	userId   string
+	lastSeen time.Time // 最后一次记录或使用的时间
+}
+
+// recordedMeta 已记录的媒体版本信息
+type recordedMeta struct {
+	MediaMeta
+	lastSeen time.Time // 最后一次记录或使用的时间
+}
+
+// Store 播放会话存储
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+
+	// mu 保护 active、pending、tokenUsers 和 metas
+	mu sync.Mutex
+	// active 进行中的会话, key 为 token|itemId|mediaSourceId
+	active map[string]*Session
+	// pending 等待批量写入的会话快照, key 为会话 id, 同一会话只保留最新的快照
+	pending map[string]Session
+	// done 关闭存储时停止批量写入
+	done      chan struct{}
+	closeOnce sync.Once
+	// tokenUsers 客户端 token 与用户 id 的对应关系, 与空闲会话一同清理
+	tokenUsers map[string]*tokenUser
+	// metas MediaSourceId 与媒体版本信息的对应关系, 与空闲会话一同清理
+	metas map[string]*recordedMeta
+
+	// now 当前时间, 便于测试
+	now func() time.Time
+}
+
+// Open 打开会话存储, retention 为 0 表示不清理历史记录
+func Open(path string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %v", err)
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second * 3})
+	if err != nil {
+		return nil, fmt.Errorf("打开数据文件失败: %v", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketSessions, bucketErrors} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据文件失败: %v", err)
+	}
+	s := &Store{
+		db:         db,
+		retention:  retention,
+		active:     map[string]*Session{},
+		pending:    map[string]Session{},
+		tokenUsers: map[string]*tokenUser{},
+		metas:      map[string]*recordedMeta{},
+		done:       make(chan struct{}),
+		now:        time.Now,
+	}
+	go s.loopFlush()
+	return s, nil
+}
+
+// Close 写入等待中的会话记录, 并关闭会话存储
+func (s *Store) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if err = s.Flush(); err != nil {
+			logs.Warn("写入播放会话失败: %v", err)
+		}
+		err = s.db.Close()
+	})
+	return err
+}
+
+// loopFlush 定期将等待中的会话记录批量写入数据文件
+//
+// 客户端会频繁上报播放进度, 逐条同步写入会阻塞请求处理
+func (s *Store) loopFlush() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				logs.Warn("写入播放会话失败: %v", err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Flush 将等待中的会话记录在一个事务中写入数据文件
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[string]Session{}
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSessions)
+		for id, ss := range pending {
+			bytes, err := json.Marshal(ss)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(id), bytes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RecordPlaybackInfo 记录 PlaybackInfo 请求中的用户和媒体版本信息
+func (s *Store) RecordPlaybackInfo(token, userId string, metas []MediaMeta) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strs.AllNotEmpty(token, userId) {
+		s.tokenUsers[token] = &tokenUser{userId: userId, lastSeen: now}
+	}
+	for _, m := range metas {
+		if strs.AllNotEmpty(m.MediaSourceId) {
+			s.metas[m.MediaSourceId] = &recordedMeta{MediaMeta: m, lastSeen: now}
+		}
+	}
+	s.evictIdle(now)
+}
+
+// RecordStream 记录一次播放资源重定向, 同一资源的多次请求归入同一会话
+func (s *Store) RecordStream(token, itemId, mediaSourceId, cdn string) {
+	s.update(token, itemId, mediaSourceId, func(ss *Session) {
+		if strs.AllNotEmpty(cdn) {
+			ss.Cdn = cdn
+		}
+	})
+}
+
+// RecordProgress 记录客户端的播放进度上报, stopped 表示播放结束
+func (s *Store) RecordProgress(token, itemId, mediaSourceId string, positionTicks int64, stopped bool) {
+	s.update(token, itemId, mediaSourceId, func(ss *Session) {
+		ss.Reports++
+		if positionTicks > 0 {
+			ss.PositionTicks = positionTicks
+		}
+		if stopped {
+			ss.Stop = ss.LastSeen
+		}
+	})
+}
+
+// RecordError 记录一次 CDN 重定向失败
+func (s *Store) RecordError(cdn, itemId, reason string) {
+	e := ErrorEvent{Time: s.now(), Cdn: cdn, ItemId: itemId, Reason: reason}
+	if err := s.put(bucketErrors, timeKey(e.Time), e); err != nil {
+		logs.Warn("记录 CDN 错误失败: %v", err)
+	}
+}
+
+// update 找到进行中的会话 (不存在则新建), 修改后等待批量写入
+func (s *Store) update(token, itemId, mediaSourceId string, fn func(ss *Session)) {
+	if strs.AnyEmpty(itemId) {
+		return
+	}
+	now := s.now()
+	key := token + "|" + itemId + "|" + mediaSourceId
+
+	s.mu.Lock()
+	ss, ok := s.active[key]
+	if !ok || !ss.Stop.IsZero() || now.Sub(ss.LastSeen) > SessionIdleTimeout {
+		ss = s.newSession(token, itemId, mediaSourceId, now)
+		s.active[key] = ss
+	}
+	ss.LastSeen = now
+	if tu, ok := s.tokenUsers[token]; ok {
+		tu.lastSeen = now
+	}
+	if m, ok := s.metas[mediaSourceId]; ok {
+		m.lastSeen = now
+	}
+	fn(ss)
+	s.pending[ss.Id] = *ss
+	if !ss.Stop.IsZero() {
+		delete(s.active, key)
+	}
+	s.evictIdle(now)
+	s.mu.Unlock()
+}
+
+// newSession 新建会话, 并从已记录的信息中补全用户和媒体版本
+func (s *Store) newSession(token, itemId, mediaSourceId string, now time.Time) *Session {
+	ss := &Session{
+		Id:            string(timeKey(now)),
+		ItemId:        itemId,
+		MediaSourceId: mediaSourceId,
+		Start:         now,
+	}
+	if tu, ok := s.tokenUsers[token]; ok {
+		ss.UserId = tu.userId
+	}
+	if m, ok := s.metas[mediaSourceId]; ok {
+		ss.Version, ss.Size, ss.RunTimeTicks = m.Name, m.Size, m.RunTimeTicks
+	}
+	return ss
+}
+
+// Active 获取进行中的会话, 按最后上报时间倒序
+func (s *Store) Active() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evictIdle(s.now())
+	res := make([]Session, 0, len(s.active))
+	for _, ss := range s.active {
+		res = append(res, *ss)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].LastSeen.After(res[j].LastSeen) })
+	return res
+}
+
+// evictIdle 移除长时间没有上报的会话, 以及长时间没有使用的用户和媒体版本信息, 调用方需持有锁
+func (s *Store) evictIdle(now time.Time) {
+	for key, ss := range s.active {
+		if now.Sub(ss.LastSeen) > SessionIdleTimeout {
+			delete(s.active, key)
+		}
+	}
+	for token, tu := range s.tokenUsers {
+		if now.Sub(tu.lastSeen) > SessionIdleTimeout {
+			delete(s.tokenUsers, token)
+		}
+	}
+	for id, m := range s.metas {
+		if now.Sub(m.lastSeen) > SessionIdleTimeout {
+			delete(s.metas, id)
+		}
+	}
+}
+
+// put 序列化并写入记录
+func (s *Store) put(bucket, key []byte, value any) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, bytes)
+	})
+}
+
+// Cleanup 清理超出保留时长的记录
+func (s *Store) Cleanup() error {
+	if s.retention <= 0 {
+		return nil
+	}
+	before := fmt.Sprintf("%020d", s.now().Add(-s.retention).UnixNano())
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSessions, bucketErrors} {
+			c := tx.Bucket(name).Cursor()
+			for k, _ := c.First(); k != nil && string(k) < before; k, _ = c.Next() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// timeKey 按时间排序的记录 key
+func timeKey(t time.Time) []byte {
+	return []byte(fmt.Sprintf("%020d-%s", t.UnixNano(), randoms.RandomHex(4)))
+}
+
+// std 全局会话存储, 未启用时为空
+var std *Store
+
+// Init 根据配置文件初始化播放统计
+func Init() error {
+	conf := config.C().Analytics
+	if !conf.Enable {
+		return nil
+	}
+	store, err := Open(conf.Path, time.Duration(conf.RetentionDays)*24*time.Hour)
+	if err != nil {
+		return fmt.Errorf("初始化播放统计失败: %v", err)
+	}
+	std = store
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if err := std.Cleanup(); err != nil {
+				logs.Warn("清理播放统计历史记录失败: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+	logs.Success("播放统计已启用, 数据文件: %s", conf.Path)
+	return nil
+}
+
+// Close 关闭全局会话存储, 写入等待中的会话记录, 未启用时忽略
+func Close() error {
+	if std == nil {
+		return nil
+	}
+	return std.Close()
+}
+
+// Default 获取全局会话存储
+func Default() (*Store, error) {
+	if std == nil {
+		return nil, ErrDisabled
+	}
+	return std, nil
+}
+
+// RecordPlaybackInfo 见 Store.RecordPlaybackInfo, 未启用时忽略
+func RecordPlaybackInfo(token, userId string, metas []MediaMeta) {
+	if std != nil {
+		std.RecordPlaybackInfo(token, userId, metas)
+	}
+}
+
+// RecordStream 见 Store.RecordStream, 未启用时忽略
+func RecordStream(token, itemId, mediaSourceId, cdn string) {
+	if std != nil {
+		std.RecordStream(token, itemId, mediaSourceId, cdn)
+	}
+}
+
+// RecordProgress 见 Store.RecordProgress, 未启用时忽略
+func RecordProgress(token, itemId, mediaSourceId string, positionTicks int64, stopped bool) {
+	if std != nil {
+		std.RecordProgress(token, itemId, mediaSourceId, positionTicks, stopped)
+	}
+}
+
+// RecordError 见 Store.RecordError, 未启用时忽略
+func RecordError(cdn, itemId, reason string) {
+	if std != nil {
+		std.RecordError(cdn, itemId, reason)
+	}
+}
@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTest 打开一个使用假时钟的临时会话存储
+func openTest(t *testing.T) (*Store, *time.Time) {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "analytics.db"), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	now := time.Date(2025, 1, 1, 20, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+// TestSessions 测试会话的创建、合并与结束
+func TestSessions(t *testing.T) {
+	s, now := openTest(t)
+	start := *now
+
+	s.RecordPlaybackInfo("token1", "user1", []MediaMeta{{MediaSourceId: "ms1", Name: "4K", Size: 1000, RunTimeTicks: int64(time.Hour / 100)}})
+	s.RecordStream("token1", "item1", "ms1", "main")
+	*now = now.Add(10 * time.Minute)
+	s.RecordStream("token1", "item1", "ms1", "main")
+	s.RecordProgress("token1", "item1", "ms1", 600*10_000_000, false)
+	*now = now.Add(20 * time.Minute)
+	s.RecordProgress("token1", "item1", "ms1", 1800*10_000_000, true)
+
+	// 结束后再次播放, 产生新的会话
+	*now = now.Add(time.Minute)
+	s.RecordStream("token1", "item1", "ms1", "backup")
+
+	// 未知用户, 没有进度上报
+	s.RecordStream("token2", "item2", "ms2", "backup")
+
+	sessions, err := s.Sessions(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("会话数量 = %d, want 3", len(sessions))
+	}
+	first := sessions[0]
+	if first.UserId != "user1" || first.Version != "4K" || first.Cdn != "main" || first.Reports != 2 {
+		t.Errorf("会话字段不符合预期: %+v", first)
+	}
+	if first.WatchDuration() != 30*time.Minute || first.EstimatedBytes() != 500 {
+		t.Errorf("观看时长 = %v, 流量 = %d, want 30m0s, 500", first.WatchDuration(), first.EstimatedBytes())
+	}
+
+	items, _ := s.TopItems(start, 1)
+	if len(items) != 1 || items[0].ItemId != "item1" || items[0].Plays != 2 || items[0].WatchSeconds != 1800 {
+		t.Errorf("TopItems = %+v", items)
+	}
+
+	users, _ := s.UserWatchTime(start)
+	if len(users) != 2 || users[0].UserId != "user1" || users[1].UserId != "" {
+		t.Errorf("UserWatchTime = %+v", users)
+	}
+
+	cdns, _ := s.CdnTraffic(start)
+	want := []CdnStat{{Cdn: "backup", Sessions: 2, EstimatedBytes: 1000}, {Cdn: "main", Sessions: 1, EstimatedBytes: 500}}
+	if len(cdns) != len(want) || cdns[0] != want[0] || cdns[1] != want[1] {
+		t.Errorf("CdnTraffic = %+v, want %+v", cdns, want)
+	}
+}
+
+// TestEvictRecordedInfo 测试用户和媒体版本信息与空闲会话一同清理
+func TestEvictRecordedInfo(t *testing.T) {
+	s, now := openTest(t)
+
+	s.RecordPlaybackInfo("token1", "user1", []MediaMeta{{MediaSourceId: "ms1", Name: "4K"}})
+	s.RecordPlaybackInfo("token2", "user2", []MediaMeta{{MediaSourceId: "ms2", Name: "1080p"}})
+	*now = now.Add(20 * time.Minute)
+	s.RecordStream("token1", "item1", "ms1", "main")
+
+	// token1 仍在播放, token2 超过空闲时间后被清理
+	*now = now.Add(20 * time.Minute)
+	s.RecordProgress("token1", "item1", "ms1", 1, false)
+	s.mu.Lock()
+	_, ok1 := s.tokenUsers["token1"]
+	_, ok2 := s.tokenUsers["token2"]
+	users, metas := len(s.tokenUsers), len(s.metas)
+	s.mu.Unlock()
+	if !ok1 || ok2 || users != 1 || metas != 1 {
+		t.Errorf("tokenUsers: token1=%v token2=%v, 用户数 = %d, 版本数 = %d, want true false 1 1", ok1, ok2, users, metas)
+	}
+
+	*now = now.Add(SessionIdleTimeout + time.Minute)
+	if active := s.Active(); len(active) != 0 {
+		t.Errorf("进行中的会话数量 = %d, want 0", len(active))
+	}
+	s.mu.Lock()
+	users, metas = len(s.tokenUsers), len(s.metas)
+	s.mu.Unlock()
+	if users != 0 || metas != 0 {
+		t.Errorf("用户数 = %d, 版本数 = %d, want 0", users, metas)
+	}
+}
+
+// TestCdnErrorsAndCleanup 测试错误统计与历史记录清理
+func TestCdnErrorsAndCleanup(t *testing.T) {
+	s, now := openTest(t)
+	start := *now
+
+	s.RecordError("main", "item1", "签名失败")
+	s.RecordStream("token1", "item1", "ms1", "main")
+	*now = now.Add(time.Hour)
+	s.RecordError("main", "item2", "超时")
+	s.RecordError("", "item3", "未匹配到路径映射")
+
+	errs, err := s.CdnErrors(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 || errs[0].Cdn != "main" || errs[0].Count != 2 || errs[0].LastReason != "超时" {
+		t.Errorf("CdnErrors = %+v", errs)
+	}
+
+	*now = start.Add(24*time.Hour + 30*time.Minute)
+	if err := s.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	sessions, _ := s.Sessions(start)
+	errs, _ = s.CdnErrors(start)
+	if len(sessions) != 0 || len(errs) != 2 || errs[0].Count != 1 {
+		t.Errorf("清理后 sessions = %+v, errors = %+v", sessions, errs)
+	}
+}
+
+// TestFlush 测试会话记录批量写入, 同一会话只写入最新的快照
+func TestFlush(t *testing.T) {
+	s, now := openTest(t)
+	start := *now
+
+	count := func() int {
+		n := 0
+		s.db.View(func(tx *bolt.Tx) error {
+			n = tx.Bucket(bucketSessions).Stats().KeyN
+			return nil
+		})
+		return n
+	}
+
+	s.RecordStream("token1", "item1", "ms1", "main")
+	for i := 1; i <= 10; i++ {
+		*now = now.Add(time.Minute)
+		s.RecordProgress("token1", "item1", "ms1", int64(i)*60*10_000_000, false)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("批量写入之前不应写入数据文件, 记录数: %d", n)
+	}
+
+	sessions, err := s.Sessions(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].Reports != 10 || count() != 1 {
+		t.Errorf("会话 = %+v, 记录数 = %d, want 1 个会话, 10 次上报", sessions, count())
+	}
+}
@@ -0,0 +1,221 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ItemStat 单个项目的播放统计
+type ItemStat struct {
+	ItemId       string `json:"item_id"`
+	Plays        int    `json:"plays"`
+	WatchSeconds int64  `json:"watch_seconds"`
+}
+
+// UserStat 单个用户的观看统计
+type UserStat struct {
+	UserId       string `json:"user_id"`
+	Sessions     int    `json:"sessions"`
+	WatchSeconds int64  `json:"watch_seconds"`
+}
+
+// CdnStat 单个 CDN 的流量估算
+type CdnStat struct {
+	Cdn            string `json:"cdn"`
+	Sessions       int    `json:"sessions"`
+	EstimatedBytes int64  `json:"estimated_bytes"`
+}
+
+// ErrorStat 单个 CDN 的错误统计
+type ErrorStat struct {
+	Cdn        string    `json:"cdn"`
+	Count      int       `json:"count"`
+	LastReason string    `json:"last_reason"`
+	LastTime   time.Time `json:"last_time"`
+}
+
+// Sessions 获取 since 之后开始的所有会话
+func (s *Store) Sessions(since time.Time) ([]Session, error) {
+	var res []Session
+	err := s.scan(bucketSessions, since, func(v []byte) error {
+		var ss Session
+		if err := json.Unmarshal(v, &ss); err != nil {
+			return err
+		}
+		res = append(res, ss)
+		return nil
+	})
+	return res, err
+}
+
+// TopItems 按播放次数排序的项目统计, limit 小于等于 0 时返回全部
+func (s *Store) TopItems(since time.Time, limit int) ([]ItemStat, error) {
+	sessions, err := s.Sessions(since)
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]*ItemStat{}
+	for _, ss := range sessions {
+		st, ok := stats[ss.ItemId]
+		if !ok {
+			st = &ItemStat{ItemId: ss.ItemId}
+			stats[ss.ItemId] = st
+		}
+		st.Plays++
+		st.WatchSeconds += int64(ss.WatchDuration().Seconds())
+	}
+
+	res := make([]ItemStat, 0, len(stats))
+	for _, st := range stats {
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Plays != res[j].Plays {
+			return res[i].Plays > res[j].Plays
+		}
+		if res[i].WatchSeconds != res[j].WatchSeconds {
+			return res[i].WatchSeconds > res[j].WatchSeconds
+		}
+		return res[i].ItemId < res[j].ItemId
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// UserWatchTime 按观看时长排序的用户统计, 无法识别用户的会话归入空用户
+func (s *Store) UserWatchTime(since time.Time) ([]UserStat, error) {
+	sessions, err := s.Sessions(since)
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]*UserStat{}
+	for _, ss := range sessions {
+		st, ok := stats[ss.UserId]
+		if !ok {
+			st = &UserStat{UserId: ss.UserId}
+			stats[ss.UserId] = st
+		}
+		st.Sessions++
+		st.WatchSeconds += int64(ss.WatchDuration().Seconds())
+	}
+
+	res := make([]UserStat, 0, len(stats))
+	for _, st := range stats {
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].WatchSeconds != res[j].WatchSeconds {
+			return res[i].WatchSeconds > res[j].WatchSeconds
+		}
+		return res[i].UserId < res[j].UserId
+	})
+	return res, nil
+}
+
+// CdnTraffic 按估算流量排序的 CDN 统计
+func (s *Store) CdnTraffic(since time.Time) ([]CdnStat, error) {
+	sessions, err := s.Sessions(since)
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]*CdnStat{}
+	for _, ss := range sessions {
+		if ss.Cdn == "" {
+			continue
+		}
+		st, ok := stats[ss.Cdn]
+		if !ok {
+			st = &CdnStat{Cdn: ss.Cdn}
+			stats[ss.Cdn] = st
+		}
+		st.Sessions++
+		st.EstimatedBytes += ss.EstimatedBytes()
+	}
+
+	res := make([]CdnStat, 0, len(stats))
+	for _, st := range stats {
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].EstimatedBytes != res[j].EstimatedBytes {
+			return res[i].EstimatedBytes > res[j].EstimatedBytes
+		}
+		return res[i].Cdn < res[j].Cdn
+	})
+	return res, nil
+}
+
+// EstimatedBytes 估算会话消耗的 CDN 流量
+//
+// 按观看时长占影片时长的比例折算文件大小,
+// 客户端没有上报过进度或影片时长未知时, 按整个文件计算
+func (s Session) EstimatedBytes() int64 {
+	if s.Size <= 0 {
+		return 0
+	}
+	if s.Reports == 0 || s.RunTimeTicks <= 0 {
+		return s.Size
+	}
+	runtime := time.Duration(s.RunTimeTicks * 100)
+	ratio := min(float64(s.WatchDuration())/float64(runtime), 1)
+	return int64(float64(s.Size) * ratio)
+}
+
+// CdnErrors 按错误次数排序的 CDN 错误统计
+func (s *Store) CdnErrors(since time.Time) ([]ErrorStat, error) {
+	stats := map[string]*ErrorStat{}
+	err := s.scan(bucketErrors, since, func(v []byte) error {
+		var e ErrorEvent
+		if err := json.Unmarshal(v, &e); err != nil {
+			return err
+		}
+		st, ok := stats[e.Cdn]
+		if !ok {
+			st = &ErrorStat{Cdn: e.Cdn}
+			stats[e.Cdn] = st
+		}
+		st.Count++
+		if !e.Time.Before(st.LastTime) {
+			st.LastTime, st.LastReason = e.Time, e.Reason
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]ErrorStat, 0, len(stats))
+	for _, st := range stats {
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Cdn < res[j].Cdn
+	})
+	return res, nil
+}
+
+// scan 按时间顺序遍历 since 之后的记录, 遍历前先写入等待中的会话记录
+func (s *Store) scan(bucket []byte, since time.Time, fn func(v []byte) error) error {
+	if err := s.Flush(); err != nil {
+		return fmt.Errorf("写入播放会话失败: %v", err)
+	}
+	from := fmt.Appendf(nil, "%020d", since.UnixNano())
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek(from); k != nil; k, v = c.Next() {
+			if err := fn(v); err != nil {
+				return fmt.Errorf("解析记录 %s 失败: %v", k, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,48 @@
+package emby
+
+import (
+	"strconv"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/analytics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordPlaybackAnalytics 记录 PlaybackInfo 中的用户和媒体版本信息, 用于补全播放会话
+func recordPlaybackAnalytics(c *gin.Context, itemInfo ItemInfo, mediaSources *jsons.Item) {
+	metas := make([]analytics.MediaMeta, 0, mediaSources.Len())
+	mediaSources.RangeArr(func(_ int, source *jsons.Item) error {
+		meta := analytics.MediaMeta{}
+		meta.MediaSourceId, _ = source.Attr("Id").String()
+		meta.Name, _ = source.Attr("Name").String()
+		meta.Size, _ = source.Attr("Size").Int64()
+		meta.RunTimeTicks, _ = source.Attr("RunTimeTicks").Int64()
+		metas = append(metas, meta)
+		return nil
+	})
+	analytics.RecordPlaybackInfo(itemInfo.ApiKey, c.Query("UserId"), metas)
+}
+
+// recordPlayingAnalytics 记录客户端上报的播放进度
+func recordPlayingAnalytics(c *gin.Context, bodyJson *jsons.Item, stopped bool) {
+	itemId, _ := bodyJson.Attr("ItemId").String()
+	if itemIdNum, ok := bodyJson.Attr("ItemId").Int(); ok {
+		itemId = strconv.Itoa(itemIdNum)
+	}
+	msId, _ := bodyJson.Attr("MediaSourceId").String()
+	positionTicks, _ := bodyJson.Attr("PositionTicks").Int64()
+	_, _, apiKey := getApiKey(c)
+	analytics.RecordProgress(apiKey, itemId, msId, positionTicks, stopped)
+}
+
+// checkCdnErr 记录 CDN 重定向失败后, 按错误处理策略返回响应
+//
+// 返回 true 表示请求已经被处理
+func checkCdnErr(c *gin.Context, cdn string, itemInfo ItemInfo, err error) bool {
+	if err != nil {
+		recordCdnError(cdn, err)
+		analytics.RecordError(cdn, itemInfo.Id, err.Error())
+	}
+	return checkErr(c, err)
+}
@@ -1,9 +1,11 @@
 package emby
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/model"
@@ -19,7 +21,7 @@ import (
 // 如果请求是失败的响应, 会直接返回客户端, 并在第二个参数中返回 false
 func proxyAndSetRespHeader(c *gin.Context) (model.HttpRes[*jsons.Item], bool) {
 	c.Request.Header.Del("Accept-Encoding")
-	res, respHeader := RawFetch(c.Request.URL.String(), c.Request.Method, c.Request.Header, c.Request.Body)
+	res, respHeader := RawFetch(c.Request.Context(), c.Request.URL.String(), c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
 		return res, false
@@ -29,13 +31,15 @@ func proxyAndSetRespHeader(c *gin.Context) (model.HttpRes[*jsons.Item], bool) {
 }
 
 // Fetch 请求 emby api 接口, 使用 map 请求体
-func Fetch(uri, method string, header http.Header, body map[string]any) (model.HttpRes[*jsons.Item], http.Header) {
-	return RawFetch(uri, method, header, https.MapBody(body))
+func Fetch(ctx context.Context, uri, method string, header http.Header, body map[string]any) (model.HttpRes[*jsons.Item], http.Header) {
+	return RawFetch(ctx, uri, method, header, https.MapBody(body))
 }
 
 // RawFetch 请求 emby api 接口, 使用流式请求体
-func RawFetch(uri, method string, header http.Header, body io.ReadCloser) (model.HttpRes[*jsons.Item], http.Header) {
-	u := config.C.Emby.Host + uri
+//
+// ctx 只用于传递追踪信息, 请求不随 ctx 取消
+func RawFetch(ctx context.Context, uri, method string, header http.Header, body io.ReadCloser) (model.HttpRes[*jsons.Item], http.Header) {
+	u := config.C().Emby.Host + uri
 
 	// 构造请求头, 发出请求
 	if header == nil {
@@ -45,8 +49,11 @@ func RawFetch(uri, method string, header http.Header, body io.ReadCloser) (model
 		header.Set("Content-Type", "application/json;charset=utf-8")
 	}
 
-	resp, err := https.Request(method, u).Header(header).Body(body).Do()
+	start := time.Now()
+	resp, err := https.Request(method, u).Context(context.WithoutCancel(ctx)).Header(header).Body(body).Do()
+	upstreamDuration.ObserveSince(start, upstreamKindApi)
 	if err != nil {
+		upstreamErrors.Inc(upstreamKindApi)
 		return model.HttpRes[*jsons.Item]{Code: http.StatusBadRequest, Msg: "请求发送失败: " + err.Error()}, nil
 	}
 	defer resp.Body.Close()
@@ -54,6 +61,7 @@ func RawFetch(uri, method string, header http.Header, body io.ReadCloser) (model
 	// 读取响应
 	result, err := jsons.Read(resp.Body)
 	if err != nil {
+		upstreamErrors.Inc(upstreamKindApi)
 		return model.HttpRes[*jsons.Item]{Code: http.StatusBadRequest, Msg: "解析响应失败: " + err.Error()}, nil
 	}
 	return model.HttpRes[*jsons.Item]{Code: http.StatusOK, Data: result}, resp.Header
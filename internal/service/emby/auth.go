@@ -3,16 +3,18 @@ package emby
 import (
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
 
 	"github.com/gin-gonic/gin"
 )
@@ -54,37 +56,31 @@ var AuthorizationTokenExtractReg = regexp.MustCompile(`(?i)token="([^"]+)"`)
 // 说明这个 api_key 是客户端伪造的, 阻断客户端的请求
 func ApiKeyChecker() gin.HandlerFunc {
 
-	patterns := []*regexp.Regexp{
-		regexp.MustCompile(constant.Reg_ResourceStream),
-		regexp.MustCompile(constant.Reg_PlaybackInfo),
-		regexp.MustCompile(constant.Reg_ItemDownload),
-		regexp.MustCompile(constant.Reg_ItemSyncDownload),
-		regexp.MustCompile(constant.Reg_UserItems),
+	checkRoutes := map[routes.Id]struct{}{
+		routes.ResourceStream:   {},
+		routes.PlaybackInfo:     {},
+		routes.ItemDownload:     {},
+		routes.ItemSyncDownload: {},
+		routes.UserItems:        {},
 	}
 
 	return func(c *gin.Context) {
 		// 1 取出 api_key
 		kType, kName, apiKey := getApiKey(c)
 
-		// 2 如果该 key 已经是被信任的, 跳过校验
-		if _, ok := validApiKeys.Load(apiKey); ok {
+		// 2 判断当前请求的 uri 是否需要被校验
+		if _, ok := checkRoutes[routes.IdOf(c)]; !ok {
 			return
 		}
 
-		// 3 判断当前请求的 uri 是否需要被校验
-		needCheck := false
-		for _, pattern := range patterns {
-			if pattern.MatchString(c.Request.RequestURI) {
-				needCheck = true
-				break
-			}
-		}
-		if !needCheck {
+		// 3 如果该 key 已经是被信任的, 跳过校验
+		if _, ok := validApiKeys.Load(apiKey); ok {
+			apiKeyChecks.Inc(apiKeyTrusted)
 			return
 		}
 
 		// 4 发出请求, 验证 api_key
-		u := config.C.Emby.Host + AuthUri
+		u := config.C().Emby.Host + AuthUri
 		var header http.Header
 		if kType == Query {
 			u = urls.AppendArgs(u, kName, apiKey)
@@ -92,9 +88,10 @@ func ApiKeyChecker() gin.HandlerFunc {
 			header = make(http.Header)
 			header.Set(kName, apiKey)
 		}
-		resp, err := https.Get(u).Header(header).Do()
+		resp, err := https.Get(u).Context(c.Request.Context()).Header(header).Do()
 		if err != nil {
 			logs.Error("鉴权失败: %v", err)
+			apiKeyChecks.Inc(apiKeyError)
 			c.Abort()
 			return
 		}
@@ -108,16 +105,65 @@ func ApiKeyChecker() gin.HandlerFunc {
 
 		// 5 判断是否被源服务器拒绝
 		if resp.StatusCode == http.StatusUnauthorized && respBody == UnauthorizedResp {
+			apiKeyChecks.Inc(apiKeyRejected)
 			c.String(http.StatusUnauthorized, "鉴权失败")
 			c.Abort()
 			return
 		}
 
 		// 6 校验通过, 加入信任集合
+		apiKeyChecks.Inc(apiKeyPassed)
 		validApiKeys.Store(apiKey, struct{}{})
 	}
 }
 
+// RequestApiKey 获取客户端请求中携带的 api_key
+func RequestApiKey(c *gin.Context) string {
+	_, _, apiKey := getApiKey(c)
+	return apiKey
+}
+
+// tokenUserExpired api_key 所属用户的缓存时长
+const tokenUserExpired = time.Minute * 30
+
+// tokenUser 缓存的 api_key 所属用户
+type tokenUser struct {
+	id      string    // emby 用户 id
+	expired time.Time // 过期时间
+}
+
+// tokenUsers 已经查询过的 api_key 所属用户, 只缓存查询成功的结果
+//
+// 与 validApiKeys 相同, 合法的 api_key 个数有限, 不作大小限制
+var tokenUsers = sync.Map{}
+
+// RequestUserId 获取客户端请求中 api_key 所属的 emby 用户 id
+//
+// 用户 id 由 emby 根据 api_key 返回, 不使用客户端传递的 UserId 参数, 防止伪造;
+// 请求没有携带 api_key, 或者查询失败时返回空字符串
+func RequestUserId(c *gin.Context) string {
+	apiKey := RequestApiKey(c)
+	if strs.AnyEmpty(apiKey) {
+		return ""
+	}
+	if tu, ok := tokenUsers.Load(apiKey); ok && time.Now().Before(tu.(tokenUser).expired) {
+		return tu.(tokenUser).id
+	}
+
+	uri := "/emby/Users/Me?" + QueryApiKeyName + "=" + url.QueryEscape(apiKey)
+	res, _ := Fetch(c.Request.Context(), uri, http.MethodGet, nil, nil)
+	if res.Code != http.StatusOK {
+		logs.Warn("查询 api_key 所属用户失败: %s", res.Msg)
+		return ""
+	}
+	userId, _ := res.Data.Attr("Id").String()
+	if strs.AnyEmpty(userId) {
+		return ""
+	}
+	tokenUsers.Store(apiKey, tokenUser{id: userId, expired: time.Now().Add(tokenUserExpired)})
+	return userId
+}
+
 // getApiKey 获取请求中的 api_key 信息
 func getApiKey(c *gin.Context) (keyType ApiKeyType, keyName string, apiKey string) {
 	if c == nil {
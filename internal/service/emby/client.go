@@ -0,0 +1,68 @@
+package emby
+
+import (
+	"regexp"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientRuleGinKey 匹配到的客户端规则存放在 gin 上下文中的 key
+const ClientRuleGinKey = "clientRule"
+
+// authorizationFieldRegs 从 X-Emby-Authorization 头中提取客户端字段
+var authorizationFieldRegs = map[string]*regexp.Regexp{
+	"Client":     regexp.MustCompile(`(?i)\bclient="([^"]*)"`),
+	"DeviceName": regexp.MustCompile(`(?i)\bdevice="([^"]*)"`),
+}
+
+// ClientInfo 客户端特征信息
+type ClientInfo struct {
+	UserAgent  string // User-Agent 请求头
+	Client     string // X-Emby-Client
+	DeviceName string // X-Emby-Device-Name
+}
+
+// resolveClientInfo 解析客户端特征信息
+//
+// 依次从请求头、query 参数、X-Emby-Authorization 头中获取
+func resolveClientInfo(c *gin.Context) ClientInfo {
+	info := ClientInfo{UserAgent: c.Request.UserAgent()}
+
+	fromAuth := func(field string) string {
+		for _, name := range []string{HeaderFullAuthName, HeaderAuthName} {
+			matches := authorizationFieldRegs[field].FindStringSubmatch(c.GetHeader(name))
+			if len(matches) > 1 {
+				return matches[1]
+			}
+		}
+		return ""
+	}
+
+	lookup := func(name, field string) string {
+		if v := c.GetHeader(name); strs.AllNotEmpty(v) {
+			return v
+		}
+		if v := c.Query(name); strs.AllNotEmpty(v) {
+			return v
+		}
+		return fromAuth(field)
+	}
+
+	info.Client = lookup("X-Emby-Client", "Client")
+	info.DeviceName = lookup("X-Emby-Device-Name", "DeviceName")
+	return info
+}
+
+// matchClientRule 匹配当前请求的客户端规则, 匹配结果会缓存在 gin 上下文中
+func matchClientRule(c *gin.Context) *config.ClientRule {
+	if rule, ok := c.Get(ClientRuleGinKey); ok {
+		return rule.(*config.ClientRule)
+	}
+	info := resolveClientInfo(c)
+	rule := config.C().Emby.MatchClientRule(info.UserAgent, info.Client, info.DeviceName)
+	c.Set(ClientRuleGinKey, rule)
+	return rule
+}
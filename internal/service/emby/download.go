@@ -27,7 +27,7 @@ func HandleSyncDownload(c *gin.Context) {
 
 	// 请求 targets 列表
 	targetUri := "/Sync/Targets?api_key=" + itemInfo.ApiKey
-	resp, _ := Fetch(targetUri, http.MethodGet, nil, nil)
+	resp, _ := Fetch(c.Request.Context(), targetUri, http.MethodGet, nil, nil)
 	if resp.Code != http.StatusOK {
 		checkErr(c, fmt.Errorf("请求 emby 失败: %v, uri: %s", resp.Msg, targetUri))
 		return
@@ -48,7 +48,7 @@ func HandleSyncDownload(c *gin.Context) {
 
 		// 请求 Ready 接口
 		readyUri := readyUriTmpl + id
-		resp, _ := Fetch(readyUri, http.MethodGet, nil, nil)
+		resp, _ := Fetch(c.Request.Context(), readyUri, http.MethodGet, nil, nil)
 		if resp.Code != http.StatusOK {
 			checkErr(c, fmt.Errorf("请求 emby 失败: %v, uri: %s", resp.Msg, readyUri))
 			return jsons.ErrBreakRange
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/bytess"
@@ -25,7 +26,7 @@ func ProxySocket() func(*gin.Context) {
 	var once = sync.Once{}
 
 	initFunc := func() {
-		origin := config.C.Emby.Host
+		origin := config.C().Emby.Host
 		u, err := url.Parse(origin)
 		if err != nil {
 			panic("转换 emby host 异常: " + err.Error())
@@ -47,13 +48,23 @@ func ProxySocket() func(*gin.Context) {
 
 // HandleImages 处理图片请求
 //
-// 修改图片质量参数为配置值
+// 修改图片质量参数为配置值, 开启图片 CDN 后优先重定向到 CDN (客户端要求缩放或重新编码时除外),
+// 开启图片缓存后优先从磁盘缓存中响应
 func HandleImages(c *gin.Context) {
 	q := c.Request.URL.Query()
+	cdnable := !needImageProcess(q)
 	q.Del("quality")
 	q.Del("Quality")
-	q.Set("Quality", strconv.Itoa(config.C.Emby.ImagesQuality))
+	q.Set("Quality", strconv.Itoa(config.C().Emby.ImagesQuality))
 	c.Request.RequestURI = c.Request.URL.Path + "?" + q.Encode()
+	c.Request.URL.RawQuery = q.Encode()
+
+	if config.C().Emby.ImageCdn.Enable && c.Request.Method == http.MethodGet && cdnable && redirectImage2Cdn(c) {
+		return
+	}
+	if config.C().Emby.ImageCache.Enable && c.Request.Method == http.MethodGet && serveCachedImage(c) {
+		return
+	}
 	ProxyOrigin(c)
 }
 
@@ -62,13 +73,27 @@ func ProxyOrigin(c *gin.Context) {
 	if c == nil {
 		return
 	}
-	origin := config.C.Emby.Host
+	origin := config.C().Emby.Host
 
 	// 传递客户端 IP 到 emby
 	c.Request.Header.Set("X-Forwarded-For", c.ClientIP())
 	c.Request.Header.Set("X-Real-IP", c.ClientIP())
 
-	if err := https.ProxyPass(c.Request, c.Writer, origin); err != nil {
+	// 上游耗时只统计到收到响应头, 不包括响应体的传输时间
+	start := time.Now()
+	resp, err := https.ProxyRequest(c.Request, origin)
+	upstreamDuration.ObserveSince(start, upstreamKindProxy)
+	if err != nil {
+		upstreamErrors.Inc(upstreamKindProxy)
+		logs.Error("代理异常: 代理请求失败: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		upstreamErrors.Inc(upstreamKindProxy)
+	}
+	if err := https.WriteResponse(c.Writer, resp); err != nil {
 		logs.Error("代理异常: %v", err)
 	}
 }
@@ -117,8 +142,9 @@ func TestProxyUri(c *gin.Context) bool {
 	}
 	infos.Body = string(bodyBytes)
 
-	origin := config.C.Emby.Host
+	origin := config.C().Emby.Host
 	resp, err := https.Request(infos.Method, origin+infos.Uri).
+		Context(c.Request.Context()).
 		Header(c.Request.Header).
 		Body(io.NopCloser(bytes.NewBuffer(bodyBytes))).
 		Do()
@@ -152,7 +178,8 @@ func TestProxyUri(c *gin.Context) bool {
 
 // ProxyRoot web 首页代理
 func ProxyRoot(c *gin.Context) {
-	resp, err := https.Request(c.Request.Method, config.C.Emby.Host+c.Request.URL.String()).
+	resp, err := https.Request(c.Request.Method, config.C().Emby.Host+c.Request.URL.String()).
+		Context(c.Request.Context()).
 		Header(c.Request.Header).
 		Body(c.Request.Body).
 		DoSingle()
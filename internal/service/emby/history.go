@@ -0,0 +1,197 @@
+package emby
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/analytics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/accesslog"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redirectHistorySize 保留的最近重定向记录数
+const redirectHistorySize = 100
+
+// cdnErrorWindow 最近出现过错误的 CDN 在这个时间内视为异常
+const cdnErrorWindow = 5 * time.Minute
+
+// CDN 健康状态
+const (
+	CdnStatusIdle     = "idle"     // 最近没有请求
+	CdnStatusOk       = "ok"       // 最近的请求都成功
+	CdnStatusDegraded = "degraded" // 最近出现过错误
+)
+
+// RedirectRecord 一次 CDN 重定向记录
+type RedirectRecord struct {
+	Time       time.Time `json:"time"`
+	Cdn        string    `json:"cdn"`
+	Kind       string    `json:"kind"`
+	ClientIp   string    `json:"client_ip"`
+	Path       string    `json:"path"`
+	RemotePath string    `json:"remote_path"`
+}
+
+// CdnHealth 按重定向结果统计的 CDN 健康状态
+type CdnHealth struct {
+	Cdn          string    `json:"cdn"`
+	Status       string    `json:"status"`
+	Redirects    int64     `json:"redirects"`
+	Errors       int64     `json:"errors"`
+	LastSuccess  time.Time `json:"last_success,omitzero"`
+	LastError    time.Time `json:"last_error,omitzero"`
+	LastErrorMsg string    `json:"last_error_msg,omitempty"`
+}
+
+var (
+	// historyMu 保护 redirectHistory 和 cdnHealths
+	historyMu sync.Mutex
+	// redirectHistory 最近的重定向记录, 环形缓冲区
+	redirectHistory = make([]RedirectRecord, 0, redirectHistorySize)
+	// historyNext 环形缓冲区下一个写入位置
+	historyNext int
+	// cdnHealths 各 CDN 的健康状态
+	cdnHealths = map[string]*CdnHealth{}
+)
+
+// 随重定向响应一起缓存的元数据 key
+const (
+	metaKeyItemId        = "itemId"
+	metaKeyMediaSourceId = "mediaSourceId"
+	metaKeyCdn           = "cdn"
+	metaKeyCdnPath       = "cdnPath"
+	metaKeyKind          = "kind"
+)
+
+func init() {
+	cache.OnHit(restoreRedirect)
+
+	// 移除已经从配置中删除的 CDN 的健康状态
+	config.OnReload(func(_, c *config.Config) {
+		historyMu.Lock()
+		defer historyMu.Unlock()
+		for name := range cdnHealths {
+			if name != "" && !slices.ContainsFunc(c.Emby.Strm.Cdns, func(cdn config.CdnConfig) bool { return cdn.Name == name }) {
+				delete(cdnHealths, name)
+			}
+		}
+	})
+}
+
+// restoreRedirect 命中缓存的重定向请求, 恢复访问日志中的资源与 CDN 信息, 并记录播放统计
+func restoreRedirect(c *gin.Context, meta map[string]string) {
+	cdn, ok := meta[metaKeyCdn]
+	if !ok {
+		return
+	}
+	itemId, msId := meta[metaKeyItemId], meta[metaKeyMediaSourceId]
+	accesslog.SetItem(c, itemId, msId)
+	accesslog.SetRedirect(c, cdn, meta[metaKeyCdnPath])
+	if meta[metaKeyKind] == redirectKindStream {
+		analytics.RecordStream(RequestApiKey(c), itemId, msId, cdn)
+	}
+}
+
+// recordRedirect 记录一次成功的 CDN 重定向, 同时写入监控指标和访问日志
+func recordRedirect(c *gin.Context, mapRes config.MapResult, kind string) {
+	cdn := mapRes.Cdn.Name
+	cdnRedirects.Inc(cdn, kind)
+	accesslog.SetRedirect(c, cdn, mapRes.RemotePath)
+	cache.SetMeta(c, metaKeyCdn, cdn)
+	cache.SetMeta(c, metaKeyCdnPath, mapRes.RemotePath)
+	cache.SetMeta(c, metaKeyKind, kind)
+
+	r := RedirectRecord{
+		Time:       time.Now(),
+		Cdn:        cdn,
+		Kind:       kind,
+		ClientIp:   c.ClientIP(),
+		Path:       c.Request.URL.Path,
+		RemotePath: mapRes.RemotePath,
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	if len(redirectHistory) < redirectHistorySize {
+		redirectHistory = append(redirectHistory, r)
+	} else {
+		redirectHistory[historyNext] = r
+	}
+	historyNext = (historyNext + 1) % redirectHistorySize
+
+	h := cdnHealthOf(cdn)
+	h.Redirects++
+	h.LastSuccess = r.Time
+}
+
+// recordCdnError 记录一次 CDN 重定向失败
+func recordCdnError(cdn string, err error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	h := cdnHealthOf(cdn)
+	h.Errors++
+	h.LastError, h.LastErrorMsg = time.Now(), err.Error()
+}
+
+// cdnHealthOf 获取 CDN 的健康状态, 调用方需持有锁
+func cdnHealthOf(cdn string) *CdnHealth {
+	h, ok := cdnHealths[cdn]
+	if !ok {
+		h = &CdnHealth{Cdn: cdn}
+		cdnHealths[cdn] = h
+	}
+	return h
+}
+
+// RecentRedirects 获取最近的重定向记录, 按时间倒序
+func RecentRedirects() []RedirectRecord {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	res := make([]RedirectRecord, 0, len(redirectHistory))
+	for i := 1; i <= len(redirectHistory); i++ {
+		idx := (historyNext - i + redirectHistorySize) % redirectHistorySize
+		if idx >= len(redirectHistory) {
+			continue
+		}
+		res = append(res, redirectHistory[idx])
+	}
+	return res
+}
+
+// CdnHealths 获取所有已配置 CDN 的健康状态
+//
+// 最近一次错误发生在 cdnErrorWindow 内, 并且之后没有成功的重定向时, 视为异常;
+// 未匹配到 CDN 的错误以空名称列出
+func CdnHealths() []CdnHealth {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	names := map[string]struct{}{}
+	for _, cdn := range config.C().Emby.Strm.Cdns {
+		names[cdn.Name] = struct{}{}
+	}
+	for name := range cdnHealths {
+		names[name] = struct{}{}
+	}
+
+	now := time.Now()
+	res := make([]CdnHealth, 0, len(names))
+	for name := range names {
+		h := CdnHealth{Cdn: name, Status: CdnStatusIdle}
+		if stat, ok := cdnHealths[name]; ok {
+			h = *stat
+			h.Status = CdnStatusOk
+			if now.Sub(h.LastError) < cdnErrorWindow && h.LastError.After(h.LastSuccess) {
+				h.Status = CdnStatusDegraded
+			}
+		}
+		res = append(res, h)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Cdn < res[j].Cdn })
+	return res
+}
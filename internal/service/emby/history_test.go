@@ -0,0 +1,69 @@
+package emby
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/accesslog"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestCachedRedirectAccessLog 测试命中缓存的重定向请求, 访问日志仍然记录 CDN 信息
+func TestCachedRedirectAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	old := config.C()
+	config.Set(&config.Config{Cache: new(config.Cache)})
+	t.Cleanup(func() { config.Set(old) })
+	cache.Purge()
+	t.Cleanup(func() { cache.Purge() })
+
+	var buf bytes.Buffer
+	calls := 0
+
+	r := gin.New()
+	r.Use(accesslog.Middleware(&buf, accesslog.FormatJson, "matchRoute"))
+	r.Use(cache.RequestCacher())
+	r.GET("/*vars", func(c *gin.Context) {
+		calls++
+		c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute))
+		cache.SetMeta(c, metaKeyItemId, "123")
+		cache.SetMeta(c, metaKeyMediaSourceId, "ms1")
+		mapRes := config.MapResult{Cdn: &config.CdnConfig{Name: "main"}, RemotePath: "/series/a.mkv"}
+		recordRedirect(c, mapRes, redirectKindStream)
+		c.Redirect(http.StatusFound, "https://cdn.example.com/series/a.mkv?sign=xxx")
+	})
+
+	const uri = "/emby/videos/123/stream.mkv?MediaSourceId=mediasource_ms1&test=cached-redirect"
+	serve := func() accesslog.Entry {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, uri, nil))
+		var e accesslog.Entry
+		if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &e); err != nil {
+			t.Fatalf("解析访问日志失败: %v, 日志: %s", err, buf.String())
+		}
+		return e
+	}
+
+	serve()
+	// 缓存由后台协程异步写入
+	time.Sleep(50 * time.Millisecond)
+	cache.WaitingForHandleChan()
+	e := serve()
+	if calls != 1 {
+		t.Fatalf("第二次请求应该命中缓存, 处理器执行次数: %d", calls)
+	}
+	if e.Status != http.StatusFound || e.Cdn != "main" || e.CdnPath != "/series/a.mkv" {
+		t.Errorf("命中缓存的访问日志缺少 CDN 信息: %+v", e)
+	}
+	if e.ItemId != "123" || e.MediaSourceId != "ms1" || strings.Contains(e.Uri, "sign=") {
+		t.Errorf("命中缓存的访问日志字段不符合预期: %+v", e)
+	}
+}
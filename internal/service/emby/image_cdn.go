@@ -0,0 +1,190 @@
+package emby
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// itemImageReg 匹配 item 图片接口, 如 /Items/123/Images/Backdrop/0
+var itemImageReg = regexp.MustCompile(`(?i)/items/([^/]+)/images/([^/]+)(?:/(\d+))?/?$`)
+
+const (
+	// imagePathTtl 图片本地路径的缓存时长
+	imagePathTtl = time.Hour * 6
+	// maxImagePaths 最多缓存多少个图片本地路径, 超出后不再缓存新的路径, 直到过期清理
+	maxImagePaths = 20000
+)
+
+// imageProcessParams 要求 emby 对图片进行缩放、裁剪或重新编码的参数 (小写)
+//
+// CDN 只能返回原图, 携带这些参数的请求不作重定向
+var imageProcessParams = map[string]struct{}{
+	"maxwidth": {}, "maxheight": {}, "width": {}, "height": {},
+	"fillwidth": {}, "fillheight": {}, "quality": {}, "format": {},
+	"blur": {}, "cropwhitespace": {}, "backgroundcolor": {}, "foregroundlayer": {},
+	"percentplayed": {}, "unplayedcount": {}, "addplayedindicator": {},
+}
+
+// needImageProcess 判断请求是否要求 emby 处理图片
+func needImageProcess(q url.Values) bool {
+	for key := range q {
+		if _, ok := imageProcessParams[strings.ToLower(key)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// imagePath 缓存的图片本地路径
+type imagePath struct {
+	path    string    // 本地路径, 为空表示图片不存在
+	expired time.Time // 过期时间
+}
+
+var (
+	// imagePaths 缓存图片的本地路径, key: itemId_图片类型_图片索引_tag
+	imagePaths = sync.Map{}
+	// imagePathCnt 当前缓存的图片路径数量
+	imagePathCnt atomic.Int64
+)
+
+func init() {
+	go loopCleanImagePaths()
+}
+
+// loopCleanImagePaths 定期清理过期的图片路径
+func loopCleanImagePaths() {
+	ticker := time.NewTicker(time.Minute * 10)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		imagePaths.Range(func(key, value any) bool {
+			if now.After(value.(*imagePath).expired) && imagePaths.CompareAndDelete(key, value) {
+				imagePathCnt.Add(-1)
+			}
+			return true
+		})
+	}
+}
+
+// loadImagePath 读取缓存的图片路径
+func loadImagePath(key string) (string, bool) {
+	value, ok := imagePaths.Load(key)
+	if !ok {
+		return "", false
+	}
+	ip := value.(*imagePath)
+	if time.Now().After(ip.expired) {
+		return "", false
+	}
+	return ip.path, true
+}
+
+// storeImagePath 缓存图片路径, 缓存数量达到上限时不作缓存
+func storeImagePath(key, localPath string) {
+	ip := &imagePath{path: localPath, expired: time.Now().Add(imagePathTtl)}
+	if _, loaded := imagePaths.Swap(key, ip); loaded {
+		return
+	}
+	if imagePathCnt.Add(1) > maxImagePaths {
+		if imagePaths.CompareAndDelete(key, ip) {
+			imagePathCnt.Add(-1)
+		}
+	}
+}
+
+// redirectImage2Cdn 将图片的本地路径映射为 CDN 直链, 并重定向客户端
+//
+// 图片路径查询失败或无法映射时, 返回 false
+func redirectImage2Cdn(c *gin.Context) bool {
+	localPath, ok := lookupImagePath(c)
+	if !ok {
+		return false
+	}
+
+	mapRes, err := config.C().Emby.ImageCdn.Resolve(localPath, c.ClientIP())
+	if err != nil {
+		logs.Warn("图片映射失败, 回源处理: %v", err)
+		return false
+	}
+
+	recordRedirect(c, mapRes, redirectKindImage)
+	c.Redirect(http.StatusFound, mapRes.Url)
+	return true
+}
+
+// lookupImagePath 查询请求的图片在 emby 中的本地路径
+//
+// 携带 tag 的请求对应固定版本的图片, 查询结果会被缓存
+func lookupImagePath(c *gin.Context) (string, bool) {
+	matches := itemImageReg.FindStringSubmatch(c.Request.URL.Path)
+	if len(matches) < 4 {
+		return "", false
+	}
+	itemId, imageType := matches[1], matches[2]
+	imageIndex, _ := strconv.Atoi(matches[3])
+
+	tag := ""
+	for key, values := range c.Request.URL.Query() {
+		if strings.EqualFold(key, "tag") && len(values) > 0 {
+			tag = values[0]
+		}
+	}
+	cacheKey := strings.ToLower(fmt.Sprintf("%s_%s_%d_%s", itemId, imageType, imageIndex, tag))
+	if tag != "" {
+		if p, ok := loadImagePath(cacheKey); ok {
+			return p, p != ""
+		}
+	}
+
+	uri := fmt.Sprintf("/Items/%s/Images", itemId)
+	header := make(http.Header)
+	if apiKey := config.C().Emby.ImageCdn.ApiKey; strs.AllNotEmpty(apiKey) {
+		uri += fmt.Sprintf("?%s=%s", QueryApiKeyName, url.QueryEscape(apiKey))
+	} else {
+		kType, kName, apiKey := getApiKey(c)
+		if strs.AnyEmpty(apiKey) {
+			return "", false
+		}
+		if kType == Query {
+			uri += fmt.Sprintf("?%s=%s", kName, url.QueryEscape(apiKey))
+		} else {
+			header.Set(kName, apiKey)
+		}
+	}
+	res, _ := RawFetch(c.Request.Context(), uri, http.MethodGet, header, nil)
+	if res.Code != http.StatusOK {
+		logs.Warn("查询图片信息失败: %s", res.Msg)
+		return "", false
+	}
+
+	localPath := ""
+	res.Data.RangeArr(func(_ int, info *jsons.Item) error {
+		typ, _ := info.Attr("ImageType").String()
+		idx, _ := info.Attr("ImageIndex").Int()
+		if !strings.EqualFold(typ, imageType) || idx != imageIndex {
+			return nil
+		}
+		localPath, _ = info.Attr("Path").String()
+		return jsons.ErrBreakRange
+	})
+
+	if tag != "" {
+		storeImagePath(cacheKey, localPath)
+	}
+	return localPath, localPath != ""
+}
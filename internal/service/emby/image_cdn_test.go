@@ -0,0 +1,52 @@
+package emby
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// TestStoreImagePath 测试图片路径缓存达到上限后不再缓存新的路径
+func TestStoreImagePath(t *testing.T) {
+	t.Cleanup(func() {
+		imagePaths.Clear()
+		imagePathCnt.Store(0)
+	})
+
+	for i := 0; i < maxImagePaths; i++ {
+		storeImagePath(strconv.Itoa(i), "/images/"+strconv.Itoa(i))
+	}
+	storeImagePath("0", "/images/new")
+	if p, ok := loadImagePath("0"); !ok || p != "/images/new" {
+		t.Errorf("已缓存的 key 应该可以更新, got: %s, %v", p, ok)
+	}
+
+	storeImagePath("overflow", "/images/overflow")
+	if _, ok := loadImagePath("overflow"); ok {
+		t.Error("缓存数量达到上限后不应再缓存新的路径")
+	}
+	if got := imagePathCnt.Load(); got != maxImagePaths {
+		t.Errorf("缓存数量 = %d, want %d", got, maxImagePaths)
+	}
+}
+
+// TestNeedImageProcess 测试要求缩放或重新编码的图片请求不重定向到 CDN
+func TestNeedImageProcess(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "tag=abc", want: false},
+		{query: "", want: false},
+		{query: "maxWidth=300&tag=abc", want: true},
+		{query: "MAXHEIGHT=200", want: true},
+		{query: "quality=90", want: true},
+		{query: "format=webp", want: true},
+	}
+	for _, tt := range tests {
+		q, _ := url.ParseQuery(tt.query)
+		if got := needImageProcess(q); got != tt.want {
+			t.Errorf("needImageProcess(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package emby
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/diskcaches"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/images"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// maxImageSize 允许缓存的最大图片大小
+const maxImageSize = 30 * 1024 * 1024
+
+// imageCache 图片磁盘缓存, 首次使用时初始化
+var imageCache = sync.OnceValues(func() (*diskcaches.Cache, error) {
+	conf := config.C().Emby.ImageCache
+	return diskcaches.New(conf.Dir(), conf.Quota())
+})
+
+func init() {
+	// 调整图片缓存上限后立即生效, 缓存目录固定, 不需要重建缓存
+	config.OnReload(func(old, c *config.Config) {
+		conf := c.Emby.ImageCache
+		if !conf.Enable || old.Emby.ImageCache.MaxSize == conf.MaxSize {
+			return
+		}
+		cache, err := imageCache()
+		if err != nil {
+			return
+		}
+		if err := cache.SetQuota(conf.Quota()); err != nil {
+			logs.Error("更新图片缓存上限失败: %v", err)
+			return
+		}
+		logs.Info("图片缓存上限已更新为 %d MB", conf.MaxSize)
+	})
+}
+
+// imageGroup 合并相同图片的并发处理
+var imageGroup = singleflight.Group{}
+
+// imageRequest 客户端请求的图片信息
+type imageRequest struct {
+	path      string        // 图片接口路径
+	tag       string        // 图片版本标识
+	maxWidth  int           // 最大宽度
+	maxHeight int           // 最大高度
+	quality   int           // 图片质量
+	format    images.Format // 目标格式, 为空则根据原图决定
+	query     url.Values    // 透传给 emby 的其他参数
+}
+
+// variantKey 缩放后图片的缓存 key
+func (ir imageRequest) variantKey() string {
+	return fmt.Sprintf("%s|%s|%dx%d|%s|%d", ir.path, ir.tag, ir.maxWidth, ir.maxHeight, ir.format, ir.quality)
+}
+
+// originKey 原图的缓存 key
+func (ir imageRequest) originKey() string {
+	return fmt.Sprintf("%s|%s|origin", ir.path, ir.tag)
+}
+
+// parseImageRequest 解析客户端请求的图片信息, 参数名称不区分大小写
+func parseImageRequest(c *gin.Context) imageRequest {
+	ir := imageRequest{path: strings.ToLower(c.Request.URL.Path), query: url.Values{}}
+	atoi := func(v string) int {
+		n, _ := strconv.Atoi(v)
+		return n
+	}
+
+	for key, values := range c.Request.URL.Query() {
+		if len(values) == 0 {
+			continue
+		}
+		v := values[0]
+		switch strings.ToLower(key) {
+		case "tag":
+			ir.tag = v
+		case "maxwidth", "width", "fillwidth":
+			ir.maxWidth = max(ir.maxWidth, atoi(v))
+		case "maxheight", "height", "fillheight":
+			ir.maxHeight = max(ir.maxHeight, atoi(v))
+		case "quality":
+			ir.quality = atoi(v)
+		case "format":
+			if strings.EqualFold(v, "png") {
+				ir.format = images.FormatPng
+			}
+		case QueryApiKeyName, strings.ToLower(QueryTokenName):
+			ir.query.Set(key, v)
+		}
+	}
+
+	if config.C().Emby.ImageCache.Webp && strings.Contains(c.GetHeader("Accept"), "image/webp") {
+		ir.format = images.FormatWebp
+	}
+	return ir
+}
+
+// serveCachedImage 从磁盘缓存中响应图片, 缓存不存在时生成并缓存
+//
+// 处理失败时返回 false, 由调用方回源处理
+func serveCachedImage(c *gin.Context) bool {
+	ir := parseImageRequest(c)
+	if ir.tag == "" {
+		// 没有版本标识的图片可能随时变化, 不作缓存
+		return false
+	}
+
+	ic, err := imageCache()
+	if err != nil {
+		logs.Error("图片缓存初始化失败: %v", err)
+		return false
+	}
+
+	data, ok := ic.Get(ir.variantKey())
+	if !ok {
+		v, err, _ := imageGroup.Do(ir.variantKey(), func() (any, error) {
+			return buildImageVariant(c, ic, ir)
+		})
+		if err != nil {
+			logs.Warn("图片缓存处理失败, 回源处理: %v", err)
+			return false
+		}
+		data = v.([]byte)
+	}
+
+	format, ok := images.DetectFormat(data)
+	if !ok {
+		return false
+	}
+	c.Header("Cache-Control", "public, max-age=2592000")
+	if config.C().Emby.ImageCache.Webp {
+		// 响应格式取决于客户端是否支持 WebP, 防止下游缓存混用
+		c.Writer.Header().Add("Vary", "Accept")
+	}
+	c.Data(http.StatusOK, format.ContentType(), data)
+	return true
+}
+
+// buildImageVariant 生成请求尺寸的图片并写入缓存
+func buildImageVariant(c *gin.Context, ic *diskcaches.Cache, ir imageRequest) ([]byte, error) {
+	var data []byte
+	var err error
+
+	if ir.format == images.FormatWebp {
+		// WebP 交由 emby 生成
+		q := cloneValues(ir.query)
+		q.Set("tag", ir.tag)
+		q.Set("format", "webp")
+		if ir.maxWidth > 0 {
+			q.Set("maxWidth", strconv.Itoa(ir.maxWidth))
+		}
+		if ir.maxHeight > 0 {
+			q.Set("maxHeight", strconv.Itoa(ir.maxHeight))
+		}
+		if ir.quality > 0 {
+			q.Set("quality", strconv.Itoa(ir.quality))
+		}
+		if data, err = fetchEmbyImage(c, ir.path, q); err != nil {
+			return nil, err
+		}
+	} else {
+		origin, ok := ic.Get(ir.originKey())
+		if !ok {
+			q := cloneValues(ir.query)
+			q.Set("tag", ir.tag)
+			if origin, err = fetchEmbyImage(c, ir.path, q); err != nil {
+				return nil, err
+			}
+			if err = ic.Put(ir.originKey(), origin); err != nil {
+				logs.Warn("原图写入缓存失败: %v", err)
+			}
+		}
+
+		srcFormat, ok := images.DetectFormat(origin)
+		if !ok {
+			return nil, errors.New("无法识别原图格式")
+		}
+		target := ir.format
+		switch {
+		case srcFormat == images.FormatGif:
+			// 动图不作处理
+			return origin, nil
+		case target == "" && srcFormat == images.FormatPng:
+			// 保留透明通道
+			target = images.FormatPng
+		case target == "":
+			target = images.FormatJpeg
+		}
+
+		quality := ir.quality
+		if quality <= 0 || quality > 100 {
+			quality = config.C().Emby.ImagesQuality
+		}
+		if data, err = images.Resize(origin, ir.maxWidth, ir.maxHeight, target, quality); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = ic.Put(ir.variantKey(), data); err != nil {
+		logs.Warn("图片写入缓存失败: %v", err)
+	}
+	return data, nil
+}
+
+// fetchEmbyImage 请求 emby 图片
+//
+// 同一图片的并发请求会共用结果, 请求不随发起方客户端的断开而取消
+func fetchEmbyImage(c *gin.Context, path string, q url.Values) ([]byte, error) {
+	header := c.Request.Header.Clone()
+	header.Del("Accept-Encoding")
+	header.Del("Range")
+	ctx := context.WithoutCancel(c.Request.Context())
+	resp, err := https.Get(config.C().Emby.Host + path + "?" + q.Encode()).Context(ctx).Header(header).Do()
+	if err != nil {
+		return nil, fmt.Errorf("请求 emby 图片失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return nil, fmt.Errorf("请求 emby 图片失败, status: %s, type: %s", resp.Status, resp.Header.Get("Content-Type"))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取 emby 图片失败: %v", err)
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("图片过大: %d", len(data))
+	}
+	return data, nil
+}
+
+// cloneValues 复制 query 参数
+func cloneValues(q url.Values) url.Values {
+	res := make(url.Values, len(q))
+	for k, v := range q {
+		res[k] = append([]string(nil), v...)
+	}
+	return res
+}
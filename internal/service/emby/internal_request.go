@@ -0,0 +1,50 @@
+package emby
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// headerInternalSecret 服务内部自请求的凭证请求头
+	headerInternalSecret = "X-Ge2o-Internal-Secret"
+	// headerInternalClientIp 服务内部自请求携带的原始客户端 ip
+	headerInternalClientIp = "X-Ge2o-Client-Ip"
+)
+
+// internalSecret 服务内部自请求的凭证, 每次启动时随机生成, 防止外部请求伪造客户端 ip
+var internalSecret = func() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic("生成内部请求凭证失败: " + err.Error())
+	}
+	return hex.EncodeToString(buf)
+}()
+
+// setInternalClientIp 在服务内部自请求中携带原始请求的客户端 ip
+func setInternalClientIp(header http.Header, c *gin.Context) {
+	header.Set(headerInternalSecret, internalSecret)
+	header.Set(headerInternalClientIp, c.ClientIP())
+}
+
+// InternalClientIpRestorer 还原服务内部自请求的原始客户端 ip
+//
+// 凭证校验通过时, 将原始客户端 ip 写入连接来源地址, 后续的 ClientIP 均返回原始 ip;
+// 无论校验是否通过, 都会移除相关请求头, 防止透传到上游
+func InternalClientIpRestorer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Request.Header
+		secret, ip := header.Get(headerInternalSecret), header.Get(headerInternalClientIp)
+		header.Del(headerInternalSecret)
+		header.Del(headerInternalClientIp)
+
+		if secret != internalSecret || net.ParseIP(ip) == nil {
+			return
+		}
+		c.Request.RemoteAddr = net.JoinHostPort(ip, "0")
+	}
+}
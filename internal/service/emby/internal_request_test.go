@@ -0,0 +1,59 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestInternalClientIpRestorer 测试只有携带正确凭证的自请求才能还原客户端 ip
+func TestInternalClientIpRestorer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const remoteIp = "127.0.0.1"
+
+	tests := []struct {
+		name   string
+		secret string
+		ip     string
+		want   string
+	}{
+		{name: "凭证正确", secret: internalSecret, ip: "203.0.113.7", want: "203.0.113.7"},
+		{name: "凭证错误", secret: "forged", ip: "192.168.1.2", want: remoteIp},
+		{name: "缺少凭证", secret: "", ip: "192.168.1.2", want: remoteIp},
+		{name: "ip 无效", secret: internalSecret, ip: "bad", want: remoteIp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := r.SetTrustedProxies(nil); err != nil {
+				t.Fatal(err)
+			}
+			r.Use(InternalClientIpRestorer())
+			var got string
+			var leaked bool
+			r.GET("/", func(c *gin.Context) {
+				got = c.ClientIP()
+				leaked = c.GetHeader(headerInternalSecret) != "" || c.GetHeader(headerInternalClientIp) != ""
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = remoteIp + ":34567"
+			req.Header.Set("X-Forwarded-For", "192.168.1.2")
+			if tt.secret != "" {
+				req.Header.Set(headerInternalSecret, tt.secret)
+			}
+			req.Header.Set(headerInternalClientIp, tt.ip)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.want)
+			}
+			if leaked {
+				t.Error("内部请求头没有被移除")
+			}
+		})
+	}
+}
@@ -17,7 +17,7 @@ import (
 func ProxyAddItemsPreviewInfo(c *gin.Context) {
 	// 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, config.C().Emby.Host)
 	if checkErr(c, err) {
 		return
 	}
@@ -46,7 +46,11 @@ func ProxyAddItemsPreviewInfo(c *gin.Context) {
 		return
 	}
 
-	// 遍历每个 Item, 处理 MediaSource 信息
+	processItemsMediaSources(itemsArr)
+}
+
+// processItemsMediaSources 遍历每个 Item, 处理 MediaSource 信息
+func processItemsMediaSources(itemsArr *jsons.Item) {
 	itemsArr.RangeArr(func(index int, item *jsons.Item) error {
 		mediaSources, ok := item.Attr("MediaSources").Done()
 		if !ok || mediaSources.Empty() {
@@ -69,9 +73,13 @@ func ProxyAddItemsPreviewInfo(c *gin.Context) {
 
 // ProxyLatestItems 代理 Latest 请求
 func ProxyLatestItems(c *gin.Context) {
+	if handleVirtualLatest(c) {
+		return
+	}
+
 	// 代理请求
 	c.Request.Header.Del("Accept-Encoding")
-	resp, err := https.ProxyRequest(c.Request, config.C.Emby.Host)
+	resp, err := https.ProxyRequest(c.Request, config.C().Emby.Host)
 	if checkErr(c, err) {
 		return
 	}
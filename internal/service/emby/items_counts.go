@@ -1,19 +1,38 @@
 package emby
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
 )
 
+// itemsCountsEntry 缓存的 emby 真实统计数量
+type itemsCountsEntry struct {
+	counts   map[string]int
+	expireAt time.Time
+}
+
+// itemsCountsCache 动态模式下的统计数量缓存, key: api_key 所属用户 id, 全局统计为空字符串
+var itemsCountsCache = sync.Map{}
+
+// itemsCountsGroup 合并相同用户的并发统计请求
+var itemsCountsGroup = singleflight.Group{}
+
 // HandleItemsCounts 处理 /Items/Counts 请求
 //
 // 功能说明：
 //  1. 如果 items-counts.enable=false，回源透传
 //  2. 如果请求包含 ParentId 参数，回源透传（查询特定媒体库的统计）
 //  3. 如果请求不包含 ParentId 参数，返回自定义统计数据（全局统计）
+//  4. 动态模式下，统计数据由 emby 真实数量按配置调整得到
 //
 // 使用场景：
 //   - Emby 客户端启动时会请求 /Items/Counts 获取媒体库统计信息
@@ -22,7 +41,7 @@ func HandleItemsCounts(c *gin.Context) {
 	logs.Info("Items/Counts 路由匹配成功: %s", c.Request.URL.Path)
 
 	// 1. 检查是否启用自定义统计
-	if !config.C.ItemsCounts.Enable {
+	if !config.C().ItemsCounts.Enable {
 		logs.Info("Items/Counts: 未启用自定义统计，回源透传")
 		ProxyOrigin(c)
 		return
@@ -40,7 +59,18 @@ func HandleItemsCounts(c *gin.Context) {
 	logs.Info("Items/Counts: 返回自定义统计数据")
 
 	// 生成 Emby 格式的 JSON 响应
-	countsData := config.C.ItemsCounts.ToJSON()
+	var countsData map[string]int
+	if config.C().ItemsCounts.Mode == config.ItemsCountsModeDynamic {
+		realCounts, err := fetchItemsCounts(c)
+		if err != nil {
+			logs.Warn("Items/Counts: 获取真实统计失败, 回源透传: %v", err)
+			ProxyOrigin(c)
+			return
+		}
+		countsData = config.C().ItemsCounts.Adjust(realCounts)
+	} else {
+		countsData = config.C().ItemsCounts.ToJSON()
+	}
 
 	// 详细日志输出 - 主要媒体类型
 	logs.Success("Items/Counts 统计: 电影=%d, 剧集=%d, 分集=%d, 总计=%d",
@@ -67,3 +97,59 @@ func HandleItemsCounts(c *gin.Context) {
 	c.Header("Content-Type", "application/json; charset=utf-8")
 	c.JSON(http.StatusOK, countsData)
 }
+
+// fetchItemsCounts 从 emby 获取真实统计数量, 结果按配置的时间缓存
+//
+// 请求中的 api_key 需要先通过 emby 校验, 才能读取缓存;
+// 开启 per-user 时, 按 api_key 所属用户统计该用户可见媒体库中的数量,
+// 否则使用配置的服务器 api_key 统计全局数量
+func fetchItemsCounts(c *gin.Context) (map[string]int, error) {
+	conf := config.C().ItemsCounts
+	owner := RequestUserId(c)
+	if strs.AnyEmpty(owner) {
+		return nil, fmt.Errorf("请求中的 api_key 无效")
+	}
+
+	userId := ""
+	if conf.PerUser {
+		userId = owner
+	}
+	if entry, ok := itemsCountsCache.Load(userId); ok && time.Now().Before(entry.(itemsCountsEntry).expireAt) {
+		return entry.(itemsCountsEntry).counts, nil
+	}
+
+	v, err, _ := itemsCountsGroup.Do(userId, func() (any, error) {
+		q := url.Values{}
+		header := make(http.Header)
+		if userId != "" {
+			kType, kName, apiKey := getApiKey(c)
+			q.Set("UserId", userId)
+			if kType == Query {
+				q.Set(kName, apiKey)
+			} else {
+				header.Set(kName, apiKey)
+			}
+		} else {
+			q.Set(QueryApiKeyName, conf.ApiKey)
+		}
+
+		res, _ := Fetch(c.Request.Context(), "/Items/Counts?"+q.Encode(), http.MethodGet, header, nil)
+		if res.Code != http.StatusOK {
+			return nil, fmt.Errorf("请求 emby 统计接口失败: %s", res.Msg)
+		}
+		counts := make(map[string]int, len(config.ItemsCountsFields))
+		for _, field := range config.ItemsCountsFields {
+			if count, ok := res.Data.Attr(field).Int(); ok {
+				counts[field] = count
+			}
+		}
+
+		expireAt := time.Now().Add(time.Duration(conf.CacheTtl) * time.Second)
+		itemsCountsCache.Store(userId, itemsCountsEntry{counts: counts, expireAt: expireAt})
+		return counts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]int), nil
+}
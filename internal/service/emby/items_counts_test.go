@@ -0,0 +1,72 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestFetchItemsCounts 测试动态统计先校验 api_key, 全局统计使用服务器 api_key, 按用户统计使用 api_key 所属用户
+func TestFetchItemsCounts(t *testing.T) {
+	var countsQueries []string
+	emby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owners := map[string]string{"token-a": "u1", "token-b": "u2"}
+		switch r.URL.Path {
+		case "/emby/Users/Me":
+			id, ok := owners[r.URL.Query().Get(QueryApiKeyName)]
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"Id":"` + id + `"}`))
+		case "/Items/Counts":
+			countsQueries = append(countsQueries, r.URL.RawQuery)
+			w.Write([]byte(`{"MovieCount":10}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer emby.Close()
+
+	old := config.C()
+	t.Cleanup(func() {
+		config.Set(old)
+		itemsCountsCache.Clear()
+	})
+	request := func(query string) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/Items/Counts?"+query, nil)
+		_, err := fetchItemsCounts(c)
+		return err
+	}
+
+	conf := &config.ItemsCounts{Enable: true, Mode: config.ItemsCountsModeDynamic, ApiKey: "server-key"}
+	if err := conf.Init(); err != nil {
+		t.Fatal(err)
+	}
+	config.Set(&config.Config{Emby: &config.Emby{Host: emby.URL}, ItemsCounts: conf})
+
+	if err := request("api_key=forged"); err == nil {
+		t.Error("无效的 api_key 不应返回统计数量")
+	}
+	for _, q := range []string{"api_key=token-a", "api_key=token-b", "api_key=forged"} {
+		request(q)
+	}
+	if len(countsQueries) != 1 || countsQueries[0] != "api_key=server-key" {
+		t.Errorf("全局统计请求 = %v, want [api_key=server-key]", countsQueries)
+	}
+
+	countsQueries = nil
+	itemsCountsCache.Clear()
+	conf.PerUser = true
+	if err := request("api_key=token-b&UserId=u1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(countsQueries) != 1 || countsQueries[0] != "UserId=u2&api_key=token-b" {
+		t.Errorf("按用户统计请求 = %v, want [UserId=u2&api_key=token-b]", countsQueries)
+	}
+}
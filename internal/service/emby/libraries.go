@@ -0,0 +1,478 @@
+package emby
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// libraryNamesTtl 用户媒体库列表的缓存时长, 查询不到媒体库时才会重新查询
+const libraryNamesTtl = time.Minute * 10
+
+// userLibraries 用户可见的媒体库
+type userLibraries struct {
+	names    map[string]string // 媒体库 id => 名称
+	loadedAt time.Time         // 查询时间
+}
+
+// libraryNames 缓存用户可见的媒体库 id 与名称的对应关系, key: 用户 id
+//
+// 不同用户可见的媒体库不同, 需要分开缓存; 与 tokenUsers 相同, 用户数量有限, 不作大小限制
+var libraryNames = sync.Map{}
+
+// libraryQuery 合并多个媒体库查询结果时使用的参数
+type libraryQuery struct {
+	itemsKey  string // 响应中项目列表的属性名
+	sortBy    string // 请求未指定排序方式时的默认排序字段
+	sortOrder string // 请求未指定排序方式时的默认排序顺序
+}
+
+var (
+	// itemsQuery Items 接口, 按请求参数排序
+	itemsQuery = libraryQuery{itemsKey: "Items"}
+	// resumeQuery 继续观看接口, 默认按最近播放时间倒序
+	resumeQuery = libraryQuery{itemsKey: "Items", sortBy: "DatePlayed", sortOrder: "Descending"}
+	// hintsQuery 搜索提示接口
+	hintsQuery = libraryQuery{itemsKey: "SearchHints"}
+)
+
+// virtualLibraryRules 获取对指定用户生效的媒体库虚拟化规则
+func virtualLibraryRules(userId string) []*config.VirtualLibrary {
+	rules := make([]*config.VirtualLibrary, 0)
+	if userId == "" {
+		return rules
+	}
+	for _, vl := range config.C().Emby.VirtualLibraries {
+		if vl.AppliesTo(userId) {
+			rules = append(rules, vl)
+		}
+	}
+	return rules
+}
+
+// requestLibraryRules 获取对请求生效的媒体库虚拟化规则
+//
+// 用户按 api_key 所属用户判断, 没有配置规则时不会查询用户
+func requestLibraryRules(c *gin.Context) (userId string, rules []*config.VirtualLibrary) {
+	if len(config.C().Emby.VirtualLibraries) == 0 {
+		return "", nil
+	}
+	userId = RequestUserId(c)
+	return userId, virtualLibraryRules(userId)
+}
+
+// ProxyUserViews 代理用户媒体库列表接口, 按虚拟化规则隐藏、重命名媒体库
+//
+// 被合并到其他媒体库的媒体库同样不再单独展示
+func ProxyUserViews(c *gin.Context) {
+	userId, rules := requestLibraryRules(c)
+	if len(rules) == 0 {
+		ProxyOrigin(c)
+		return
+	}
+
+	c.Request.Header.Del("Accept-Encoding")
+	resp, err := https.ProxyRequest(c.Request, config.C().Emby.Host)
+	if checkErr(c, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		checkErr(c, fmt.Errorf("emby 远程返回了错误的响应码: %d", resp.StatusCode))
+		return
+	}
+	resJson, err := jsons.Read(resp.Body)
+	if checkErr(c, err) {
+		return
+	}
+
+	defer func() {
+		https.CloneHeader(c.Writer, resp.Header)
+		jsons.OkResp(c.Writer, resJson)
+	}()
+
+	views, ok := resJson.Attr("Items").Done()
+	if !ok || views.Type() != jsons.JsonTypeArr {
+		return
+	}
+	storeLibraryNames(userId, views)
+	views = views.Filter(func(view *jsons.Item) bool {
+		id, _ := view.Attr("Id").String()
+		name, _ := view.Attr("Name").String()
+
+		for _, vl := range rules {
+			if !vl.Matches(id, name) {
+				continue
+			}
+			if vl.Hide || vl.MergeInto != "" {
+				return false
+			}
+			if vl.Rename != "" {
+				view.Attr("Name").Set(vl.Rename)
+			}
+		}
+		return true
+	})
+	resJson.Put("Items", views)
+	if _, ok := resJson.Attr("TotalRecordCount").Int(); ok {
+		resJson.Attr("TotalRecordCount").Set(views.Len())
+	}
+}
+
+// ProxyLibraryItems 代理指定了 ParentId 的 Items 接口
+//
+// 父级为隐藏的媒体库时返回空列表, 为合并的目标媒体库时合并多个媒体库的结果,
+// 其余情况按原有路由处理
+func ProxyLibraryItems(c *gin.Context) {
+	if handleHiddenLibraries(c, itemsQuery) {
+		return
+	}
+	if routes.Matches(routes.UserEpisodeItems, c.Request.RequestURI) {
+		ProxyAddItemsPreviewInfo(c)
+		return
+	}
+	ProxyOrigin(c)
+}
+
+// ProxySearchItems 代理搜索接口, 搜索结果中不包含对用户隐藏的媒体库中的项目
+func ProxySearchItems(c *gin.Context) {
+	lq := itemsQuery
+	if routes.IdOf(c) == routes.SearchHints {
+		lq = hintsQuery
+	}
+	if handleHiddenLibraries(c, lq) {
+		return
+	}
+	if routes.Matches(routes.UserEpisodeItems, c.Request.RequestURI) {
+		ProxyAddItemsPreviewInfo(c)
+		return
+	}
+	ProxyOrigin(c)
+}
+
+// ProxyResumeItems 代理继续观看接口, 不展示对用户隐藏的媒体库中的项目
+func ProxyResumeItems(c *gin.Context) {
+	if handleHiddenLibraries(c, resumeQuery) {
+		return
+	}
+	ProxyOrigin(c)
+}
+
+// handleHiddenLibraries 按虚拟化规则处理媒体库中项目的查询请求, 未处理时返回 false
+//
+// 指定了 ParentId 时, 父级为隐藏的媒体库返回空列表, 为合并的目标媒体库时合并多个媒体库的结果;
+// 未指定 ParentId 且用户存在隐藏的媒体库时, 只在其余媒体库中查询并合并结果
+func handleHiddenLibraries(c *gin.Context, lq libraryQuery) bool {
+	if c.Query("ParentId") == "" {
+		libraryIds, hidden := visibleLibraryIds(c)
+		if !hidden {
+			return false
+		}
+		mergeLibraryItems(c, libraryIds, lq)
+		return true
+	}
+
+	libraryIds, hidden := virtualLibraryIds(c)
+	switch {
+	case hidden:
+		res := jsons.NewEmptyObj()
+		res.Put(lq.itemsKey, jsons.NewEmptyArr())
+		res.Put("TotalRecordCount", jsons.FromValue(0))
+		jsons.OkResp(c.Writer, res)
+		return true
+	case len(libraryIds) > 1:
+		mergeLibraryItems(c, libraryIds, lq)
+		return true
+	}
+	return false
+}
+
+// handleVirtualLatest 按虚拟化规则处理 Latest 请求, 未处理时返回 false
+func handleVirtualLatest(c *gin.Context) bool {
+	libraryIds, hidden := virtualLibraryIds(c)
+	if hidden {
+		jsons.OkResp(c.Writer, jsons.NewEmptyArr())
+		return true
+	}
+	if len(libraryIds) < 2 {
+		return false
+	}
+
+	q := c.Request.URL.Query()
+	limit, limitErr := strconv.Atoi(q.Get("Limit"))
+	header := c.Request.Header.Clone()
+	header.Del("Accept-Encoding")
+
+	merged := make([]*jsons.Item, 0)
+	for _, id := range libraryIds {
+		q.Set("ParentId", id)
+		res, _ := RawFetch(c.Request.Context(), c.Request.URL.Path+"?"+q.Encode(), http.MethodGet, header, nil)
+		if res.Code != http.StatusOK || res.Data.Type() != jsons.JsonTypeArr {
+			logs.Warn("合并媒体库最新项目失败: %s", res.Msg)
+			return false
+		}
+		merged = append(merged, res.Data.ValuesArr()...)
+	}
+
+	sortMergedItems(merged, "DateCreated", "Descending")
+	if limitErr == nil && limit >= 0 && limit < len(merged) {
+		merged = merged[:limit]
+	}
+	resJson := jsons.NewEmptyArr()
+	resJson.Append(merged...)
+	processItemsMediaSources(resJson)
+	jsons.OkResp(c.Writer, resJson)
+	return true
+}
+
+// virtualLibraryIds 根据请求的 ParentId 计算实际需要查询的媒体库 id
+//
+// 第一个 id 为请求的 ParentId, 其余为合并到该媒体库的媒体库 id;
+// 父级为对用户隐藏的媒体库时, hidden 返回 true
+func virtualLibraryIds(c *gin.Context) (ids []string, hidden bool) {
+	parentId := c.Query("ParentId")
+	if parentId == "" {
+		return nil, false
+	}
+	userId, rules := requestLibraryRules(c)
+	if len(rules) == 0 {
+		return nil, false
+	}
+
+	names := lookupLibraryNames(c, userId, parentId)
+	name := names[parentId]
+	ids = []string{parentId}
+	for _, vl := range rules {
+		if vl.Hide && vl.Matches(parentId, name) {
+			return nil, true
+		}
+		if !vl.MergesInto(parentId, name) {
+			continue
+		}
+		if _, ok := libraryIdOf(names, vl.Library); !ok {
+			names = lookupLibraryNames(c, userId, vl.Library)
+		}
+		if id, ok := libraryIdOf(names, vl.Library); ok && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, false
+}
+
+// visibleLibraryIds 计算用户未被隐藏的媒体库 id
+//
+// 用户不存在隐藏的媒体库时, hidden 返回 false
+func visibleLibraryIds(c *gin.Context) (ids []string, hidden bool) {
+	userId, rules := requestLibraryRules(c)
+	if !slices.ContainsFunc(rules, func(vl *config.VirtualLibrary) bool { return vl.Hide }) {
+		return nil, false
+	}
+
+	names := lookupLibraryNames(c, userId, "")
+	for id, name := range names {
+		if slices.ContainsFunc(rules, func(vl *config.VirtualLibrary) bool { return vl.Hide && vl.Matches(id, name) }) {
+			hidden = true
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, hidden
+}
+
+// storeLibraryNames 缓存用户可见的媒体库列表
+func storeLibraryNames(userId string, views *jsons.Item) map[string]string {
+	names := make(map[string]string, views.Len())
+	views.RangeArr(func(_ int, view *jsons.Item) error {
+		id, _ := view.Attr("Id").String()
+		name, _ := view.Attr("Name").String()
+		names[id] = name
+		return nil
+	})
+	libraryNames.Store(userId, &userLibraries{names: names, loadedAt: time.Now()})
+	return names
+}
+
+// lookupLibraryNames 获取用户可见的媒体库列表
+//
+// 缓存中不存在指定的媒体库 id 或名称, 且缓存已超过 libraryNamesTtl 时, 重新查询;
+// 查询失败时使用已缓存的列表
+func lookupLibraryNames(c *gin.Context, userId, nameOrId string) map[string]string {
+	var cached map[string]string
+	if value, ok := libraryNames.Load(userId); ok {
+		ul := value.(*userLibraries)
+		if _, found := libraryIdOf(ul.names, nameOrId); found || time.Since(ul.loadedAt) < libraryNamesTtl {
+			return ul.names
+		}
+		cached = ul.names
+	}
+
+	kType, kName, apiKey := getApiKey(c)
+	uri := fmt.Sprintf("/Users/%s/Views", userId)
+	header := make(http.Header)
+	if kType == Query {
+		uri += fmt.Sprintf("?%s=%s", kName, url.QueryEscape(apiKey))
+	} else {
+		header.Set(kName, apiKey)
+	}
+	res, _ := RawFetch(c.Request.Context(), uri, http.MethodGet, header, nil)
+	if res.Code != http.StatusOK {
+		logs.Warn("查询用户媒体库列表失败: %s", res.Msg)
+		return cached
+	}
+	views, ok := res.Data.Attr("Items").Done()
+	if !ok {
+		return cached
+	}
+	return storeLibraryNames(userId, views)
+}
+
+// libraryIdOf 根据媒体库名称或 id 查询媒体库 id
+func libraryIdOf(names map[string]string, nameOrId string) (string, bool) {
+	if _, ok := names[nameOrId]; ok {
+		return nameOrId, true
+	}
+	for id, name := range names {
+		if name == nameOrId {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// mergeLibraryItems 合并多个媒体库的查询结果, 按请求的排序方式重新排序并分页
+func mergeLibraryItems(c *gin.Context, libraryIds []string, lq libraryQuery) {
+	q := c.Request.URL.Query()
+	if q.Get("SortBy") == "" && lq.sortBy != "" {
+		q.Set("SortBy", lq.sortBy)
+		q.Set("SortOrder", lq.sortOrder)
+	}
+	start, _ := strconv.Atoi(q.Get("StartIndex"))
+	start = max(start, 0)
+	limit, limitErr := strconv.Atoi(q.Get("Limit"))
+	hasLimit := limitErr == nil && limit >= 0
+	q.Del("StartIndex")
+	if hasLimit {
+		// 每个媒体库都需要取出目标页之前的所有数据, 才能保证合并后的顺序正确
+		q.Set("Limit", strconv.Itoa(start+limit))
+	}
+	header := c.Request.Header.Clone()
+	header.Del("Accept-Encoding")
+
+	merged, total := make([]*jsons.Item, 0), 0
+	for _, id := range libraryIds {
+		q.Set("ParentId", id)
+		res, _ := RawFetch(c.Request.Context(), c.Request.URL.Path+"?"+q.Encode(), http.MethodGet, header, nil)
+		if res.Code != http.StatusOK {
+			checkErr(c, fmt.Errorf("合并媒体库 [%s] 失败: %s", id, res.Msg))
+			return
+		}
+		if items, ok := res.Data.Attr(lq.itemsKey).Done(); ok {
+			merged = append(merged, items.ValuesArr()...)
+		}
+		count, _ := res.Data.Attr("TotalRecordCount").Int()
+		total += count
+	}
+
+	sortMergedItems(merged, q.Get("SortBy"), q.Get("SortOrder"))
+	end := len(merged)
+	if hasLimit {
+		end = min(end, start+limit)
+	}
+	page := jsons.NewEmptyArr()
+	if start < end {
+		page.Append(merged[start:end]...)
+	}
+	processItemsMediaSources(page)
+
+	resJson := jsons.NewEmptyObj()
+	resJson.Put(lq.itemsKey, page)
+	resJson.Put("TotalRecordCount", jsons.FromValue(total))
+	logs.Info("合并媒体库 %v: 共 %d 项, 返回 %d 项", libraryIds, total, page.Len())
+	jsons.OkResp(c.Writer, resJson)
+}
+
+// sortMergedItems 按 emby 的排序参数对合并后的 Items 进行稳定排序
+//
+// 多个排序字段以逗号分隔, 不支持的字段视为相等
+func sortMergedItems(items []*jsons.Item, sortBy, sortOrder string) {
+	if strings.TrimSpace(sortBy) == "" {
+		return
+	}
+	keys := strings.Split(sortBy, ",")
+	orders := strings.Split(sortOrder, ",")
+	slices.SortStableFunc(items, func(a, b *jsons.Item) int {
+		for i, key := range keys {
+			res := compareItemField(a, b, strings.TrimSpace(key))
+			if strings.EqualFold(strings.TrimSpace(orders[min(i, len(orders)-1)]), "Descending") {
+				res = -res
+			}
+			if res != 0 {
+				return res
+			}
+		}
+		return 0
+	})
+}
+
+// compareItemField 比较两个 Item 的排序字段
+func compareItemField(a, b *jsons.Item, key string) int {
+	field := key
+	switch strings.ToLower(key) {
+	case "sortname":
+		as, _ := a.Attr("SortName").String()
+		bs, _ := b.Attr("SortName").String()
+		if as == "" || bs == "" {
+			as, _ = a.Attr("Name").String()
+			bs, _ = b.Attr("Name").String()
+		}
+		return strings.Compare(strings.ToLower(as), strings.ToLower(bs))
+	case "runtime":
+		field = "RunTimeTicks"
+	case "dateplayed":
+		ad, _ := a.Attr("UserData").Attr("LastPlayedDate").String()
+		bd, _ := b.Attr("UserData").Attr("LastPlayedDate").String()
+		return strings.Compare(ad, bd)
+	}
+
+	switch av := a.Attr(field).Val().(type) {
+	case string:
+		bv, _ := b.Attr(field).String()
+		return strings.Compare(av, bv)
+	case nil:
+		return 0
+	default:
+		an, _ := itemNumber(a, field)
+		bn, _ := itemNumber(b, field)
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	}
+}
+
+// itemNumber 获取 Item 中的数值字段
+func itemNumber(item *jsons.Item, field string) (float64, bool) {
+	if f, ok := item.Attr(field).Float(); ok {
+		return f, true
+	}
+	n, ok := item.Attr(field).Int64()
+	return float64(n), ok
+}
@@ -0,0 +1,138 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSortMergedItems 测试合并媒体库后的排序
+func TestSortMergedItems(t *testing.T) {
+	raw := `[
+		{"Name": "b", "SortName": "b", "ProductionYear": 2020, "DateCreated": "2024-01-02T00:00:00Z"},
+		{"Name": "a", "SortName": "a", "ProductionYear": 2021, "DateCreated": "2024-01-03T00:00:00Z"},
+		{"Name": "c", "SortName": "c", "ProductionYear": 2020, "DateCreated": "2024-01-01T00:00:00Z"}
+	]`
+
+	tests := []struct {
+		name      string
+		sortBy    string
+		sortOrder string
+		want      string
+	}{
+		{name: "名称升序", sortBy: "SortName", sortOrder: "Ascending", want: "abc"},
+		{name: "添加时间降序", sortBy: "DateCreated", sortOrder: "Descending", want: "abc"},
+		{name: "年份降序再按名称", sortBy: "ProductionYear,SortName", sortOrder: "Descending,Ascending", want: "abc"},
+		{name: "年份升序再按名称", sortBy: "ProductionYear,SortName", sortOrder: "Ascending", want: "bca"},
+		{name: "不支持的字段保持原顺序", sortBy: "Random", want: "bac"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arr, err := jsons.New(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items := arr.ValuesArr()
+			sortMergedItems(items, tt.sortBy, tt.sortOrder)
+			got := ""
+			for _, item := range items {
+				name, _ := item.Attr("Name").String()
+				got += name
+			}
+			if got != tt.want {
+				t.Errorf("sortMergedItems() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHiddenLibraries 测试继续观看与搜索不展示隐藏的媒体库, 媒体库列表按用户分开缓存
+func TestHiddenLibraries(t *testing.T) {
+	emby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		owners := map[string]string{"token-a": "u1", "token-b": "u2"}
+		switch r.URL.Path {
+		case "/emby/Users/Me":
+			id, ok := owners[q.Get(QueryApiKeyName)]
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"Id":"` + id + `"}`))
+		case "/Users/u1/Views":
+			w.Write([]byte(`{"Items":[{"Id":"l1","Name":"电影"},{"Id":"l2","Name":"4K 电影"}]}`))
+		case "/Users/u2/Views":
+			w.Write([]byte(`{"Items":[{"Id":"l3","Name":"4K 电影"}]}`))
+		case "/Users/u1/Items/Resume":
+			items := map[string]string{
+				"l1": `{"Name":"a","UserData":{"LastPlayedDate":"2024-01-01T00:00:00Z"}},{"Name":"b","UserData":{"LastPlayedDate":"2024-01-03T00:00:00Z"}}`,
+				"l2": `{"Name":"hidden","UserData":{"LastPlayedDate":"2024-01-02T00:00:00Z"}}`,
+			}
+			w.Write([]byte(`{"Items":[` + items[q.Get("ParentId")] + `],"TotalRecordCount":1}`))
+		case "/Search/Hints":
+			hints := map[string]string{"l1": `{"Name":"a"}`, "l2": `{"Name":"hidden"}`}
+			w.Write([]byte(`{"SearchHints":[` + hints[q.Get("ParentId")] + `],"TotalRecordCount":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer emby.Close()
+
+	vl := &config.VirtualLibrary{Library: "4K 电影", Users: []string{"u1"}, Hide: true}
+	if err := vl.Init(); err != nil {
+		t.Fatal(err)
+	}
+	old := config.C()
+	config.Set(&config.Config{Emby: &config.Emby{Host: emby.URL, VirtualLibraries: []*config.VirtualLibrary{vl}}})
+	t.Cleanup(func() {
+		config.Set(old)
+		libraryNames.Clear()
+	})
+
+	names := func(w *httptest.ResponseRecorder, key string) string {
+		res, err := jsons.New(w.Body.String())
+		if err != nil {
+			t.Fatalf("响应解析失败: %v, body: %s", err, w.Body.String())
+		}
+		got := ""
+		items, _ := res.Attr(key).Done()
+		items.RangeArr(func(_ int, item *jsons.Item) error {
+			name, _ := item.Attr("Name").String()
+			got += name + ","
+			return nil
+		})
+		return got
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/Users/u1/Items/Resume?api_key=token-a", nil)
+	ProxyResumeItems(c)
+	if got := names(w, "Items"); got != "b,a," {
+		t.Errorf("继续观看 = %s, want b,a,", got)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/Search/Hints?SearchTerm=a&api_key=token-a", nil)
+	c.Set(routes.GinKey, routes.SearchHints)
+	ProxySearchItems(c)
+	if got := names(w, "SearchHints"); got != "a," {
+		t.Errorf("搜索提示 = %s, want a,", got)
+	}
+
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/Users/u1/Items/Resume?api_key=token-b&UserId=u1", nil)
+	if _, hidden := visibleLibraryIds(c); hidden {
+		t.Error("规则不对 u2 生效, 伪造 UserId 不应改变结果")
+	}
+	if got := lookupLibraryNames(c, "u2", "4K 电影"); len(got) != 1 || got["l3"] != "4K 电影" {
+		t.Errorf("u2 的媒体库列表 = %v, want map[l3:4K 电影]", got)
+	}
+}
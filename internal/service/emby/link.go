@@ -0,0 +1,130 @@
+package emby
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LinkUriPrefix 一次性链接的访问前缀
+const LinkUriPrefix = "/ge2o/link/"
+
+// claimedLinkExpired 一次性链接被使用后的有效期, 覆盖一次完整的播放过程
+const claimedLinkExpired = time.Hour * 12
+
+// linkToken 由代理签发的一次性链接信息
+//
+// 链接首次使用后绑定使用者的 ip, 有效期内只允许该 ip 继续请求, 用于客户端拖动进度时的 Range 请求
+type linkToken struct {
+	target   string    // 实际指向的 CDN 直链, 只在代理内部使用, 不会返回给客户端
+	cdnName  string    // CDN 名称
+	clientIp string    // 允许使用链接的客户端 ip, 为空则在首次使用时绑定
+	expired  time.Time // 过期时间, 未使用的链接在 token-ttl 后过期
+	claimed  bool      // 是否已经被使用
+	mu       sync.Mutex
+}
+
+// linkTokens 存放所有未被使用的一次性链接
+var linkTokens = sync.Map{}
+
+func init() {
+	go loopCleanLinkTokens()
+}
+
+// loopCleanLinkTokens 定期清理过期的一次性链接
+func loopCleanLinkTokens() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		linkTokens.Range(func(key, value any) bool {
+			lt := value.(*linkToken)
+			lt.mu.Lock()
+			expired := now.After(lt.expired)
+			lt.mu.Unlock()
+			if expired {
+				linkTokens.Delete(key)
+			}
+			return true
+		})
+	}
+}
+
+// mintLinkToken 为 CDN 直链签发一个一次性链接, 返回代理上的相对地址
+//
+// 资源由代理回传, res 中的签名不能绑定客户端 ip; CDN 开启了 bind-client-ip 时, 链接绑定 clientIp
+func mintLinkToken(res config.MapResult, clientIp string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	lt := &linkToken{
+		target:  res.Url,
+		cdnName: res.Cdn.Name,
+		expired: time.Now().Add(time.Second * time.Duration(res.Cdn.TokenTtl)),
+	}
+	if res.Cdn.BindClientIp {
+		lt.clientIp = clientIp
+	}
+	linkTokens.Store(token, lt)
+	return LinkUriPrefix + token, nil
+}
+
+// claimLinkToken 使用一次性链接
+//
+// 链接在首次使用时绑定当前客户端 ip, 并延长有效期, 其他 ip 的请求会被拒绝
+func claimLinkToken(token, clientIp string) (*linkToken, bool) {
+	value, ok := linkTokens.Load(token)
+	if !ok {
+		return nil, false
+	}
+	lt := value.(*linkToken)
+
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	if time.Now().After(lt.expired) {
+		linkTokens.Delete(token)
+		return nil, false
+	}
+	if lt.clientIp != "" && lt.clientIp != clientIp {
+		logs.Warn("一次性链接客户端 ip 不匹配, 允许: %s, 请求: %s", lt.clientIp, clientIp)
+		return nil, false
+	}
+	if !lt.claimed {
+		lt.claimed = true
+		lt.clientIp = clientIp
+		lt.expired = time.Now().Add(claimedLinkExpired)
+	}
+	return lt, true
+}
+
+// ServeLinkToken 校验一次性链接, 校验通过后由代理回传实际的 CDN 资源
+//
+// 签名直链不会返回给客户端, 链接只能由绑定的客户端在有效期内使用
+func ServeLinkToken(c *gin.Context) {
+	c.Header(cache.HeaderKeyExpired, "-1")
+
+	token := path.Base(c.Request.URL.Path)
+	lt, ok := claimLinkToken(token, c.ClientIP())
+	if !ok {
+		c.String(http.StatusForbidden, "链接无效或已过期")
+		return
+	}
+
+	logs.Success("一次性链接校验通过 [%s], 由代理回传资源", lt.cdnName)
+	if err := proxyStream(c, lt.target); err != nil {
+		logs.Error("一次性链接回传资源失败 [%s]: %v", lt.cdnName, err)
+		c.String(http.StatusBadGateway, "获取资源失败")
+	}
+}
@@ -0,0 +1,114 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestCdn 启动一个模拟的 CDN 服务, 对任意路径返回固定内容
+func newTestCdn(t *testing.T) *httptest.Server {
+	t.Helper()
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "a.mkv", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	t.Cleanup(cdn.Close)
+	return cdn
+}
+
+// TestServeLinkTokenForgedIp 测试伪造 X-Forwarded-For 无法冒用绑定 ip 的一次性链接
+func TestServeLinkTokenForgedIp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const boundIp, remoteIp = "203.0.113.7", "198.51.100.9"
+	cdn := newTestCdn(t)
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		want           int
+	}{
+		{name: "不信任任何代理", trustedProxies: nil, want: http.StatusForbidden},
+		{name: "来源不是可信代理", trustedProxies: []string{"10.0.0.0/8"}, want: http.StatusForbidden},
+		{name: "来源是可信代理", trustedProxies: []string{remoteIp}, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			r.GET(LinkUriPrefix+":token", ServeLinkToken)
+
+			res := config.MapResult{
+				Cdn: &config.CdnConfig{Name: "test", BindClientIp: true, TokenTtl: 60},
+				Url: cdn.URL + "/a.mkv?sign=secret",
+			}
+			uri, err := mintLinkToken(res, boundIp)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, uri, nil)
+			req.RemoteAddr = remoteIp + ":34567"
+			req.Header.Set("X-Forwarded-For", boundIp)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("响应码 = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+// TestServeLinkTokenProxy 测试一次性链接由代理回传资源, 并且只允许首次使用的客户端继续请求
+func TestServeLinkTokenProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cdn := newTestCdn(t)
+	r := gin.New()
+	r.GET(LinkUriPrefix+":token", ServeLinkToken)
+
+	res := config.MapResult{
+		Cdn: &config.CdnConfig{Name: "test", TokenTtl: 60},
+		Url: cdn.URL + "/a.mkv?sign=secret",
+	}
+	uri, err := mintLinkToken(res, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(ip, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, uri, nil)
+		req.RemoteAddr = ip + ":34567"
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("203.0.113.7", "")
+	if w.Code != http.StatusOK || w.Body.String() != "0123456789" {
+		t.Fatalf("首次使用应由代理回传资源, 响应码: %d, 响应: %s", w.Code, w.Body.String())
+	}
+	if loc := w.Header().Get("Location"); strings.Contains(loc+w.Body.String(), "sign=") {
+		t.Errorf("响应中不应包含签名直链: %s", loc)
+	}
+
+	w = serve("203.0.113.7", "bytes=5-")
+	if w.Code != http.StatusPartialContent || w.Body.String() != "56789" || w.Header().Get("Content-Range") != "bytes 5-9/10" {
+		t.Errorf("同一客户端拖动进度失败, 响应码: %d, 响应: %s", w.Code, w.Body.String())
+	}
+
+	if w = serve("198.51.100.9", ""); w.Code != http.StatusForbidden {
+		t.Errorf("其他客户端使用链接, 响应码 = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -2,6 +2,7 @@ package emby
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,6 +17,10 @@ import (
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,7 +32,13 @@ const MediaSourceIdSegment = "[[_]]"
 //
 // uri 中必须有 query 参数 MediaSourceId,
 // 如果没有携带该参数, 可能会请求到多个媒体, 默认返回第一个媒体的本地路径
-func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
+func getEmbyFileLocalPath(ctx context.Context, itemInfo ItemInfo) (localPath string, err error) {
+	ctx, span := traces.Start(ctx, "emby.getEmbyFileLocalPath", attribute.String("emby.item_id", itemInfo.Id))
+	defer func() {
+		span.SetAttributes(attribute.String("emby.local_path", localPath))
+		traces.End(span, err)
+	}()
+
 	var header http.Header
 	switch itemInfo.ApiKeyType {
 	case Header:
@@ -39,7 +50,7 @@ func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
 	}
 
 	innerRequest := func(method string) (*http.Response, error) {
-		resp, err := https.Request(method, config.C.Emby.Host+itemInfo.PlaybackInfoUri).Header(header).Do()
+		resp, err := https.Request(method, config.C().Emby.Host+itemInfo.PlaybackInfoUri).Context(context.WithoutCancel(ctx)).Header(header).Do()
 		if err != nil {
 			return nil, fmt.Errorf("请求 Emby 接口异常, error: %v", err)
 		}
@@ -158,6 +169,8 @@ func detectVirtualVideoDisplayTitle(source *jsons.Item) {
 }
 
 // detectSubtitleStreamsDeliveryUrl 强制将外部挂载字幕的访问方式调整为直链访问
+//
+// 开启外挂字幕 CDN 分发时, 同时记录外挂字幕的本地路径, 供字幕请求映射为 CDN 直链
 func detectSubtitleStreamsDeliveryUrl(source *jsons.Item, apiKey string) {
 	if source == nil || source.Type() != jsons.JsonTypeObj {
 		return
@@ -190,6 +203,13 @@ func detectSubtitleStreamsDeliveryUrl(source *jsons.Item, apiKey string) {
 			return nil
 		}
 
+		subIndex, _ := value.Attr("Index").Int()
+		if config.C().Emby.Subtitles.CdnDelivery {
+			if p, _ := value.Attr("Path").String(); p != "" {
+				storeSubtitlePath(subtitlePathKey(itemId, id, subIndex), urls.Unescape(p))
+			}
+		}
+
 		// DeliveryMethod 为 External 时, Emby 默认会提供 DeliveryUrl 字段, 无需手动修改
 		deliveryMethod, _ := value.Attr("DeliveryMethod").String()
 		if deliveryMethod == "External" {
@@ -197,7 +217,6 @@ func detectSubtitleStreamsDeliveryUrl(source *jsons.Item, apiKey string) {
 		}
 		value.Put("DeliveryMethod", jsons.FromValue("External"))
 
-		subIndex, _ := value.Attr("Index").Int()
 		u, _ := url.Parse(fmt.Sprintf("/Videos/%s/%s/Subtitles/%d/0/Stream.vtt?api_key=%s", itemId, id, subIndex, apiKey))
 		value.Put("DeliveryUrl", jsons.FromValue(u.String()))
 		return nil
@@ -238,6 +257,8 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 	if c == nil {
 		return ItemInfo{}, errors.New("参数 c 不能为空")
 	}
+	_, span := traces.Start(c.Request.Context(), "emby.resolveItemInfo", attribute.String("emby.route_type", string(routeType)))
+	defer span.End()
 
 	// 匹配 item id
 	uri := c.Request.URL.Path
@@ -270,6 +291,10 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 	if itemInfo.ApiKeyType == Query {
 		q.Set(itemInfo.ApiKeyName, itemInfo.ApiKey)
 	}
+	// 携带用户 id, 以便按用户区分 PlaybackInfo 信息
+	if userId := c.Query("UserId"); strs.AllNotEmpty(userId) {
+		q.Set("UserId", userId)
+	}
 	q.Set("reqformat", "json")
 	q.Set("IsPlayback", "false")
 	q.Set("AutoOpenLiveStream", "false")
@@ -0,0 +1,36 @@
+package emby
+
+import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+
+var (
+	// cdnRedirects 各 CDN 的重定向次数
+	cdnRedirects = metrics.NewCounter("ge2o_cdn_redirects_total", "按 CDN 名称统计的重定向次数", "cdn", "kind")
+	// upstreamDuration emby 上游请求耗时
+	upstreamDuration = metrics.NewHistogram("ge2o_emby_request_duration_seconds", "emby 上游请求耗时", nil, "kind")
+	// upstreamErrors emby 上游请求错误数
+	upstreamErrors = metrics.NewCounter("ge2o_emby_request_errors_total", "emby 上游请求错误数", "kind")
+	// apiKeyChecks api_key 鉴权结果
+	apiKeyChecks = metrics.NewCounter("ge2o_api_key_checks_total", "api_key 鉴权结果", "result")
+)
+
+// 重定向类型
+const (
+	redirectKindStream      = "stream"
+	redirectKindProxyStream = "proxy-stream"
+	redirectKindSubtitle    = "subtitle"
+	redirectKindImage       = "image"
+)
+
+// 上游请求类型
+const (
+	upstreamKindApi   = "api"
+	upstreamKindProxy = "proxy"
+)
+
+// api_key 鉴权结果
+const (
+	apiKeyTrusted  = "trusted"
+	apiKeyPassed   = "passed"
+	apiKeyRejected = "rejected"
+	apiKeyError    = "error"
+)
@@ -1,14 +1,13 @@
 package emby
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
@@ -30,14 +29,8 @@ const (
 	PlaybackCommonPayload = `{"DeviceProfile":{"MaxStaticBitrate":140000000,"MaxStreamingBitrate":140000000,"MusicStreamingTranscodingBitrate":192000,"DirectPlayProfiles":[{"Container":"mp4,m4v","Type":"Video","VideoCodec":"h264,h265,hevc,av1,vp8,vp9","AudioCodec":"mp3,aac,opus,flac,vorbis"},{"Container":"mkv","Type":"Video","VideoCodec":"h264,h265,hevc,av1,vp8,vp9","AudioCodec":"mp3,aac,opus,flac,vorbis"},{"Container":"flv","Type":"Video","VideoCodec":"h264","AudioCodec":"aac,mp3"},{"Container":"3gp","Type":"Video","VideoCodec":"","AudioCodec":"mp3,aac,opus,flac,vorbis"},{"Container":"mov","Type":"Video","VideoCodec":"h264","AudioCodec":"mp3,aac,opus,flac,vorbis"},{"Container":"opus","Type":"Audio"},{"Container":"mp3","Type":"Audio","AudioCodec":"mp3"},{"Container":"mp2,mp3","Type":"Audio","AudioCodec":"mp2"},{"Container":"m4a","AudioCodec":"aac","Type":"Audio"},{"Container":"mp4","AudioCodec":"aac","Type":"Audio"},{"Container":"flac","Type":"Audio"},{"Container":"webma,webm","Type":"Audio"},{"Container":"wav","Type":"Audio","AudioCodec":"PCM_S16LE,PCM_S24LE"},{"Container":"ogg","Type":"Audio"},{"Container":"webm","Type":"Video","AudioCodec":"vorbis,opus","VideoCodec":"av1,VP8,VP9"}],"TranscodingProfiles":[{"Container":"aac","Type":"Audio","AudioCodec":"aac","Context":"Streaming","Protocol":"hls","MaxAudioChannels":"2","MinSegments":"1","BreakOnNonKeyFrames":true},{"Container":"aac","Type":"Audio","AudioCodec":"aac","Context":"Streaming","Protocol":"http","MaxAudioChannels":"2"},{"Container":"mp3","Type":"Audio","AudioCodec":"mp3","Context":"Streaming","Protocol":"http","MaxAudioChannels":"2"},{"Container":"opus","Type":"Audio","AudioCodec":"opus","Context":"Streaming","Protocol":"http","MaxAudioChannels":"2"},{"Container":"wav","Type":"Audio","AudioCodec":"wav","Context":"Streaming","Protocol":"http","MaxAudioChannels":"2"},{"Container":"opus","Type":"Audio","AudioCodec":"opus","Context":"Static","Protocol":"http","MaxAudioChannels":"2"},{"Container":"mp3","Type":"Audio","AudioCodec":"mp3","Context":"Static","Protocol":"http","MaxAudioChannels":"2"},{"Container":"aac","Type":"Audio","AudioCodec":"aac","Context":"Static","Protocol":"http","MaxAudioChannels":"2"},{"Container":"wav","Type":"Audio","AudioCodec":"wav","Context":"Static","Protocol":"http","MaxAudioChannels":"2"},{"Container":"mkv","Type":"Video","AudioCodec":"mp3,aac,opus,flac,vorbis","VideoCodec":"h264,h265,hevc,av1,vp8,vp9","Context":"Static","MaxAudioChannels":"2","CopyTimestamps":true},{"Container":"ts","Type":"Video","AudioCodec":"mp3,aac","VideoCodec":"h264,h265,hevc,av1","Context":"Streaming","Protocol":"hls","MaxAudioChannels":"2","MinSegments":"1","BreakOnNonKeyFrames":true,"ManifestSubtitles":"vtt"},{"Container":"webm","Type":"Video","AudioCodec":"vorbis","VideoCodec":"vpx","Context":"Streaming","Protocol":"http","MaxAudioChannels":"2"},{"Container":"mp4","Type":"Video","AudioCodec":"mp3,aac,opus,flac,vorbis","VideoCodec":"h264","Context":"Static","Protocol":"http"}],"ContainerProfiles":[],"CodecProfiles":[{"Type":"VideoAudio","Codec":"aac","Conditions":[{"Condition":"Equals","Property":"IsSecondaryAudio","Value":"false","IsRequired":"false"}]},{"Type":"VideoAudio","Conditions":[{"Condition":"Equals","Property":"IsSecondaryAudio","Value":"false","IsRequired":"false"}]},{"Type":"Video","Codec":"h264","Conditions":[{"Condition":"EqualsAny","Property":"VideoProfile","Value":"high|main|baseline|constrained baseline|high 10","IsRequired":false},{"Condition":"LessThanEqual","Property":"VideoLevel","Value":"62","IsRequired":false}]},{"Type":"Video","Codec":"hevc","Conditions":[{"Condition":"EqualsAny","Property":"VideoCodecTag","Value":"hvc1|hev1|hevc|hdmv","IsRequired":false}]}],"SubtitleProfiles":[{"Format":"vtt","Method":"Hls"},{"Format":"eia_608","Method":"VideoSideData","Protocol":"hls"},{"Format":"eia_708","Method":"VideoSideData","Protocol":"hls"},{"Format":"vtt","Method":"External"},{"Format":"ass","Method":"External"},{"Format":"ssa","Method":"External"}],"ResponseProfiles":[{"Type":"Video","Container":"m4v","MimeType":"video/mp4"}]}}`
 )
 
-var (
-
-	// ValidCacheItemsTypeRegex 校验 Items 的 Type 参数, 通过正则才覆盖 PlaybackInfo 缓存
-	ValidCacheItemsTypeRegex = regexp.MustCompile(`(?i)(movie|episode)`)
-
-	// UnvalidCacheItemsUARegex 特定客户端的 Items 请求, 不覆盖 PlaybackInfo 缓存
-	UnvalidCacheItemsUARegex = regexp.MustCompile(`(?i)(infuse)`)
-)
+// ValidCacheItemsTypeRegex 校验 Items 的 Type 参数, 通过正则才覆盖 PlaybackInfo 缓存
+var ValidCacheItemsTypeRegex = regexp.MustCompile(`(?i)(movie|episode)`)
 
 // TransferPlaybackInfo 代理 PlaybackInfo 接口, 防止客户端转码
 func TransferPlaybackInfo(c *gin.Context) {
@@ -47,6 +40,19 @@ func TransferPlaybackInfo(c *gin.Context) {
 	if checkErr(c, err) {
 		return
 	}
+	itemInfo.CacheTag = playbackCacheTag(c)
+
+	// 记录客户端选择的音轨和字幕
+	recordStreamPref(c, itemInfo)
+
+	// 客户端规则不要求改写时, 直接代理到源服务器
+	rule := matchClientRule(c)
+	if !rule.ShouldRewritePlaybackInfo() {
+		logs.Info("客户端规则 [%s] 不改写 PlaybackInfo, 回源处理", rule.Name)
+		c.Header(cache.HeaderKeyExpired, "-1")
+		ProxyOrigin(c)
+		return
+	}
 
 	// 如果是远程资源, 直接代理到源服务器
 	if handleSpecialPlayback(c, itemInfo) {
@@ -63,9 +69,10 @@ func TransferPlaybackInfo(c *gin.Context) {
 
 	// 2 请求 emby 源服务器的 PlaybackInfo 信息
 	c.Request.Header.Del("Accept-Encoding")
+	payload := playbackInfoPayload(c)
 	originRequestBody := c.Request.Body
-	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
-	res, respHeader := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	c.Request.Body = payloadBody(payload)
+	res, respHeader := RawFetch(c.Request.Context(), itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
 		return
@@ -120,10 +127,12 @@ func TransferPlaybackInfo(c *gin.Context) {
 			source.Attr("Path").Set(urls.Unescape(path))
 		}
 
-		source.Put("SupportsTranscoding", jsons.FromValue(false))
-		source.DelKey("TranscodingUrl")
-		source.DelKey("TranscodingSubProtocol")
-		source.DelKey("TranscodingContainer")
+		if !allowTranscoding(c, rule, source) {
+			source.Put("SupportsTranscoding", jsons.FromValue(false))
+			source.DelKey("TranscodingUrl")
+			source.DelKey("TranscodingSubProtocol")
+			source.DelKey("TranscodingContainer")
+		}
 
 		return nil
 	})
@@ -131,6 +140,14 @@ func TransferPlaybackInfo(c *gin.Context) {
 	if err == haveReturned {
 		return
 	}
+	recordPlaybackAnalytics(c, itemInfo, mediaSources)
+
+	// 按多版本偏好规则排序
+	mediaSources = sortMediaSources(c, mediaSources)
+	resJson.Put("MediaSources", mediaSources)
+
+	// 应用用户在剧集上的音轨字幕偏好
+	applyStreamPref(c, itemInfo, mediaSources)
 
 	defer func() {
 		// 缓存 12h
@@ -152,9 +169,12 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 	}
 
 	c.Request.Header.Del("Accept-Encoding")
+	payload := playbackInfoPayload(c)
 	originRequestBody := c.Request.Body
-	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
-	res, _ := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	c.Request.Body = payloadBody(payload)
+	res, _ := RawFetch(c.Request.Context(), itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
+	// 还原客户端原始请求体, 供后续回源或再次请求使用
+	c.Request.Body = originRequestBody
 	if res.Code != http.StatusOK {
 		return false
 	}
@@ -170,7 +190,6 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 		iis, _ := value.Attr("IsInfiniteStream").Bool()
 		if iis {
 			// 特殊媒体直接代理至源服务器
-			c.Request.Body = originRequestBody
 			ProxyOrigin(c)
 			return haveReturned
 		}
@@ -197,7 +216,7 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 	}
 	reqId := itemInfo.MsInfo.RawId
 
-	if !config.C.Cache.Enable {
+	if !config.C().Cache.Enable {
 		// 未开启缓存功能
 		return false
 	}
@@ -321,7 +340,7 @@ func useCacheSpacePlaybackInfo(c *gin.Context, itemInfo ItemInfo) bool {
 	}
 
 	// 如果是单个查询, 则手动请求一次全量
-	if _, err := fetchFullPlaybackInfo(itemInfo); err != nil {
+	if _, err := fetchFullPlaybackInfo(c, itemInfo); err != nil {
 		logs.Error("更新缓存空间 PlaybackInfo 信息异常: %v", err)
 		c.String(http.StatusInternalServerError, "查无缓存, 请稍后尝试重新播放")
 		return true
@@ -353,7 +372,9 @@ func LoadCacheItems(c *gin.Context) {
 }
 
 // fetchFullPlaybackInfo 请求全量的 PlaybackInfo 信息
-func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
+//
+// 会透传客户端特征信息, 保证全量请求与原请求匹配到相同的客户端规则
+func fetchFullPlaybackInfo(c *gin.Context, itemInfo ItemInfo) (*jsons.Item, error) {
 	u, err := url.Parse(config.ServerInternalRequestHost() + itemInfo.PlaybackInfoUri)
 	if err != nil {
 		return nil, fmt.Errorf("PlaybackInfo 地址异常: %v, uri: %s", err, itemInfo.PlaybackInfoUri)
@@ -362,13 +383,23 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 	q.Del("MediaSourceId")
 	u.RawQuery = q.Encode()
 
-	reqBody := io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
+	reqBody := payloadBody(playbackInfoPayload(c))
 	header := make(http.Header)
 	header.Set("Content-Type", "text/plain")
+	info := resolveClientInfo(c)
+	header.Set("User-Agent", info.UserAgent)
+	if info.Client != "" {
+		header.Set("X-Emby-Client", info.Client)
+	}
+	if info.DeviceName != "" {
+		header.Set("X-Emby-Device-Name", info.DeviceName)
+	}
+	// 携带客户端 ip, 保证全量请求与原请求计算出相同的缓存空间 key
+	setInternalClientIp(header, c)
 	if itemInfo.ApiKeyType == Header {
 		header.Set(itemInfo.ApiKeyName, itemInfo.ApiKey)
 	}
-	resp, err := https.Post(u.String()).Header(header).Body(reqBody).Do()
+	resp, err := https.Post(u.String()).Context(c.Request.Context()).Header(header).Body(reqBody).Do()
 	if err != nil {
 		return nil, fmt.Errorf("获取全量 PlaybackInfo 失败: %v", err)
 	}
@@ -386,7 +417,40 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 
 // calcPlaybackInfoSpaceCacheKey 根据请求的 item 信息计算 PlaybackInfo 在缓存空间中的 key
 func calcPlaybackInfoSpaceCacheKey(itemInfo ItemInfo) string {
-	return itemInfo.Id + "_" + itemInfo.ApiKey
+	key := itemInfo.Id + "_" + itemInfo.ApiKey
+	if itemInfo.CacheTag != "" {
+		key += "_" + itemInfo.CacheTag
+	}
+	return key
+}
+
+// playbackCacheTag 计算 PlaybackInfo 缓存 key 的附加标识
+//
+// 转码策略或多版本偏好规则与客户端网络有关时, 内外网客户端的 PlaybackInfo 需要分开缓存;
+// 用户的音轨字幕偏好发生变更后, 需要使用新的缓存
+func playbackCacheTag(c *gin.Context) string {
+	tags := make([]string, 0, 2)
+	e := config.C().Emby
+	if e.Transcode.RemoteSensitive() || e.SourcePreferenceRemoteSensitive() {
+		if !config.C().Network.IsTrusted(c.ClientIP()) {
+			tags = append(tags, "remote")
+		} else {
+			tags = append(tags, "lan")
+		}
+	}
+	if tag := streamPrefTag(c); tag != "" {
+		tags = append(tags, tag)
+	}
+	return strings.Join(tags, "_")
+}
+
+// CacheKeyTagger 为请求设置缓存 key 的附加标识, 避免响应结果与客户端相关的请求复用同一份缓存
+func CacheKeyTagger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tag := playbackCacheTag(c); tag != "" {
+			c.Set(cache.CacheKeyTagGinKey, tag)
+		}
+	}
 }
 
 // getPlaybackInfoByCacheSpace 从缓存空间中获取 PlaybackInfo 信息
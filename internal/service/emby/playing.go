@@ -2,6 +2,7 @@ package emby
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -31,6 +32,7 @@ func PlayingStoppedHelper(c *gin.Context) {
 
 	// 代理原始 Stopped 接口
 	ProxyOrigin(c)
+	recordPlayingAnalytics(c, bodyJson, true)
 
 	// 提取 api apiKey
 	kType, kName, apiKey := getApiKey(c)
@@ -54,7 +56,7 @@ func PlayingStoppedHelper(c *gin.Context) {
 	body.Put("ItemId", jsons.FromValue(itemId))
 	body.Put("PlaySessionId", jsons.FromValue(randoms.RandomHex(32)))
 	body.Put("PositionTicks", jsons.FromValue(bodyJson.Attr("PositionTicks").Val()))
-	go sendPlayingProgress(kType, kName, apiKey, body)
+	go sendPlayingProgress(context.WithoutCancel(c.Request.Context()), kType, kName, apiKey, body)
 }
 
 // PlayingProgressHelper 拦截 Progress 请求, 如果进度报告为 0, 认为是无效请求
@@ -74,11 +76,12 @@ func PlayingProgressHelper(c *gin.Context) {
 		c.Status(http.StatusNoContent)
 		return
 	}
+	recordPlayingAnalytics(c, bodyJson, false)
 	ProxyOrigin(c)
 }
 
 // sendPlayingProgress 发送辅助播放进度请求
-func sendPlayingProgress(kType ApiKeyType, kName, apiKey string, body *jsons.Item) {
+func sendPlayingProgress(ctx context.Context, kType ApiKeyType, kName, apiKey string, body *jsons.Item) {
 	if body == nil {
 		return
 	}
@@ -91,7 +94,7 @@ func sendPlayingProgress(kType ApiKeyType, kName, apiKey string, body *jsons.Ite
 		} else {
 			header.Set(kName, apiKey)
 		}
-		resp, err := https.Post(remote).Header(header).Body(io.NopCloser(bytes.NewBuffer([]byte(body.String())))).Do()
+		resp, err := https.Post(remote).Context(ctx).Header(header).Body(io.NopCloser(bytes.NewBuffer([]byte(body.String())))).Do()
 		if err != nil {
 			return err
 		}
@@ -103,11 +106,11 @@ func sendPlayingProgress(kType ApiKeyType, kName, apiKey string, body *jsons.Ite
 	}
 
 	logs.Tip("开始发送辅助 Progress 进度记录, 内容: %v", body)
-	if err := inner(config.C.Emby.Host + "/emby/Sessions/Playing/Progress"); err != nil {
+	if err := inner(config.C().Emby.Host + "/emby/Sessions/Playing/Progress"); err != nil {
 		logs.Warn("辅助发送 Progress 进度记录失败: %v", err)
 		return
 	}
-	if err := inner(config.C.Emby.Host + "/emby/Sessions/Playing/Stopped"); err != nil {
+	if err := inner(config.C().Emby.Host + "/emby/Sessions/Playing/Stopped"); err != nil {
 		logs.Warn("辅助发送 Progress 进度记录失败: %v", err)
 		return
 	}
@@ -0,0 +1,101 @@
+package emby
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlaybackPayloadGinKey 生成的 PlaybackInfo 请求体存放在 gin 上下文中的 key
+const PlaybackPayloadGinKey = "playbackPayload"
+
+// playbackInfoPayload 根据客户端规则生成请求 PlaybackInfo 的请求体, 生成结果会缓存在 gin 上下文中
+func playbackInfoPayload(c *gin.Context) string {
+	if payload, ok := c.Get(PlaybackPayloadGinKey); ok {
+		return payload.(string)
+	}
+	payload := resolvePlaybackInfoPayload(c, matchClientRule(c))
+	c.Set(PlaybackPayloadGinKey, payload)
+	return payload
+}
+
+// resolvePlaybackInfoPayload 根据客户端规则的 DeviceProfile 配置生成请求体
+//
+// 读取客户端原始请求体后, 会将其重新设置回请求中, 以便后续回源使用
+func resolvePlaybackInfoPayload(c *gin.Context, rule *config.ClientRule) string {
+	if payload, ok := rule.DeviceProfilePayload(); ok {
+		return payload
+	}
+	if rule.DeviceProfile != config.DeviceProfileClient {
+		return PlaybackCommonPayload
+	}
+
+	bodyBytes, newBody, err := https.ExtractReqBody(c.Request.Body)
+	if err != nil {
+		logs.Warn("读取客户端 PlaybackInfo 请求体失败, 使用通用 DeviceProfile: %v", err)
+		return PlaybackCommonPayload
+	}
+	c.Request.Body = newBody
+
+	payload, ok := mergeClientDeviceProfile(bodyBytes)
+	if !ok {
+		logs.Warn("客户端请求未携带有效的 DeviceProfile, 使用通用 DeviceProfile")
+		return PlaybackCommonPayload
+	}
+	return payload
+}
+
+// mergeClientDeviceProfile 保留客户端原始的 DeviceProfile, 并合并通用配置中的 DirectPlay 配置
+//
+// 客户端已经声明过的 Type + Container 组合不会被覆盖, 码率等限制均以客户端为准
+func mergeClientDeviceProfile(clientBody []byte) (string, bool) {
+	if len(bytes.TrimSpace(clientBody)) == 0 {
+		return "", false
+	}
+	body, err := jsons.New(string(clientBody))
+	if err != nil || body.Type() != jsons.JsonTypeObj {
+		return "", false
+	}
+	profile, ok := body.Attr("DeviceProfile").Done()
+	if !ok || profile.Type() != jsons.JsonTypeObj {
+		return "", false
+	}
+
+	common, _ := jsons.New(PlaybackCommonPayload)
+	commonDps, _ := common.Attr("DeviceProfile").Attr("DirectPlayProfiles").Done()
+
+	dps, ok := profile.Attr("DirectPlayProfiles").Done()
+	if !ok || dps.Type() != jsons.JsonTypeArr {
+		profile.Put("DirectPlayProfiles", commonDps)
+		return body.String(), true
+	}
+
+	profileKey := func(dp *jsons.Item) string {
+		typ, _ := dp.Attr("Type").String()
+		container, _ := dp.Attr("Container").String()
+		return typ + "|" + container
+	}
+	exists := make(map[string]struct{})
+	dps.RangeArr(func(_ int, dp *jsons.Item) error {
+		exists[profileKey(dp)] = struct{}{}
+		return nil
+	})
+	commonDps.RangeArr(func(_ int, dp *jsons.Item) error {
+		if _, ok := exists[profileKey(dp)]; !ok {
+			dps.Append(dp)
+		}
+		return nil
+	})
+	return body.String(), true
+}
+
+// payloadBody 将请求体字符串包装成可读取的流
+func payloadBody(payload string) io.ReadCloser {
+	return io.NopCloser(bytes.NewBufferString(payload))
+}
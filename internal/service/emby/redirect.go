@@ -11,6 +11,7 @@ package emby
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/url"
@@ -18,8 +19,10 @@ import (
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/analytics"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/accesslog"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
 
 	"github.com/gin-gonic/gin"
@@ -39,28 +42,78 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		return
 	}
 	logs.Info("解析到的 itemInfo: %v", itemInfo)
+	accesslog.SetItem(c, itemInfo.Id, itemInfo.MsInfo.OriginId)
+	cache.SetMeta(c, metaKeyItemId, itemInfo.Id)
+	cache.SetMeta(c, metaKeyMediaSourceId, itemInfo.MsInfo.OriginId)
 
 	// 2 从 Emby 获取 STRM 文件中的本地路径
-	localPath, err := getEmbyFileLocalPath(itemInfo)
+	localPath, err := getEmbyFileLocalPath(c.Request.Context(), itemInfo)
 	if checkErr(c, err) {
 		return
 	}
 	logs.Info("STRM 文件路径: %s", localPath)
 
 	// 3 将本地路径映射为 CDN 直链
-	cdnUrl, err := config.C.Emby.Strm.MapPath(localPath)
-	if checkErr(c, err) {
+	mapRes, err := config.C().Emby.Strm.Resolve(localPath, c.ClientIP())
+	if checkCdnErr(c, "", itemInfo, err) {
 		return
 	}
 
-	// 4 返回 302 重定向
-	logs.Success("302 重定向到: %s", cdnUrl)
+	// 异步发送一个播放 Playback 请求, 触发 emby 解析 strm 视频格式
+	triggerEmbyPlayback(c.Request.Context(), itemInfo)
+
+	// 无法跟随 302 的客户端, 由代理回传资源
+	rule := matchClientRule(c)
+	if mapRes.Cdn.ProxyStream || rule.RedirectMode == config.RedirectModeProxyStream {
+		// 由代理发起请求时, 签名不能绑定客户端 ip
+		if cdn := mapRes.Cdn.Name; mapRes.Cdn.BindClientIp {
+			if mapRes, err = config.C().Emby.Strm.Resolve(localPath, ""); checkCdnErr(c, cdn, itemInfo, err) {
+				return
+			}
+		}
+		logs.Success("代理回传 [%s]: %s", mapRes.Cdn.Name, mapRes.Url)
+		recordRedirect(c, mapRes, redirectKindProxyStream)
+		analytics.RecordStream(itemInfo.ApiKey, itemInfo.Id, itemInfo.MsInfo.OriginId, mapRes.Cdn.Name)
+		checkCdnErr(c, mapRes.Cdn.Name, itemInfo, proxyStream(c, mapRes.Url))
+		return
+	}
+	cdnUrl := mapRes.Url
+
+	// 绑定了客户端 ip 或使用一次性链接时, 重定向结果不能被其他请求复用
 	c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
-	c.Redirect(http.StatusFound, cdnUrl)
+	if mapRes.Cdn.BindClientIp || mapRes.Cdn.OneTimeToken {
+		c.Header(cache.HeaderKeyExpired, "-1")
+	}
+	if mapRes.Cdn.OneTimeToken {
+		// 一次性链接由代理回传资源, 签名不能绑定客户端 ip, 客户端 ip 由链接自身校验
+		tokenRes, err := config.C().Emby.Strm.Resolve(localPath, "")
+		if checkCdnErr(c, mapRes.Cdn.Name, itemInfo, err) {
+			return
+		}
+		cdnUrl, err = mintLinkToken(tokenRes, c.ClientIP())
+		if checkCdnErr(c, mapRes.Cdn.Name, itemInfo, err) {
+			return
+		}
+	}
 
-	// 异步发送一个播放 Playback 请求, 触发 emby 解析 strm 视频格式
+	// 4 按客户端规则返回重定向
+	code := http.StatusFound
+	if rule.RedirectMode == config.RedirectMode307 {
+		code = http.StatusTemporaryRedirect
+	}
+	logs.Success("%d 重定向到 (客户端规则: %s): %s", code, rule.Name, cdnUrl)
+	recordRedirect(c, mapRes, redirectKindStream)
+	analytics.RecordStream(itemInfo.ApiKey, itemInfo.Id, itemInfo.MsInfo.OriginId, mapRes.Cdn.Name)
+	c.Redirect(code, cdnUrl)
+}
+
+// triggerEmbyPlayback 异步发送一个播放 Playback 请求, 触发 emby 解析 strm 视频格式
+//
+// 请求在后台执行, 不随客户端请求的结束而取消
+func triggerEmbyPlayback(ctx context.Context, itemInfo ItemInfo) {
+	ctx = context.WithoutCancel(ctx)
 	go func() {
-		originUrl, err := url.Parse(config.C.Emby.Host + itemInfo.PlaybackInfoUri)
+		originUrl, err := url.Parse(config.C().Emby.Host + itemInfo.PlaybackInfoUri)
 		if err != nil {
 			return
 		}
@@ -68,7 +121,7 @@ func Redirect2OpenlistLink(c *gin.Context) {
 		q.Set("IsPlayback", "true")
 		q.Set("AutoOpenLiveStream", "true")
 		originUrl.RawQuery = q.Encode()
-		resp, err := https.Post(originUrl.String()).Body(io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))).Do()
+		resp, err := https.Post(originUrl.String()).Context(ctx).Body(io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))).Do()
 		if err != nil {
 			return
 		}
@@ -98,7 +151,7 @@ func checkErr(c *gin.Context, err error) bool {
 	c.Header(cache.HeaderKeyExpired, "-1")
 
 	// 采用拒绝策略, 直接返回错误
-	if config.C.Emby.ProxyErrorStrategy == config.PeStrategyReject {
+	if config.C().Emby.ProxyErrorStrategy == config.PeStrategyReject {
 		logs.Error("代理接口失败: %v", err)
 		c.String(http.StatusInternalServerError, "代理接口失败, 请检查日志")
 		return true
@@ -0,0 +1,112 @@
+package emby
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sortMediaSources 按多版本偏好规则对 MediaSources 进行稳定排序, 返回排序后的新数组
+//
+// 没有生效的规则, 或者只有一个版本时, 直接返回原数组
+func sortMediaSources(c *gin.Context, mediaSources *jsons.Item) *jsons.Item {
+	if mediaSources.Len() < 2 {
+		return mediaSources
+	}
+
+	info := resolveClientInfo(c)
+	remote := !config.C().Network.IsTrusted(c.ClientIP())
+	prefs := make([]*config.SourcePreference, 0)
+	for _, sp := range config.C().Emby.SourcePreferences {
+		if sp.Applies(info.Client, info.UserAgent, remote) {
+			prefs = append(prefs, sp)
+		}
+	}
+	if len(prefs) == 0 {
+		return mediaSources
+	}
+
+	type scoredSource struct {
+		source *jsons.Item
+		scores []bool
+	}
+	sources := make([]scoredSource, 0, mediaSources.Len())
+	mediaSources.RangeArr(func(_ int, source *jsons.Item) error {
+		scores := make([]bool, len(prefs))
+		for i, sp := range prefs {
+			scores[i] = preferSource(sp, source)
+		}
+		sources = append(sources, scoredSource{source: source, scores: scores})
+		return nil
+	})
+
+	slices.SortStableFunc(sources, func(a, b scoredSource) int {
+		for i := range a.scores {
+			if a.scores[i] == b.scores[i] {
+				continue
+			}
+			if a.scores[i] {
+				return -1
+			}
+			return 1
+		}
+		return 0
+	})
+
+	sorted := jsons.NewEmptyArr()
+	for _, s := range sources {
+		sorted.Append(s.source)
+	}
+	return sorted
+}
+
+// preferSource 判断 MediaSource 是否满足偏好规则
+func preferSource(sp *config.SourcePreference, source *jsons.Item) bool {
+	switch sp.Type {
+	case config.SpTypeMapped:
+		path, ok := source.Attr("Path").String()
+		if !ok {
+			return false
+		}
+		return config.C().Emby.Strm.HasMapping(path)
+	case config.SpTypeMaxHeight:
+		height, ok := sourceVideoStream(source).Attr("Height").Int()
+		return ok && height <= sp.MaxHeight
+	case config.SpTypeHdr:
+		return isHdrSource(source)
+	case config.SpTypeSdr:
+		return !isHdrSource(source)
+	}
+	return false
+}
+
+// sourceVideoStream 获取 MediaSource 中的视频流, 不存在时返回空对象
+func sourceVideoStream(source *jsons.Item) *jsons.Item {
+	streams, ok := source.Attr("MediaStreams").Done()
+	if !ok {
+		return jsons.NewEmptyObj()
+	}
+	idx := streams.FindIdx(func(val *jsons.Item) bool {
+		typ, _ := val.Attr("Type").String()
+		return typ == "Video"
+	})
+	if idx == -1 {
+		return jsons.NewEmptyObj()
+	}
+	stream, _ := streams.Idx(idx).Done()
+	return stream
+}
+
+// isHdrSource 判断 MediaSource 的视频流是否为 HDR
+func isHdrSource(source *jsons.Item) bool {
+	vs := sourceVideoStream(source)
+	if vr, _ := vs.Attr("VideoRange").String(); vr != "" && !strings.EqualFold(vr, "SDR") {
+		return true
+	}
+	ext, _ := vs.Attr("ExtendedVideoType").String()
+	return ext != "" && !strings.EqualFold(ext, "None")
+}
@@ -0,0 +1,71 @@
+package emby
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/bytess"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamReqHeaders 代理回传时透传给 CDN 的请求头
+var streamReqHeaders = []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since", "User-Agent", "Accept"}
+
+// streamRespHeaders 代理回传时透传给客户端的响应头
+var streamRespHeaders = []string{
+	"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges",
+	"Last-Modified", "ETag", "Cache-Control", "Content-Disposition",
+}
+
+// proxyStream 由代理从 CDN 直链拉取资源并回传给客户端
+//
+// 使用客户端的请求方法, 透传 Range / If-Range 请求头以及 Content-Range 响应头, 支持客户端拖动进度;
+// 客户端断开连接时, 会随请求上下文一并中断对 CDN 的请求
+func proxyStream(c *gin.Context, target string) error {
+	// 流式响应不缓存, 也不暂存响应体
+	c.Header(cache.HeaderKeyExpired, "-1")
+
+	header := make(http.Header)
+	for _, key := range streamReqHeaders {
+		if value := c.GetHeader(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+
+	resp, err := https.Request(c.Request.Method, target).
+		Header(header).
+		Context(c.Request.Context()).
+		Stream().
+		Do()
+	if err != nil {
+		return fmt.Errorf("请求 CDN 资源失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	for _, key := range streamRespHeaders {
+		if value := resp.Header.Get(key); value != "" {
+			c.Header(key, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+	if c.Request.Method == http.MethodHead {
+		return nil
+	}
+
+	buf := bytess.CommonFixedBuffer()
+	defer buf.PutBack()
+	_, err = io.CopyBuffer(c.Writer, resp.Body, buf.Bytes())
+	if err == nil || errors.Is(err, context.Canceled) || c.Request.Context().Err() != nil {
+		// 客户端主动断开连接属于正常情况
+		return nil
+	}
+	logs.Warn("代理回传 CDN 资源中断: %v", err)
+	return nil
+}
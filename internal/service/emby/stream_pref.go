@@ -0,0 +1,420 @@
+package emby
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StreamChoice 客户端选择的媒体流特征, 按语言和标题匹配, 而非流索引
+type StreamChoice struct {
+	Language string `json:"language,omitempty"` // 流语言, 如 chi, jpn
+	Title    string `json:"title,omitempty"`    // 流标题
+	Disabled bool   `json:"disabled,omitempty"` // 是否关闭, 仅对字幕有效
+}
+
+// StreamPref 用户在某部剧集上的音轨字幕偏好
+type StreamPref struct {
+	Audio    *StreamChoice `json:"audio,omitempty"`
+	Subtitle *StreamChoice `json:"subtitle,omitempty"`
+}
+
+// streamPrefStore 音轨字幕偏好存储, 变更后异步持久化到数据目录
+type streamPrefStore struct {
+	mu sync.RWMutex
+	// prefs userId => seriesId => 偏好
+	prefs map[string]map[string]StreamPref
+	// revisions 用户偏好的变更版本, 参与 PlaybackInfo 缓存 key 的计算
+	revisions map[string]int
+	// saveChan 持久化信号
+	saveChan chan struct{}
+}
+
+const (
+	// itemSeriesIdTtl item 所属剧集 id 的缓存时长
+	itemSeriesIdTtl = time.Hour * 6
+	// maxItemSeriesIds 最多缓存多少个 item 的剧集 id, 超出后不再缓存新的 item, 直到过期清理
+	maxItemSeriesIds = 20000
+)
+
+// itemSeriesId 缓存的 item 所属剧集 id
+type itemSeriesId struct {
+	seriesId string    // 剧集 id, 为空表示 item 不属于剧集
+	expired  time.Time // 过期时间
+}
+
+var (
+	// itemSeriesIds 缓存 item 所属的剧集 id, key: itemId
+	itemSeriesIds = sync.Map{}
+	// itemSeriesIdCnt 当前缓存的剧集 id 数量
+	itemSeriesIdCnt atomic.Int64
+)
+
+func init() {
+	go loopCleanItemSeriesIds()
+}
+
+// loopCleanItemSeriesIds 定期清理过期的剧集 id
+func loopCleanItemSeriesIds() {
+	ticker := time.NewTicker(time.Minute * 10)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		itemSeriesIds.Range(func(key, value any) bool {
+			if now.After(value.(*itemSeriesId).expired) && itemSeriesIds.CompareAndDelete(key, value) {
+				itemSeriesIdCnt.Add(-1)
+			}
+			return true
+		})
+	}
+}
+
+// loadItemSeriesId 读取缓存的剧集 id
+func loadItemSeriesId(itemId string) (string, bool) {
+	value, ok := itemSeriesIds.Load(itemId)
+	if !ok {
+		return "", false
+	}
+	is := value.(*itemSeriesId)
+	if time.Now().After(is.expired) {
+		return "", false
+	}
+	return is.seriesId, true
+}
+
+// storeItemSeriesId 缓存剧集 id, 缓存数量达到上限时不作缓存
+func storeItemSeriesId(itemId, seriesId string) {
+	is := &itemSeriesId{seriesId: seriesId, expired: time.Now().Add(itemSeriesIdTtl)}
+	if _, loaded := itemSeriesIds.Swap(itemId, is); loaded {
+		return
+	}
+	if itemSeriesIdCnt.Add(1) > maxItemSeriesIds {
+		if itemSeriesIds.CompareAndDelete(itemId, is) {
+			itemSeriesIdCnt.Add(-1)
+		}
+	}
+}
+
+// streamPrefs 全局偏好存储, 首次使用时从文件中加载
+var streamPrefs = sync.OnceValue(func() *streamPrefStore {
+	s := &streamPrefStore{
+		prefs:     make(map[string]map[string]StreamPref),
+		revisions: make(map[string]int),
+		saveChan:  make(chan struct{}, 1),
+	}
+	fp := config.C().Emby.StreamPreference.FilePath()
+	if bytes, err := os.ReadFile(fp); err == nil {
+		if err := json.Unmarshal(bytes, &s.prefs); err != nil {
+			logs.Warn("音轨字幕偏好文件解析失败, 将重新记录: %v", err)
+			s.prefs = make(map[string]map[string]StreamPref)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logs.Warn("音轨字幕偏好文件读取失败: %v", err)
+	}
+	go s.loopSave(fp)
+	return s
+})
+
+// get 获取用户在剧集上的偏好
+func (s *streamPrefStore) get(userId, seriesId string) (StreamPref, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pref, ok := s.prefs[userId][seriesId]
+	return pref, ok
+}
+
+// hasUser 判断用户是否记录过偏好
+func (s *streamPrefStore) hasUser(userId string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.prefs[userId]) > 0
+}
+
+// revision 获取用户偏好的变更版本
+func (s *streamPrefStore) revision(userId string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revisions[userId]
+}
+
+// update 更新用户在剧集上的偏好, 为 nil 的选择保持原值
+func (s *streamPrefStore) update(userId, seriesId string, audio, subtitle *StreamChoice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.prefs[userId][seriesId]
+	pref := old
+	if audio != nil {
+		pref.Audio = audio
+	}
+	if subtitle != nil {
+		pref.Subtitle = subtitle
+	}
+	if choiceEqual(old.Audio, pref.Audio) && choiceEqual(old.Subtitle, pref.Subtitle) {
+		return
+	}
+
+	if s.prefs[userId] == nil {
+		s.prefs[userId] = make(map[string]StreamPref)
+	}
+	s.prefs[userId][seriesId] = pref
+	s.revisions[userId]++
+
+	select {
+	case s.saveChan <- struct{}{}:
+	default:
+	}
+}
+
+// loopSave 监听持久化信号, 合并短时间内的多次变更后写入文件
+func (s *streamPrefStore) loopSave(fp string) {
+	for range s.saveChan {
+		time.Sleep(time.Second * 2)
+
+		s.mu.RLock()
+		bytes, err := json.Marshal(s.prefs)
+		s.mu.RUnlock()
+		if err != nil {
+			logs.Error("音轨字幕偏好序列化失败: %v", err)
+			continue
+		}
+
+		tmp := fp + ".tmp"
+		if err := os.WriteFile(tmp, bytes, os.ModePerm); err != nil {
+			logs.Error("音轨字幕偏好写入失败: %v", err)
+			continue
+		}
+		if err := os.Rename(tmp, fp); err != nil {
+			logs.Error("音轨字幕偏好写入失败: %v", err)
+		}
+	}
+}
+
+// choiceEqual 判断两个媒体流选择是否一致
+func choiceEqual(a, b *StreamChoice) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// streamPrefTag 计算用户偏好对应的 PlaybackInfo 缓存 key 附加标识
+//
+// 偏好按 api_key 所属用户记录, 只有 PlaybackInfo 请求需要查询
+func streamPrefTag(c *gin.Context) string {
+	if !config.C().Emby.StreamPreference.Enable || routes.IdOf(c) != routes.PlaybackInfo {
+		return ""
+	}
+	userId := RequestUserId(c)
+	if strs.AnyEmpty(userId) {
+		return ""
+	}
+	rev := streamPrefs().revision(userId)
+	if rev == 0 {
+		return ""
+	}
+	return "p" + strconv.Itoa(rev)
+}
+
+// recordStreamPref 记录客户端在 PlaybackInfo 请求中选择的音轨和字幕
+//
+// 需要查询 item 所属剧集以及媒体流信息, 异步执行, 不影响本次请求;
+// 偏好记录在 api_key 所属用户下, 客户端传递的 UserId 参数不作为依据
+func recordStreamPref(c *gin.Context, itemInfo ItemInfo) {
+	if !config.C().Emby.StreamPreference.Enable {
+		return
+	}
+	audioIdx, audioErr := strconv.Atoi(c.Query("AudioStreamIndex"))
+	subIdx, subErr := strconv.Atoi(c.Query("SubtitleStreamIndex"))
+	if audioErr != nil && subErr != nil {
+		return
+	}
+	userId := RequestUserId(c)
+	if strs.AnyEmpty(userId) {
+		return
+	}
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		item, err := fetchUserItem(ctx, itemInfo, userId)
+		if err != nil {
+			logs.Warn("记录音轨字幕偏好失败: %v", err)
+			return
+		}
+		seriesId, _ := item.Attr("SeriesId").String()
+		if strs.AnyEmpty(seriesId) {
+			return
+		}
+		storeItemSeriesId(itemInfo.Id, seriesId)
+
+		sources, ok := item.Attr("MediaSources").Done()
+		if !ok || sources.Empty() {
+			return
+		}
+		idx := sources.FindIdx(func(val *jsons.Item) bool {
+			id, _ := val.Attr("Id").String()
+			return id == itemInfo.MsInfo.OriginId
+		})
+		if idx == -1 {
+			return
+		}
+		streams, ok := sources.Idx(idx).Attr("MediaStreams").Done()
+		if !ok {
+			return
+		}
+
+		var audio, subtitle *StreamChoice
+		if audioErr == nil {
+			audio = streamChoiceOf(streams, "Audio", audioIdx)
+		}
+		if subErr == nil {
+			if subIdx < 0 {
+				subtitle = &StreamChoice{Disabled: true}
+			} else {
+				subtitle = streamChoiceOf(streams, "Subtitle", subIdx)
+			}
+		}
+		if audio == nil && subtitle == nil {
+			return
+		}
+		streamPrefs().update(userId, seriesId, audio, subtitle)
+		logs.Info("已记录用户 [%s] 在剧集 [%s] 上的音轨字幕偏好", userId, seriesId)
+	}()
+}
+
+// applyStreamPref 将用户在剧集上的偏好应用到 MediaSources 的默认音轨和字幕
+//
+// 客户端在请求中明确指定了音轨或字幕时, 以客户端的选择为准, 不再应用对应的偏好
+func applyStreamPref(c *gin.Context, itemInfo ItemInfo, mediaSources *jsons.Item) {
+	_, explicitAudio := c.GetQuery("AudioStreamIndex")
+	_, explicitSub := c.GetQuery("SubtitleStreamIndex")
+	if !config.C().Emby.StreamPreference.Enable || (explicitAudio && explicitSub) {
+		return
+	}
+	userId := RequestUserId(c)
+	if strs.AnyEmpty(userId) || !streamPrefs().hasUser(userId) {
+		return
+	}
+
+	seriesId, err := lookupSeriesId(c.Request.Context(), itemInfo, userId)
+	if err != nil {
+		logs.Warn("查询剧集信息失败, 跳过音轨字幕偏好: %v", err)
+		return
+	}
+	pref, ok := streamPrefs().get(userId, seriesId)
+	if !ok {
+		return
+	}
+
+	mediaSources.RangeArr(func(_ int, source *jsons.Item) error {
+		streams, ok := source.Attr("MediaStreams").Done()
+		if !ok {
+			return nil
+		}
+		if !explicitAudio {
+			if idx, ok := matchStream(streams, "Audio", pref.Audio); ok {
+				source.Put("DefaultAudioStreamIndex", jsons.FromValue(idx))
+			}
+		}
+		if explicitSub {
+			return nil
+		}
+		if pref.Subtitle != nil && pref.Subtitle.Disabled {
+			source.Put("DefaultSubtitleStreamIndex", jsons.FromValue(-1))
+		} else if idx, ok := matchStream(streams, "Subtitle", pref.Subtitle); ok {
+			source.Put("DefaultSubtitleStreamIndex", jsons.FromValue(idx))
+		}
+		return nil
+	})
+}
+
+// lookupSeriesId 查询 item 所属的剧集 id, 非剧集返回空字符串
+func lookupSeriesId(ctx context.Context, itemInfo ItemInfo, userId string) (string, error) {
+	if seriesId, ok := loadItemSeriesId(itemInfo.Id); ok {
+		return seriesId, nil
+	}
+	item, err := fetchUserItem(ctx, itemInfo, userId)
+	if err != nil {
+		return "", err
+	}
+	seriesId, _ := item.Attr("SeriesId").String()
+	storeItemSeriesId(itemInfo.Id, seriesId)
+	return seriesId, nil
+}
+
+// fetchUserItem 以用户身份查询 item 详情
+func fetchUserItem(ctx context.Context, itemInfo ItemInfo, userId string) (*jsons.Item, error) {
+	uri := fmt.Sprintf("/Users/%s/Items/%s?Fields=MediaSources&%s=%s", userId, itemInfo.Id, QueryApiKeyName, itemInfo.ApiKey)
+	res, _ := Fetch(ctx, uri, http.MethodGet, nil, nil)
+	if res.Code != http.StatusOK {
+		return nil, fmt.Errorf("查询 item 详情失败: %s", res.Msg)
+	}
+	return res.Data, nil
+}
+
+// streamChoiceOf 根据流索引提取媒体流特征
+func streamChoiceOf(streams *jsons.Item, streamType string, index int) *StreamChoice {
+	idx := streams.FindIdx(func(val *jsons.Item) bool {
+		typ, _ := val.Attr("Type").String()
+		i, _ := val.Attr("Index").Int()
+		return typ == streamType && i == index
+	})
+	if idx == -1 {
+		return nil
+	}
+	stream, _ := streams.Idx(idx).Done()
+	lang, _ := stream.Attr("Language").String()
+	title, _ := stream.Attr("Title").String()
+	return &StreamChoice{Language: lang, Title: title}
+}
+
+// matchStream 在媒体流中匹配与偏好特征最接近的流, 返回流索引
+//
+// 偏好记录了语言时, 语言必须一致, 标题一致的流优先
+func matchStream(streams *jsons.Item, streamType string, choice *StreamChoice) (int, bool) {
+	if choice == nil || choice.Disabled || (choice.Language == "" && choice.Title == "") {
+		return 0, false
+	}
+
+	bestIdx, bestScore := 0, 0
+	streams.RangeArr(func(_ int, stream *jsons.Item) error {
+		if typ, _ := stream.Attr("Type").String(); typ != streamType {
+			return nil
+		}
+		lang, _ := stream.Attr("Language").String()
+		title, _ := stream.Attr("Title").String()
+
+		score := 0
+		if choice.Language != "" {
+			if !strings.EqualFold(lang, choice.Language) {
+				return nil
+			}
+			score += 2
+		}
+		if choice.Title != "" && strings.EqualFold(title, choice.Title) {
+			score++
+		}
+		if score > bestScore {
+			bestIdx, _ = stream.Attr("Index").Int()
+			bestScore = score
+		}
+		return nil
+	})
+	return bestIdx, bestScore > 0
+}
@@ -0,0 +1,137 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMatchStream 测试按语言和标题匹配媒体流
+func TestMatchStream(t *testing.T) {
+	streams, err := jsons.New(`[
+		{"Index":0,"Type":"Video"},
+		{"Index":1,"Type":"Audio","Language":"chi","Title":"国语"},
+		{"Index":2,"Type":"Audio","Language":"jpn","Title":"日语"},
+		{"Index":3,"Type":"Subtitle","Language":"chi","Title":"简体"},
+		{"Index":4,"Type":"Subtitle","Language":"chi","Title":"简日双语"}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		streamType string
+		choice     *StreamChoice
+		wantIdx    int
+		wantOk     bool
+	}{
+		{name: "按语言匹配音轨", streamType: "Audio", choice: &StreamChoice{Language: "jpn"}, wantIdx: 2, wantOk: true},
+		{name: "语言和标题优先", streamType: "Subtitle", choice: &StreamChoice{Language: "chi", Title: "简日双语"}, wantIdx: 4, wantOk: true},
+		{name: "标题不一致时按语言匹配", streamType: "Subtitle", choice: &StreamChoice{Language: "chi", Title: "繁体"}, wantIdx: 3, wantOk: true},
+		{name: "语言不存在", streamType: "Audio", choice: &StreamChoice{Language: "eng", Title: "国语"}, wantOk: false},
+		{name: "空偏好", streamType: "Audio", choice: nil, wantOk: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, ok := matchStream(streams, tt.streamType, tt.choice)
+			if ok != tt.wantOk || (ok && idx != tt.wantIdx) {
+				t.Errorf("matchStream() = (%d, %v), want (%d, %v)", idx, ok, tt.wantIdx, tt.wantOk)
+			}
+		})
+	}
+
+	if choice := streamChoiceOf(streams, "Audio", 2); choice == nil || choice.Language != "jpn" {
+		t.Errorf("streamChoiceOf() = %+v, want jpn", choice)
+	}
+}
+
+// TestStreamPrefStore_Update 测试偏好变更时才更新版本
+func TestStreamPrefStore_Update(t *testing.T) {
+	s := &streamPrefStore{
+		prefs:     make(map[string]map[string]StreamPref),
+		revisions: make(map[string]int),
+		saveChan:  make(chan struct{}, 1),
+	}
+
+	s.update("u1", "s1", &StreamChoice{Language: "jpn"}, nil)
+	s.update("u1", "s1", nil, &StreamChoice{Disabled: true})
+	s.update("u1", "s1", &StreamChoice{Language: "jpn"}, nil)
+
+	pref, ok := s.get("u1", "s1")
+	if !ok || pref.Audio.Language != "jpn" || !pref.Subtitle.Disabled {
+		t.Errorf("偏好不符合预期: %+v", pref)
+	}
+	if rev := s.revision("u1"); rev != 2 {
+		t.Errorf("revision() = %d, want 2", rev)
+	}
+	if s.hasUser("u2") {
+		t.Error("u2 不应存在偏好")
+	}
+}
+
+// TestApplyStreamPref 测试偏好按 api_key 所属用户应用, 且不覆盖客户端明确指定的音轨字幕
+func TestApplyStreamPref(t *testing.T) {
+	emby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owners := map[string]string{"token-a": "u1", "token-b": "u2"}
+		id, ok := owners[r.URL.Query().Get(QueryApiKeyName)]
+		if r.URL.Path != "/emby/Users/Me" || !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"Id":"` + id + `"}`))
+	}))
+	defer emby.Close()
+
+	old := config.C()
+	config.Set(&config.Config{Emby: &config.Emby{Host: emby.URL, StreamPreference: &config.StreamPreference{Enable: true}}})
+	t.Cleanup(func() { config.Set(old) })
+
+	store := streamPrefs()
+	store.mu.Lock()
+	store.prefs["u1"] = map[string]StreamPref{"s1": {
+		Audio:    &StreamChoice{Language: "jpn"},
+		Subtitle: &StreamChoice{Language: "chi", Title: "简日双语"},
+	}}
+	store.mu.Unlock()
+	storeItemSeriesId("e1", "s1")
+
+	tests := []struct {
+		name      string
+		query     string
+		wantAudio int
+		wantSub   int
+	}{
+		{name: "应用偏好", query: "api_key=token-a", wantAudio: 2, wantSub: 4},
+		{name: "客户端指定音轨", query: "api_key=token-a&AudioStreamIndex=1", wantAudio: 1, wantSub: 4},
+		{name: "客户端指定音轨和字幕", query: "api_key=token-a&AudioStreamIndex=1&SubtitleStreamIndex=3", wantAudio: 1, wantSub: 3},
+		{name: "伪造 UserId", query: "api_key=token-b&UserId=u1", wantAudio: 1, wantSub: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources, err := jsons.New(`[{"DefaultAudioStreamIndex":1,"DefaultSubtitleStreamIndex":3,"MediaStreams":[
+				{"Index":1,"Type":"Audio","Language":"chi"},
+				{"Index":2,"Type":"Audio","Language":"jpn"},
+				{"Index":3,"Type":"Subtitle","Language":"chi","Title":"简体"},
+				{"Index":4,"Type":"Subtitle","Language":"chi","Title":"简日双语"}
+			]}]`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/emby/Items/e1/PlaybackInfo?"+tt.query, nil)
+			applyStreamPref(c, ItemInfo{Id: "e1"}, sources)
+
+			audio, _ := sources.Idx(0).Attr("DefaultAudioStreamIndex").Int()
+			sub, _ := sources.Idx(0).Attr("DefaultSubtitleStreamIndex").Int()
+			if audio != tt.wantAudio || sub != tt.wantSub {
+				t.Errorf("默认音轨字幕 = (%d, %d), want (%d, %d)", audio, sub, tt.wantAudio, tt.wantSub)
+			}
+		})
+	}
+}
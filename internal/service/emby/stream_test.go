@@ -0,0 +1,57 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestProxyStream 测试代理回传时透传客户端的请求方法和 Range
+func TestProxyStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var methods []string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		http.ServeContent(w, r, "a.mkv", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer cdn.Close()
+
+	tests := []struct {
+		name        string
+		method      string
+		rangeHeader string
+		wantCode    int
+		wantBody    string
+	}{
+		{name: "GET", method: http.MethodGet, wantCode: http.StatusOK, wantBody: "0123456789"},
+		{name: "Range", method: http.MethodGet, rangeHeader: "bytes=2-4", wantCode: http.StatusPartialContent, wantBody: "234"},
+		{name: "HEAD", method: http.MethodHead, wantCode: http.StatusOK, wantBody: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods = nil
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(tt.method, "/emby/videos/1/stream", nil)
+			if tt.rangeHeader != "" {
+				c.Request.Header.Set("Range", tt.rangeHeader)
+			}
+			if err := proxyStream(c, cdn.URL+"/a.mkv"); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.wantCode || w.Body.String() != tt.wantBody {
+				t.Errorf("响应 = (%d, %q), want (%d, %q)", w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+			}
+			if len(methods) != 1 || methods[0] != tt.method {
+				t.Errorf("CDN 收到的请求方法 = %v, want %s", methods, tt.method)
+			}
+			if w.Header().Get("Content-Length") == "" {
+				t.Error("缺少 Content-Length 响应头")
+			}
+		})
+	}
+}
@@ -1,18 +1,238 @@
 package emby
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/subtitles"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
 	"github.com/gin-gonic/gin"
 )
 
+// subtitleStreamReg 匹配字幕文件流接口, 只有该接口的响应才需要处理
+//
+// 子匹配依次为: itemId, MediaSourceId, 字幕流索引
+var subtitleStreamReg = regexp.MustCompile(`(?i)/videos/([^/]+)/([^/]+)/subtitles/(\d+)/(?:\d+/)?stream\.\w+$`)
+
+const (
+	// subtitlePathTtl 外挂字幕本地路径的缓存时长, 需要覆盖 PlaybackInfo 的缓存时长
+	subtitlePathTtl = time.Hour * 24
+	// maxSubtitlePaths 最多缓存多少个外挂字幕路径, 超出后不再缓存新的路径, 直到过期清理
+	maxSubtitlePaths = 20000
+)
+
+// subtitlePath 缓存的外挂字幕本地路径
+type subtitlePath struct {
+	path    string    // 本地路径
+	expired time.Time // 过期时间
+}
+
+var (
+	// subtitlePaths 缓存外挂字幕的本地路径, key: itemId_MediaSourceId_字幕流索引
+	subtitlePaths = sync.Map{}
+	// subtitlePathCnt 当前缓存的外挂字幕路径数量
+	subtitlePathCnt atomic.Int64
+)
+
+func init() {
+	go loopCleanSubtitlePaths()
+}
+
+// loopCleanSubtitlePaths 定期清理过期的外挂字幕路径
+func loopCleanSubtitlePaths() {
+	ticker := time.NewTicker(time.Minute * 10)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		subtitlePaths.Range(func(key, value any) bool {
+			if now.After(value.(*subtitlePath).expired) && subtitlePaths.CompareAndDelete(key, value) {
+				subtitlePathCnt.Add(-1)
+			}
+			return true
+		})
+	}
+}
+
+// subtitlePathKey 计算外挂字幕路径的缓存 key
+func subtitlePathKey(itemId, msId string, index int) string {
+	return itemId + "_" + msId + "_" + strconv.Itoa(index)
+}
+
+// storeSubtitlePath 缓存外挂字幕路径, 缓存数量达到上限时不作缓存
+func storeSubtitlePath(key, localPath string) {
+	sp := &subtitlePath{path: localPath, expired: time.Now().Add(subtitlePathTtl)}
+	if _, loaded := subtitlePaths.Swap(key, sp); loaded {
+		return
+	}
+	if subtitlePathCnt.Add(1) > maxSubtitlePaths {
+		if subtitlePaths.CompareAndDelete(key, sp) {
+			subtitlePathCnt.Add(-1)
+		}
+	}
+}
+
+// maxSubtitleSize 允许处理的最大字幕大小, 超出则直接透传
+const maxSubtitleSize = 20 * 1024 * 1024
+
 // ProxySubtitles 字幕代理, 过期时间设置为 30 天
+//
+// 开启字幕处理后, 会将字幕编码转换为 UTF-8, 并按请求地址的后缀转换字幕格式
 func ProxySubtitles(c *gin.Context) {
 	if c == nil {
 		return
 	}
 
 	c.Header(cache.HeaderKeyExpired, cache.Duration(time.Hour*24*30))
-	ProxyOrigin(c)
+	conf := config.C().Emby.Subtitles
+	if !(conf.NeedProcess() || conf.CdnDelivery) ||
+		c.Request.Method != http.MethodGet ||
+		!subtitleStreamReg.MatchString(c.Request.URL.Path) {
+		ProxyOrigin(c)
+		return
+	}
+
+	if conf.CdnDelivery && redirectSubtitle2Cdn(c) {
+		return
+	}
+	if !conf.NeedProcess() {
+		ProxyOrigin(c)
+		return
+	}
+
+	c.Request.Header.Del("Accept-Encoding")
+	resp, err := https.Get(config.C().Emby.Host + c.Request.URL.String()).Context(c.Request.Context()).Header(c.Request.Header).Do()
+	if checkErr(c, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSubtitleSize+1))
+	if checkErr(c, err) {
+		return
+	}
+	if resp.StatusCode != http.StatusOK || len(data) > maxSubtitleSize {
+		// 304、404 等响应以及超出大小的字幕, 原样透传
+		if resp.StatusCode != http.StatusOK {
+			c.Header(cache.HeaderKeyExpired, "-1")
+		}
+		https.CloneHeader(c.Writer, resp.Header)
+		c.Status(resp.StatusCode)
+		c.Writer.Write(data)
+		io.Copy(c.Writer, resp.Body)
+		return
+	}
+
+	body, format, err := processSubtitle(data, subtitles.FormatOfName(c.Request.URL.Path))
+	if checkErr(c, err) {
+		return
+	}
+
+	https.CloneHeader(c.Writer, resp.Header)
+	c.Writer.Header().Del("Content-Encoding")
+	if contentType, ok := subtitles.ContentTypes[format]; ok {
+		c.Header("Content-Type", contentType)
+	}
+	c.Header("Content-Length", strconv.Itoa(len(body)))
+	c.Status(http.StatusOK)
+	c.Writer.Write(body)
+}
+
+// processSubtitle 按配置处理字幕内容, 返回处理后的内容以及最终的字幕格式
+func processSubtitle(data []byte, target subtitles.Format) ([]byte, subtitles.Format, error) {
+	conf := config.C().Emby.Subtitles
+	if conf.NormalizeEncoding {
+		var enc string
+		data, enc = subtitles.ToUTF8(data)
+		if enc != "utf-8" {
+			logs.Info("字幕编码转换: %s => utf-8", enc)
+		}
+	}
+
+	from := subtitles.DetectFormat(string(data))
+	if !conf.ConvertFormat || from == subtitles.FormatUnknown || target == subtitles.FormatUnknown || from == target {
+		return data, from, nil
+	}
+	if target == subtitles.FormatAss {
+		// 不支持转换为 ass, 保持原格式
+		return data, from, nil
+	}
+
+	converted, err := subtitles.Convert(string(data), from, target)
+	if err != nil {
+		return nil, from, errors.New("字幕格式转换失败: " + err.Error())
+	}
+	logs.Info("字幕格式转换: %s => %s", from, target)
+	return []byte(converted), target, nil
+}
+
+// redirectSubtitle2Cdn 将外挂字幕的本地路径映射为 CDN 直链, 并重定向客户端
+//
+// 字幕不是外挂字幕、格式与请求不一致或无法映射时, 返回 false
+func redirectSubtitle2Cdn(c *gin.Context) bool {
+	localPath, ok := lookupSubtitlePath(c)
+	if !ok {
+		return false
+	}
+	want := subtitles.FormatOfName(c.Request.URL.Path)
+	if want != subtitles.FormatUnknown && subtitles.FormatOfName(localPath) != want {
+		return false
+	}
+
+	mapRes, err := config.C().Emby.Strm.Resolve(localPath, c.ClientIP())
+	if err != nil {
+		logs.Warn("外挂字幕映射失败, 回源处理: %v", err)
+		return false
+	}
+	cdnUrl := mapRes.Url
+
+	// 绑定了客户端 ip 或使用一次性链接时, 重定向结果不能被其他请求复用
+	c.Header(cache.HeaderKeyExpired, cache.Duration(time.Minute*10))
+	if mapRes.Cdn.BindClientIp || mapRes.Cdn.OneTimeToken {
+		c.Header(cache.HeaderKeyExpired, "-1")
+	}
+	if mapRes.Cdn.OneTimeToken {
+		// 一次性链接由代理回传资源, 签名不能绑定客户端 ip
+		tokenRes, err := config.C().Emby.Strm.Resolve(localPath, "")
+		if err == nil {
+			cdnUrl, err = mintLinkToken(tokenRes, c.ClientIP())
+		}
+		if err != nil {
+			logs.Warn("外挂字幕签发一次性链接失败, 回源处理: %v", err)
+			return false
+		}
+	}
+
+	logs.Success("外挂字幕重定向到 [%s]: %s", mapRes.Cdn.Name, cdnUrl)
+	recordRedirect(c, mapRes, redirectKindSubtitle)
+	c.Redirect(http.StatusFound, cdnUrl)
+	return true
+}
+
+// lookupSubtitlePath 查询请求的外挂字幕在 emby 中的本地路径
+//
+// 路径在处理 PlaybackInfo 时记录, 没有记录或已过期时返回 false
+func lookupSubtitlePath(c *gin.Context) (string, bool) {
+	matches := subtitleStreamReg.FindStringSubmatch(c.Request.URL.Path)
+	if len(matches) < 4 {
+		return "", false
+	}
+	index, _ := strconv.Atoi(matches[3])
+	value, ok := subtitlePaths.Load(subtitlePathKey(matches[1], matches[2], index))
+	if !ok {
+		return "", false
+	}
+	sp := value.(*subtitlePath)
+	if time.Now().After(sp.expired) {
+		return "", false
+	}
+	return sp.path, true
 }
@@ -0,0 +1,91 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestProxySubtitlesPassThrough 测试源服务器的非 200 响应原样透传
+func TestProxySubtitlesPassThrough(t *testing.T) {
+	emby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("case") {
+		case "304":
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusNotModified)
+		case "404":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		default:
+			w.Write([]byte("1\n00:00:01,000 --> 00:00:02,000\nhello\n"))
+		}
+	}))
+	defer emby.Close()
+
+	old := config.C()
+	config.Set(&config.Config{Emby: &config.Emby{Host: emby.URL, Subtitles: &config.Subtitles{NormalizeEncoding: true}}})
+	t.Cleanup(func() { config.Set(old) })
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+		wantBody string
+	}{
+		{name: "正常字幕", query: "case=200", wantCode: http.StatusOK, wantBody: "1\n00:00:01,000 --> 00:00:02,000\nhello\n"},
+		{name: "未修改", query: "case=304", wantCode: http.StatusNotModified},
+		{name: "不存在", query: "case=404", wantCode: http.StatusNotFound, wantBody: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/Videos/1/ms1/Subtitles/3/Stream.srt?"+tt.query, nil)
+			ProxySubtitles(c)
+			if w.Code != tt.wantCode || w.Body.String() != tt.wantBody {
+				t.Errorf("ProxySubtitles() = (%d, %q), want (%d, %q)", w.Code, w.Body.String(), tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+// TestLookupSubtitlePath 测试外挂字幕路径从 PlaybackInfo 响应中记录, 只按请求的 MediaSourceId 查询
+func TestLookupSubtitlePath(t *testing.T) {
+	old := config.C()
+	config.Set(&config.Config{Emby: &config.Emby{Subtitles: &config.Subtitles{CdnDelivery: true}}})
+	t.Cleanup(func() { config.Set(old) })
+
+	source, err := jsons.New(`{"Id":"ms1","ItemId":"10","MediaStreams":[
+		{"Index":2,"Type":"Subtitle","IsExternal":true,"Path":"/mnt/media/a.chs.srt"},
+		{"Index":3,"Type":"Subtitle","IsExternal":false}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	detectSubtitleStreamsDeliveryUrl(source, "key")
+
+	tests := []struct {
+		name     string
+		uri      string
+		wantPath string
+		wantOk   bool
+	}{
+		{name: "外挂字幕", uri: "/Videos/10/ms1/Subtitles/2/Stream.srt", wantPath: "/mnt/media/a.chs.srt", wantOk: true},
+		{name: "内嵌字幕", uri: "/Videos/10/ms1/Subtitles/3/Stream.srt"},
+		{name: "未知的 MediaSourceId", uri: "/Videos/10/ms2/Subtitles/2/Stream.srt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, tt.uri, nil)
+			if p, ok := lookupSubtitlePath(c); p != tt.wantPath || ok != tt.wantOk {
+				t.Errorf("lookupSubtitlePath() = (%q, %v), want (%q, %v)", p, ok, tt.wantPath, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package emby
+
+import (
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+
+	"github.com/gin-gonic/gin"
+)
+
+// allowTranscoding 判断当前请求是否保留指定媒体的转码能力
+//
+// 客户端规则允许转码, 或者满足选择性转码策略的任意放行条件时, 保留转码;
+// 允许转码的用户按 api_key 所属用户判断, 客户端传递的 UserId 参数不作为依据
+func allowTranscoding(c *gin.Context, rule *config.ClientRule, source *jsons.Item) bool {
+	if !rule.ShouldDisableTranscoding() {
+		return true
+	}
+
+	policy := config.C().Emby.Transcode
+	info := resolveClientInfo(c)
+	var userId string
+	if len(policy.AllowUsers) > 0 {
+		userId = RequestUserId(c)
+	}
+	remote := !config.C().Network.IsTrusted(c.ClientIP())
+	if policy.AllowRequest(userId, info.Client, info.UserAgent, remote) {
+		return true
+	}
+
+	bitrate, _ := source.Attr("Bitrate").Int()
+	return policy.ExceedsBitrate(bitrate)
+}
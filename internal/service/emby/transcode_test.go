@@ -0,0 +1,60 @@
+package emby
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAllowTranscodingForgedUserId 测试允许转码的用户按 api_key 所属用户判断, 伪造 UserId 参数无效
+func TestAllowTranscodingForgedUserId(t *testing.T) {
+	emby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owners := map[string]string{"token-vip": "vip", "token-guest": "guest"}
+		id, ok := owners[r.URL.Query().Get(QueryApiKeyName)]
+		if r.URL.Path != "/emby/Users/Me" || !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(UnauthorizedResp))
+			return
+		}
+		w.Write([]byte(`{"Id":"` + id + `"}`))
+	}))
+	defer emby.Close()
+
+	old := config.C()
+	policy := &config.Transcode{Enable: true, AllowUsers: []string{"vip"}}
+	if err := policy.Init(); err != nil {
+		t.Fatal(err)
+	}
+	network := new(config.Network)
+	if err := network.Init(); err != nil {
+		t.Fatal(err)
+	}
+	config.Set(&config.Config{Emby: &config.Emby{Host: emby.URL, Transcode: policy}, Network: network})
+	t.Cleanup(func() { config.Set(old) })
+
+	source, _ := jsons.New(`{"Bitrate":1000000}`)
+	rule := new(config.ClientRule)
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{name: "允许转码的用户", query: "api_key=token-vip", want: true},
+		{name: "伪造 UserId", query: "api_key=token-guest&UserId=vip", want: false},
+		{name: "无效 api_key", query: "api_key=forged&UserId=vip", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/emby/Items/1/PlaybackInfo?"+tt.query, nil)
+			if got := allowTranscoding(c, rule, source); got != tt.want {
+				t.Errorf("allowTranscoding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package emby
 import (
 	"encoding/json"
 	"fmt"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
 // MsInfo MediaSourceId 解析信息
@@ -43,13 +45,14 @@ type ItemInfo struct {
 	ApiKeyType      ApiKeyType // emby 接口密钥类型
 	ApiKeyName      string     // emby 接口密钥名称
 	PlaybackInfoUri string     // item 信息查询接口 uri, 通过源服务器查询
+	CacheTag        string     // PlaybackInfo 缓存空间 key 的附加标识
 	RouteType
 }
 
 // String 序列化输出
 func (ii ItemInfo) String() string {
 	return fmt.Sprintf("ItemInfo{Id: [%s], MsInfo: [%v], ApiKey: [%s], ApiKeyType: [%s], ApiKeyName: [%s], PlaybackInfoUri: [%s], RouteType: [%s]}",
-		ii.Id, ii.MsInfo, ii.ApiKey, ii.ApiKeyType, ii.ApiKeyName, ii.PlaybackInfoUri, ii.RouteType)
+		ii.Id, ii.MsInfo, logs.Mask(ii.ApiKey), ii.ApiKeyType, ii.ApiKeyName, logs.Redact(ii.PlaybackInfoUri), ii.RouteType)
 }
 
 // ItemsHolder Emby Items 接口响应接收结构
@@ -1,6 +1,7 @@
 package openlist
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,7 +18,7 @@ import (
 )
 
 // FetchResource 请求 openlist 资源 url 直链
-func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
+func FetchResource(ctx context.Context, fi FetchInfo) model.HttpRes[Resource] {
 	if strs.AnyEmpty(fi.Path) {
 		return model.HttpRes[Resource]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -25,7 +26,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 
 	if !fi.UseTranscode {
 		// 请求原画资源
-		res := FetchFsGet(fi.Path, fi.Header)
+		res := FetchFsGet(ctx, fi.Path, fi.Header)
 		if res.Code == http.StatusOK {
 			return model.HttpRes[Resource]{Code: http.StatusOK, Data: Resource{Url: res.Data.RawUrl}}
 		}
@@ -39,11 +40,11 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 		}
 		logs.Error("请求转码资源失败, 尝试请求原画资源, 原始响应: %v", jsons.FromObject(originRes))
 		fi.UseTranscode = false
-		return FetchResource(fi)
+		return FetchResource(ctx, fi)
 	}
 
 	// 请求转码资源
-	res := FetchFsOther(fi.Path, fi.Header)
+	res := FetchFsOther(ctx, fi.Path, fi.Header)
 	if res.Code != http.StatusOK {
 		return failedAndTryRaw(res)
 	}
@@ -84,7 +85,7 @@ func FetchResource(fi FetchInfo) model.HttpRes[Resource] {
 // FetchFsList 请求 openlist "/api/fs/list" 接口
 //
 // 传入 path 与接口的 path 作用一致
-func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
+func FetchFsList(ctx context.Context, path string, header http.Header) model.HttpRes[FsList] {
 	if strs.AnyEmpty(path) {
 		return model.HttpRes[FsList]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -93,7 +94,7 @@ func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
 	defer removeMainApiRunner()
 
 	var res FsList
-	err := Fetch("/api/fs/list", http.MethodPost, header, map[string]any{
+	err := Fetch(ctx, "/api/fs/list", http.MethodPost, header, map[string]any{
 		"refresh":  false,
 		"password": "",
 		"path":     path,
@@ -107,7 +108,7 @@ func FetchFsList(path string, header http.Header) model.HttpRes[FsList] {
 // FetchFsGet 请求 openlist "/api/fs/get" 接口
 //
 // 传入 path 与接口的 path 作用一致
-func FetchFsGet(path string, header http.Header) model.HttpRes[FsGet] {
+func FetchFsGet(ctx context.Context, path string, header http.Header) model.HttpRes[FsGet] {
 	if strs.AnyEmpty(path) {
 		return model.HttpRes[FsGet]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -116,7 +117,7 @@ func FetchFsGet(path string, header http.Header) model.HttpRes[FsGet] {
 	defer removeMainApiRunner()
 
 	var res FsGet
-	err := Fetch("/api/fs/get", http.MethodPost, header, map[string]any{
+	err := Fetch(ctx, "/api/fs/get", http.MethodPost, header, map[string]any{
 		"refresh":  false,
 		"password": "",
 		"path":     path,
@@ -130,7 +131,7 @@ func FetchFsGet(path string, header http.Header) model.HttpRes[FsGet] {
 // FetchFsOther 请求 openlist "/api/fs/other" 接口
 //
 // 传入 path 与接口的 path 作用一致
-func FetchFsOther(path string, header http.Header) model.HttpRes[FsOther] {
+func FetchFsOther(ctx context.Context, path string, header http.Header) model.HttpRes[FsOther] {
 	if strs.AnyEmpty(path) {
 		return model.HttpRes[FsOther]{Code: http.StatusBadRequest, Msg: "参数 path 不能为空"}
 	}
@@ -139,7 +140,7 @@ func FetchFsOther(path string, header http.Header) model.HttpRes[FsOther] {
 	defer removeMainApiRunner()
 
 	var res FsOther
-	err := Fetch("/api/fs/other", http.MethodPost, header, map[string]any{
+	err := Fetch(ctx, "/api/fs/other", http.MethodPost, header, map[string]any{
 		"method":   "video_preview",
 		"password": "",
 		"path":     path,
@@ -151,9 +152,11 @@ func FetchFsOther(path string, header http.Header) model.HttpRes[FsOther] {
 }
 
 // Fetch 请求 openlist api, 响应封装在 v 指针指向的结构中
-func Fetch(uri, method string, header http.Header, body map[string]any, v any, closeConn bool) error {
-	host := config.C.Openlist.Host
-	token := config.C.Openlist.Token
+//
+// ctx 取消时请求会被中断
+func Fetch(ctx context.Context, uri, method string, header http.Header, body map[string]any, v any, closeConn bool) error {
+	host := config.C().Openlist.Host
+	token := config.C().Openlist.Token
 	if strs.AnyEmpty(host, token) {
 		return fmt.Errorf("openlist.host 或 openlist.token 配置为空")
 	}
@@ -167,7 +170,7 @@ func Fetch(uri, method string, header http.Header, body map[string]any, v any, c
 	header.Set("Content-Type", "application/json;charset=utf-8")
 	header.Set("Authorization", token)
 
-	holder := https.Request(method, host+uri).Header(header).Body(https.MapBody(body))
+	holder := https.Request(method, host+uri).Context(ctx).Header(header).Body(https.MapBody(body))
 	if closeConn {
 		holder.CloseConn()
 	}
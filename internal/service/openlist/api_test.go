@@ -1,6 +1,7 @@
 package openlist_test
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -26,7 +27,7 @@ func TestFetch(t *testing.T) {
 	}
 
 	var res openlist.FsList
-	err = openlist.Fetch("/api/fs/list", http.MethodPost, nil, map[string]any{
+	err = openlist.Fetch(context.Background(), "/api/fs/list", http.MethodPost, nil, map[string]any{
 		"refresh":  true,
 		"password": "",
 		"path":     "/",
@@ -2,11 +2,11 @@ package localtree
 
 import (
 	"fmt"
-	"log"
 	"path/filepath"
 	"time"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 )
 
@@ -16,41 +16,77 @@ const DirName = "openlist-local-tree"
 // Init 根据配置文件, 初始化本地目录树
 func Init() error {
 	// 判断配置是否开启
-	if !config.C.Openlist.LocalTreeGen.Enable {
+	if !config.C().Openlist.LocalTreeGen.Enable {
 		return nil
 	}
 
 	dirAbs := filepath.Join(config.BasePath, DirName)
 
 	s := NewSynchronizer(dirAbs, 30)
+	statusMu.Lock()
+	current, status.Enable = s, true
+	statusMu.Unlock()
 	go startSync(s)
 
 	return nil
 }
 
-// startSync 立即同步一次目录树, 并开始定时扫描同步变更
+// startSync 立即同步一次目录树, 并开始定时扫描同步变更, 也可通过 TriggerSync 手动触发
 func startSync(s *Synchronizer) {
 	doSync := func() {
 		logf(colors.Blue, "开始同步")
 		start := time.Now()
+		syncStarted(start)
 		total, added, deleted, err := s.Sync()
+		syncFinished(total, added, deleted, err)
+		syncDuration.ObserveSince(start)
 		if err != nil {
+			syncTotal.Inc("failure")
 			logf(colors.Red, "同步失败: %v", err)
 			return
 		}
+		syncTotal.Inc("success")
+		syncFiles.Set(float64(total))
+		syncChanges.Add(float64(added), "added")
+		syncChanges.Add(float64(deleted), "deleted")
 		logf(colors.Green, "同步完成, 总数: %d, 新增: %d, 删除: %d, 耗时: %v", total, added, deleted, time.Since(start))
 	}
 	doSync()
 
-	d := time.Minute * time.Duration(config.C.Openlist.LocalTreeGen.RefreshInterval)
-	timer := time.NewTicker(d)
-	for range timer.C {
+	interval := func() time.Duration {
+		return time.Minute * time.Duration(config.C().Openlist.LocalTreeGen.RefreshInterval)
+	}
+	timer := time.NewTicker(interval())
+	for {
+		select {
+		case <-timer.C:
+		case <-triggerChan:
+			logf(colors.Blue, "收到手动同步请求")
+		case <-intervalChan:
+			d := interval()
+			timer.Reset(d)
+			logf(colors.Blue, "刷新间隔调整为 %v", d)
+			continue
+		}
 		doSync()
 	}
 }
 
-// logf 带上前缀的日志输出
+// logf 带上前缀的日志输出, 日志级别由颜色决定
 func logf(c colors.C, format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	log.Println(colors.WrapColor(c, "[openlist 目录树]: "+s))
+	s := "[openlist 目录树]: " + fmt.Sprintf(format, v...)
+	switch c {
+	case colors.Red:
+		logs.Error("%s", s)
+	case colors.Yellow:
+		logs.Warn("%s", s)
+	case colors.Green:
+		logs.Success("%s", s)
+	case colors.Purple:
+		logs.Progress("%s", s)
+	case colors.Gray:
+		logs.Tip("%s", s)
+	default:
+		logs.Info("%s", s)
+	}
 }
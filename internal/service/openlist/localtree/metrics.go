@@ -0,0 +1,15 @@
+package localtree
+
+import "github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+
+var (
+	// syncTotal 同步次数
+	syncTotal = metrics.NewCounter("ge2o_localtree_syncs_total", "本地目录树同步次数", "result")
+	// syncDuration 同步耗时
+	syncDuration = metrics.NewHistogram("ge2o_localtree_sync_duration_seconds", "本地目录树同步耗时",
+		[]float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600})
+	// syncFiles 最近一次同步后的文件总数
+	syncFiles = metrics.NewGauge("ge2o_localtree_files", "最近一次同步后本地目录树的文件总数")
+	// syncChanges 同步过程中新增和删除的文件数
+	syncChanges = metrics.NewCounter("ge2o_localtree_sync_changes_total", "本地目录树同步过程中新增和删除的文件数", "change")
+)
@@ -0,0 +1,105 @@
+package localtree
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+// ErrDisabled 未启用本地目录树
+var ErrDisabled = errors.New("未启用本地目录树")
+
+// Status 本地目录树的同步状态
+type Status struct {
+	Enable  bool `json:"enable"`
+	Running bool `json:"running"`
+	// Finish, Total 当前同步已处理完成的任务数和已发现的总任务数
+	Finish int64 `json:"finish"`
+	Total  int64 `json:"total"`
+
+	LastStart    time.Time     `json:"last_start,omitzero"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	// LastFiles, LastAdded, LastDeleted 上一次成功同步的文件总数和变更数
+	LastFiles   int `json:"last_files"`
+	LastAdded   int `json:"last_added"`
+	LastDeleted int `json:"last_deleted"`
+}
+
+var (
+	// statusMu 保护 status
+	statusMu sync.Mutex
+	// status 当前的同步状态
+	status Status
+	// current 当前使用的同步器
+	current *Synchronizer
+	// triggerChan 手动触发同步的信号通道
+	triggerChan = make(chan struct{}, 1)
+	// intervalChan 刷新间隔变化的信号通道
+	intervalChan = make(chan struct{}, 1)
+)
+
+func init() {
+	config.OnReload(func(old, c *config.Config) {
+		if old.Openlist.LocalTreeGen.RefreshInterval == c.Openlist.LocalTreeGen.RefreshInterval {
+			return
+		}
+		select {
+		case intervalChan <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// CurrentStatus 获取当前的同步状态
+func CurrentStatus() Status {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	res := status
+	if res.Running && current != nil {
+		res.Finish, res.Total = current.Progress()
+	}
+	return res
+}
+
+// TriggerSync 手动触发一次同步, 同步进行中时会在本次同步完成后再执行一次
+func TriggerSync() error {
+	statusMu.Lock()
+	enable := status.Enable
+	statusMu.Unlock()
+	if !enable {
+		return ErrDisabled
+	}
+	select {
+	case triggerChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// syncStarted 标记同步开始
+func syncStarted(start time.Time) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	status.Running, status.LastStart = true, start
+	status.Finish, status.Total = 0, 0
+}
+
+// syncFinished 标记同步结束, 记录同步结果
+func syncFinished(total, added, deleted int, err error) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	status.Running = false
+	status.LastDuration = time.Since(status.LastStart)
+	if current != nil {
+		status.Finish, status.Total = current.Progress()
+	}
+	if err != nil {
+		status.LastError = err.Error()
+		return
+	}
+	status.LastError = ""
+	status.LastFiles, status.LastAdded, status.LastDeleted = total, added, deleted
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/files"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/trys"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -62,6 +64,16 @@ func NewSynchronizer(baseDir string, pageSize int) *Synchronizer {
 
 // Sync 触发一次同步操作
 func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
+	ctx, span := traces.Start(context.Background(), "localtree.Sync")
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("localtree.total", total),
+			attribute.Int("localtree.added", added),
+			attribute.Int("localtree.deleted", deleted),
+		)
+		traces.End(span, err)
+	}()
+
 	if err := s.InitSnapshot(); err != nil {
 		return 0, 0, 0, fmt.Errorf("初始化快照异常: %w", err)
 	}
@@ -69,9 +81,10 @@ func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
 	// 初始化状态
 	s.toSyncTasks = make(chan []FileTask, 1024)
 	okTaskChan := make(chan FileTask, 1024)
-	s.eg, s.ctx = errgroup.WithContext(context.Background())
-	s.threadsSem = make(chan struct{}, config.C.Openlist.LocalTreeGen.Threads)
-	s.hasScanFinish, s.hasScanTotal = 0, 0
+	s.eg, s.ctx = errgroup.WithContext(ctx)
+	s.threadsSem = make(chan struct{}, config.C().Openlist.LocalTreeGen.Threads)
+	atomic.StoreInt64(&s.hasScanFinish, 0)
+	atomic.StoreInt64(&s.hasScanTotal, 0)
 
 	// 读取根目录放置到任务通道中
 	s.activeTaskCount = 0
@@ -85,10 +98,11 @@ func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
 	go func() {
 		for range ticker.C {
 			var percent float64
-			if s.hasScanTotal > 0 {
-				percent = float64(s.hasScanFinish) / float64(s.hasScanTotal)
+			finish, total := s.Progress()
+			if total > 0 {
+				percent = float64(finish) / float64(total)
 			}
-			logf(colors.Purple, "预估同步进度 (已扫描/已发现任务数) => %d/%d (%.2f%%)", s.hasScanFinish, s.hasScanTotal, percent*100)
+			logf(colors.Purple, "预估同步进度 (已扫描/已发现任务数) => %d/%d (%.2f%%)", finish, total, percent*100)
 		}
 	}()
 
@@ -108,6 +122,11 @@ func (s *Synchronizer) Sync() (total, added, deleted int, err error) {
 	return
 }
 
+// Progress 当前同步的进度, 返回已处理完成的任务数和已发现的总任务数
+func (s *Synchronizer) Progress() (finish, total int64) {
+	return atomic.LoadInt64(&s.hasScanFinish), atomic.LoadInt64(&s.hasScanTotal)
+}
+
 // InitSnapshot 扫描本地磁盘 初始化快照
 func (s *Synchronizer) InitSnapshot() error {
 	ss := NewSnapshot()
@@ -163,7 +182,7 @@ func (s *Synchronizer) InitSnapshot() error {
 
 // walkDir2SyncTasks 分页遍历 openlist 指定前缀目录下的文件, 加入到任务通道中
 func (s *Synchronizer) walkDir2SyncTasks(prefix string) error {
-	walker := openlist.WalkFsList(prefix, s.pageSize)
+	walker := openlist.WalkFsList(s.ctx, prefix, s.pageSize)
 	var page openlist.FsList
 	var err error
 
@@ -271,7 +290,7 @@ func (s *Synchronizer) handleSyncTasks(okTaskChan chan<- FileTask) {
 				defer atomic.AddInt64(&s.hasScanFinish, 1)
 
 				// 根据用户配置忽略特定文件和目录
-				cfg := config.C.Openlist.LocalTreeGen
+				cfg := config.C().Openlist.LocalTreeGen
 				if !cfg.IsValidPrefix(task.Path) {
 					continue
 				}
@@ -369,7 +388,7 @@ func (s *Synchronizer) updateLocalTree(okTaskChan <-chan FileTask, total, added,
 		toDelete = append(toDelete, filepath.Join(s.baseDir, path))
 	}
 
-	maxCount := config.C.Openlist.LocalTreeGen.AutoRemoveMaxCount
+	maxCount := config.C().Openlist.LocalTreeGen.AutoRemoveMaxCount
 	if len(toDelete) > maxCount {
 		logf(colors.Yellow, "过期文件数量 [%d] 超出最大限制 [%d], 跳过删除操作", len(toDelete), maxCount)
 		return
@@ -79,7 +79,7 @@ var (
 
 // LoadTaskWriter 根据文件容器加载 TaskWriter
 func LoadTaskWriter(container string) TaskWriter {
-	cfg := config.C.Openlist.LocalTreeGen
+	cfg := config.C().Openlist.LocalTreeGen
 	if cfg.IsVirtual(container) {
 		return &vw
 	}
@@ -105,7 +105,7 @@ func (vw *VirtualWriter) Path(path string) string {
 func (vw *VirtualWriter) Write(task FileTask, localPath string) error {
 	// 默认写入时长 3 小时
 	dftDuration := time.Hour * 3
-	if !config.C.Openlist.LocalTreeGen.FFmpegEnable {
+	if !config.C().Openlist.LocalTreeGen.FFmpegEnable {
 		return os.WriteFile(localPath, mp4s.GenWithDuration(dftDuration), os.ModePerm)
 	}
 
@@ -142,7 +142,7 @@ func (sw *StrmWriter) OpenlistPath(task FileTask) string {
 
 	return fmt.Sprintf(
 		"%s/d/%s?sign=%s",
-		config.C.Openlist.Host,
+		config.C().Openlist.Host,
 		strings.Join(segs, "/"),
 		task.Sign,
 	)
@@ -181,7 +181,7 @@ func (mw *MusicWriter) Path(path string) string {
 
 // Write 将文件信息写入到本地文件系统中
 func (mw *MusicWriter) Write(task FileTask, localPath string) error {
-	if !config.C.Openlist.LocalTreeGen.FFmpegEnable {
+	if !config.C().Openlist.LocalTreeGen.FFmpegEnable {
 		// 必须开启 ffmpeg 才能生成, 改用 strm 替代
 		return sw.Write(task, localPath)
 	}
@@ -281,7 +281,7 @@ func getRealDownloadUrl(task FileTask) string {
 		AddHeader("User-Agent", constant.CommonDlUserAgent).
 		DoRedirect()
 	if err != nil {
-		logs.Warn("获取真实下载链接失败: %w", err)
+		logs.Warn("获取真实下载链接失败: %v", err)
 		return openlistUrl
 	}
 	defer resp.Body.Close()
@@ -1,6 +1,7 @@
 package openlist_test
 
 import (
+	"context"
 	"log"
 	"os"
 	"testing"
@@ -24,7 +25,7 @@ func TestWalkFsList(t *testing.T) {
 		return
 	}
 
-	walker := openlist.WalkFsList("/", 4)
+	walker := openlist.WalkFsList(context.Background(), "/", 4)
 	page, err := walker.Next()
 	for err == nil {
 		log.Println("page: ", page)
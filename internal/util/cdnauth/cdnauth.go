@@ -26,6 +26,14 @@ import (
 //  3. sign：ts + "-" + rand + "-" + md5_str
 //  4. URL：url_encode(path) + "?sign=" + sign
 func GenerateGoEdgeSign(path, privateKey string, randLength int) string {
+	return GenerateGoEdgeSignWithIp(path, privateKey, randLength, "", "")
+}
+
+// GenerateGoEdgeSignWithIp 生成绑定客户端 IP 的 GoEdge CDN 鉴权签名
+//
+// ip 不为空时, 原串变为 path + "@" + ts + "@" + rand + "@" + ip + "@" + privateKey,
+// 同时将 ip 以 ipArg 参数名附加到 URL 中, 供 CDN 侧的自定义鉴权规则校验
+func GenerateGoEdgeSignWithIp(path, privateKey string, randLength int, ip, ipArg string) string {
 	// 1. 生成时间戳
 	ts := fmt.Sprintf("%d", time.Now().Unix())
 
@@ -39,6 +47,9 @@ func GenerateGoEdgeSign(path, privateKey string, randLength int) string {
 
 	// 3. 构造原始字符串（注意：这里使用的是原始未编码的 path）
 	raw := path + "@" + ts + "@" + randStr + "@" + privateKey
+	if ip != "" {
+		raw = path + "@" + ts + "@" + randStr + "@" + ip + "@" + privateKey
+	}
 
 	// 4. 计算 MD5（16进制小写）
 	hash := md5.Sum([]byte(raw))
@@ -49,7 +60,7 @@ func GenerateGoEdgeSign(path, privateKey string, randLength int) string {
 
 	// 6. 对路径的每个部分进行编码，但保留路径分隔符 /
 	encodedPath := encodePathSegments(path)
-	return encodedPath + "?sign=" + sign
+	return encodedPath + "?sign=" + sign + ipQuery(ip, ipArg)
 }
 
 // GenerateTencentSign 生成腾讯云 CDN Type-A 鉴权签名
@@ -70,6 +81,14 @@ func GenerateGoEdgeSign(path, privateKey string, randLength int) string {
 //  4. sign：ts + "-" + rand + "-" + uid + "-" + md5_str
 //  5. URL：uri + "?sign=" + sign
 func GenerateTencentSign(path, privateKey, uid string, randLength int) string {
+	return GenerateTencentSignWithIp(path, privateKey, uid, randLength, "", "")
+}
+
+// GenerateTencentSignWithIp 生成绑定客户端 IP 的腾讯云 CDN 鉴权签名
+//
+// ip 不为空时, 原串变为 uri + "-" + ts + "-" + rand + "-" + uid + "-" + ip + "-" + privateKey,
+// 同时将 ip 以 ipArg 参数名附加到 URL 中, 供 CDN 侧的自定义鉴权规则校验
+func GenerateTencentSignWithIp(path, privateKey, uid string, randLength int, ip, ipArg string) string {
 	// 1. URL 编码路径（腾讯云使用编码后的路径参与签名）
 	// 对每个路径段编码，但保留 /
 	uri := encodePathSegments(path)
@@ -92,6 +111,9 @@ func GenerateTencentSign(path, privateKey, uid string, randLength int) string {
 
 	// 5. 构造原始字符串（注意：这里使用的是编码后的 uri）
 	raw := uri + "-" + ts + "-" + randStr + "-" + uid + "-" + privateKey
+	if ip != "" {
+		raw = uri + "-" + ts + "-" + randStr + "-" + uid + "-" + ip + "-" + privateKey
+	}
 
 	// 6. 计算 MD5（16进制小写）
 	hash := md5.Sum([]byte(raw))
@@ -101,7 +123,15 @@ func GenerateTencentSign(path, privateKey, uid string, randLength int) string {
 	sign := ts + "-" + randStr + "-" + uid + "-" + md5Str
 
 	// 8. 返回带签名的路径
-	return uri + "?sign=" + sign
+	return uri + "?sign=" + sign + ipQuery(ip, ipArg)
+}
+
+// ipQuery 生成附加到签名 URL 后的客户端 IP 参数
+func ipQuery(ip, ipArg string) string {
+	if ip == "" || ipArg == "" {
+		return ""
+	}
+	return "&" + url.QueryEscape(ipArg) + "=" + url.QueryEscape(ip)
 }
 
 // generateRandomString 生成指定长度的随机字符串
@@ -109,6 +109,29 @@ func TestGenerateTencentSign(t *testing.T) {
 	t.Logf("腾讯云签名结果: %s", result)
 }
 
+// TestGenerateSignWithIp 测试绑定客户端 IP 的签名生成
+func TestGenerateSignWithIp(t *testing.T) {
+	path := "/电影/test.mp4"
+	privateKey := "test_secret_key"
+	ip := "1.2.3.4"
+
+	goedge := GenerateGoEdgeSignWithIp(path, privateKey, 16, ip, "ip")
+	if !strings.HasSuffix(goedge, "&ip=1.2.3.4") {
+		t.Errorf("GoEdge 签名应附加 ip 参数，实际: %s", goedge)
+	}
+
+	tencent := GenerateTencentSignWithIp(path, privateKey, "0", 6, ip, "cip")
+	if !strings.HasSuffix(tencent, "&cip=1.2.3.4") {
+		t.Errorf("腾讯云签名应附加 cip 参数，实际: %s", tencent)
+	}
+
+	// 不绑定 IP 时与原始签名格式一致
+	plain := GenerateGoEdgeSignWithIp(path, privateKey, 16, "", "ip")
+	if strings.Contains(plain, "&ip=") {
+		t.Errorf("未绑定 IP 时不应附加 ip 参数，实际: %s", plain)
+	}
+}
+
 // TestGenerateGoEdgeSign_WithZeroRand 测试随机字符串为 0 的情况
 func TestGenerateGoEdgeSign_WithZeroRand(t *testing.T) {
 	path := "/test.mp4"
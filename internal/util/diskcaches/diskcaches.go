@@ -0,0 +1,189 @@
+package diskcaches
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entry 缓存文件信息
+type entry struct {
+	size     int64
+	accessed time.Time
+}
+
+// Cache 基于磁盘文件的 LRU 缓存
+//
+// 缓存文件按 key 的哈希值分散存放在目录下, 总大小超出配额时,
+// 优先淘汰最久未访问的文件, 直到总大小降至配额的 90%
+type Cache struct {
+	mu      sync.Mutex
+	dir     string
+	quota   int64
+	total   int64
+	entries map[string]*entry
+}
+
+// New 初始化磁盘缓存, 会扫描目录下已有的缓存文件
+//
+// quota 为缓存总大小上限, 单位: 字节
+func New(dir string, quota int64) (*Cache, error) {
+	if quota <= 0 {
+		return nil, fmt.Errorf("非法的缓存配额: %d", quota)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("初始化缓存目录失败: %v", err)
+	}
+
+	c := &Cache{dir: dir, quota: quota, entries: make(map[string]*entry)}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.HasSuffix(path, ".tmp") {
+			// 上次写入未完成的临时文件
+			os.Remove(path)
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		c.entries[filepath.Base(path)] = &entry{size: info.Size(), accessed: info.ModTime()}
+		c.total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("扫描缓存目录失败: %v", err)
+	}
+
+	c.mu.Lock()
+	c.evict()
+	c.mu.Unlock()
+	return c, nil
+}
+
+// Get 获取缓存内容
+func (c *Cache) Get(key string) ([]byte, bool) {
+	name := hashName(key)
+
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if ok {
+		e.accessed = time.Now()
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		c.remove(name)
+		return nil, false
+	}
+	// 更新修改时间, 重启后仍能按访问时间淘汰
+	now := time.Now()
+	os.Chtimes(c.path(name), now, now)
+	return data, true
+}
+
+// Put 写入缓存, 写入后如果超出配额会触发淘汰
+func (c *Cache) Put(key string, data []byte) error {
+	name := hashName(key)
+	fp := c.path(name)
+	if err := os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp := fp + ".tmp"
+	if err := os.WriteFile(tmp, data, os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[name]; ok {
+		c.total -= old.size
+	}
+	c.entries[name] = &entry{size: int64(len(data)), accessed: time.Now()}
+	c.total += int64(len(data))
+	c.evict()
+	return nil
+}
+
+// Size 获取当前缓存总大小
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// SetQuota 修改缓存总大小上限, 超出新配额时立即淘汰
+func (c *Cache) SetQuota(quota int64) error {
+	if quota <= 0 {
+		return fmt.Errorf("非法的缓存配额: %d", quota)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quota = quota
+	c.evict()
+	return nil
+}
+
+// evict 淘汰最久未访问的缓存, 调用方需持有锁
+func (c *Cache) evict() {
+	if c.total <= c.quota {
+		return
+	}
+
+	names := make([]string, 0, len(c.entries))
+	for name := range c.entries {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return c.entries[a].accessed.Compare(c.entries[b].accessed)
+	})
+
+	target := c.quota / 10 * 9
+	for _, name := range names {
+		if c.total <= target {
+			break
+		}
+		c.total -= c.entries[name].size
+		delete(c.entries, name)
+		os.Remove(c.path(name))
+	}
+}
+
+// remove 移除一个缓存记录
+func (c *Cache) remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[name]; ok {
+		c.total -= e.size
+		delete(c.entries, name)
+	}
+}
+
+// path 获取缓存文件的绝对路径, 按哈希值前两位分散到子目录中
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, name[:2], name)
+}
+
+// hashName 计算 key 对应的缓存文件名称
+func hashName(key string) string {
+	sum := md5.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package diskcaches_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/diskcaches"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	c, err := diskcaches.New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(key string, size int) {
+		if err := c.Put(key, bytes.Repeat([]byte{'a'}, size)); err != nil {
+			t.Fatal(err)
+		}
+		// 保证访问时间有先后
+		time.Sleep(time.Millisecond * 5)
+	}
+	put("a", 40)
+	put("b", 40)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a 应该存在")
+	}
+	time.Sleep(time.Millisecond * 5)
+	put("c", 40)
+
+	// 超出配额, 淘汰最久未访问的 b
+	if _, ok := c.Get("b"); ok {
+		t.Error("b 应该被淘汰")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Error("a 不应被淘汰")
+	}
+	if c.Size() != 80 {
+		t.Errorf("Size() = %d, want 80", c.Size())
+	}
+
+	// 重新加载时恢复已有缓存
+	reloaded, err := diskcaches.New(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := reloaded.Get("c"); !ok || len(data) != 40 {
+		t.Error("重新加载后 c 应该存在")
+	}
+
+	// 缩小配额后立即淘汰
+	if err := reloaded.SetQuota(50); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Size() > 45 {
+		t.Errorf("缩小配额后 Size() = %d, want <= 45", reloaded.Size())
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 )
 
 const (
@@ -21,6 +22,9 @@ const (
 
 var client *http.Client
 
+// streamClient 用于长时间流式传输的客户端, 与 client 共用连接池, 但不限制总超时时间
+var streamClient *http.Client
+
 // RedirectCodes 有重定向含义的 http 响应码
 var RedirectCodes = [4]int{http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect}
 
@@ -48,6 +52,11 @@ func init() {
 		// 总超时时间（包含连接建立、请求发送、响应读取）
 		Timeout: 10 * time.Minute,
 	}
+
+	streamClient = &http.Client{
+		Transport:     client.Transport,
+		CheckRedirect: client.CheckRedirect,
+	}
 }
 
 // IsRedirectCode 判断 http code 是否是重定向
@@ -81,7 +90,7 @@ func MapBody(body map[string]any) io.ReadCloser {
 	}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
-		log.Printf("MapBody 转换失败, body: %v, err : %v", body, err)
+		logs.Warn("MapBody 转换失败, body: %v, err : %v", body, err)
 		return nil
 	}
 	return io.NopCloser(bytes.NewBuffer(bodyBytes))
@@ -2,11 +2,17 @@ package https
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"path"
 	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type RequestHolder struct {
@@ -28,6 +34,12 @@ type RequestHolder struct {
 
 	// closeConn 请求结束后是否关闭连接
 	closeConn bool
+
+	// ctx 请求上下文, 上下文取消时请求会被中断
+	ctx context.Context
+
+	// stream 是否使用无总超时限制的客户端
+	stream bool
 }
 
 // Request 构造自定义请求
@@ -92,6 +104,18 @@ func (r *RequestHolder) CloseConn() *RequestHolder {
 	return r
 }
 
+// Context 设置请求上下文, 上下文取消时请求会被中断
+func (r *RequestHolder) Context(ctx context.Context) *RequestHolder {
+	r.ctx = ctx
+	return r
+}
+
+// Stream 使用无总超时限制的客户端发起请求, 适用于长时间的流式传输
+func (r *RequestHolder) Stream() *RequestHolder {
+	r.stream = true
+	return r
+}
+
 // Do 发起请求 自动重定向
 func (r *RequestHolder) Do() (*http.Response, error) {
 	r.redirect = true
@@ -116,6 +140,20 @@ func (r *RequestHolder) DoRedirect() (string, *http.Response, error) {
 // 如果一个请求有多次重定向并且进行了 autoRedirect,
 // 则最后一次重定向的 url 会作为第一个参数返回
 func (r *RequestHolder) execute() (string, *http.Response, error) {
+	// 整个请求 (包括重定向) 记录为一个客户端 span, 追踪信息写入请求头副本
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	header := r.header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	ctx, span := traces.StartClient(ctx, header, "HTTP "+r.method,
+		attribute.String("http.request.method", r.method),
+		attribute.String("url.full", logs.Redact(r.url)),
+	)
+
 	var inner func(method, url string, header http.Header, body io.ReadCloser, autoRedirect bool, depth int) (string, *http.Response, error)
 	inner = func(method, url string, header http.Header, body io.ReadCloser, autoRedirect bool, depth int) (string, *http.Response, error) {
 		if depth >= MaxRedirectDepth {
@@ -130,7 +168,7 @@ func (r *RequestHolder) execute() (string, *http.Response, error) {
 				return "", nil, fmt.Errorf("读取请求体失败: %v", err)
 			}
 		}
-		req, err := http.NewRequest(method, url, bytes.NewBuffer(bodyBytes))
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(bodyBytes))
 		if err != nil {
 			return "", nil, fmt.Errorf("创建请求失败: %v", err)
 		}
@@ -139,7 +177,11 @@ func (r *RequestHolder) execute() (string, *http.Response, error) {
 		req.Header = header
 
 		// 2 发出请求
-		resp, err := client.Do(req)
+		c := client
+		if r.stream {
+			c = streamClient
+		}
+		resp, err := c.Do(req)
 		if err != nil {
 			return url, resp, err
 		}
@@ -165,5 +207,13 @@ func (r *RequestHolder) execute() (string, *http.Response, error) {
 		return inner(method, loc, header, newBody, autoRedirect, depth+1)
 	}
 
-	return inner(r.method, r.url, r.header, r.body, r.redirect, 0)
+	finalUrl, resp, err := inner(r.method, r.url, header, r.body, r.redirect, 0)
+	if resp != nil {
+		traces.SetStatusCode(span, resp.StatusCode)
+	}
+	if finalUrl != r.url {
+		span.SetAttributes(attribute.String("http.final_url", logs.Redact(finalUrl)))
+	}
+	traces.End(span, err)
+	return finalUrl, resp, err
 }
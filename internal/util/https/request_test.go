@@ -0,0 +1,61 @@
+package https_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestRequestTracing 测试请求的客户端 span 与追踪信息传递
+func TestRequestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer traces.Configure(traces.Options{})
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	ctx, parent := traces.Start(context.Background(), "handler")
+	header := http.Header{"X-Test": []string{"1"}}
+	resp, err := https.Get(srv.URL + "/videos/1/stream?api_key=secret").Context(ctx).Header(header).Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	if traceparent == "" {
+		t.Fatal("上游没有收到 traceparent 请求头")
+	}
+	if header.Get("traceparent") != "" {
+		t.Error("追踪信息不应写入调用方传入的请求头")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("span 数量 = %d, want 2", len(spans))
+	}
+	client := spans[0]
+	if client.Name != "HTTP GET" || client.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("客户端 span 不符合预期: %s, parent: %s", client.Name, client.Parent.SpanID())
+	}
+	for _, attr := range client.Attributes {
+		if attr.Key == "url.full" && attr.Value.AsString() != srv.URL+"/videos/1/stream?api_key=***" {
+			t.Errorf("url.full 未脱敏: %s", attr.Value.AsString())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package https
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -85,8 +86,9 @@ func ProxyRequest(r *http.Request, remote string) (*http.Response, error) {
 		return nil, fmt.Errorf("解析远程地址失败: %v", err)
 	}
 
-	// 2 发送请求
+	// 2 发送请求, 沿用客户端请求的追踪信息, 但不随客户端请求取消
 	return Request(r.Method, rawUrl).
+		Context(context.WithoutCancel(r.Context())).
 		Header(r.Header).
 		Body(r.Body).
 		Do()
@@ -105,16 +107,21 @@ func ProxyPass(r *http.Request, w http.ResponseWriter, remote string) error {
 	}
 	defer resp.Body.Close()
 
-	// 2 回写响应头（必须在 WriteHeader 之前设置）
+	// 2 回写响应
+	return WriteResponse(w, resp)
+}
+
+// WriteResponse 将远程响应的响应头、响应码和响应体回写到客户端, 不会关闭响应体
+func WriteResponse(w http.ResponseWriter, resp *http.Response) error {
+	// 1 回写响应头（必须在 WriteHeader 之前设置）
 	CloneHeader(w, resp.Header)
 	w.WriteHeader(resp.StatusCode)
 
-	// 3 回写响应体
+	// 2 回写响应体
 	buf := bytess.CommonFixedBuffer()
 	defer buf.PutBack()
-	_, copyErr := io.CopyBuffer(w, resp.Body, buf.Bytes())
-	if copyErr != nil {
-		return fmt.Errorf("响应体传输失败: %v", copyErr)
+	if _, err := io.CopyBuffer(w, resp.Body, buf.Bytes()); err != nil {
+		return fmt.Errorf("响应体传输失败: %v", err)
 	}
 	return nil
 }
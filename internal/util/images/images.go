@@ -0,0 +1,100 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Format 图片编码格式
+type Format string
+
+const (
+	FormatJpeg Format = "jpeg"
+	FormatPng  Format = "png"
+	FormatWebp Format = "webp"
+	FormatGif  Format = "gif"
+)
+
+// ContentType 获取图片格式对应的响应类型
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Resize 按最大宽高等比缩放图片, 并编码为指定格式
+//
+// maxWidth 或 maxHeight 为 0 表示该方向不作限制;
+// 图片无需缩放且格式与目标格式一致时, 直接返回原始数据, 避免重复压缩
+func Resize(data []byte, maxWidth, maxHeight int, target Format, quality int) ([]byte, error) {
+	src, srcFormat, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("图片解码失败: %v", err)
+	}
+
+	bounds := src.Bounds()
+	w, h := fitSize(bounds.Dx(), bounds.Dy(), maxWidth, maxHeight)
+	if w == bounds.Dx() && h == bounds.Dy() && Format(srcFormat) == target {
+		return data, nil
+	}
+
+	dst := src
+	if w != bounds.Dx() || h != bounds.Dy() {
+		scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, bounds, draw.Src, nil)
+		dst = scaled
+	}
+	return Encode(dst, target, quality)
+}
+
+// Encode 将图片编码为指定格式
+func Encode(img image.Image, format Format, quality int) ([]byte, error) {
+	buf := bytes.Buffer{}
+	var err error
+	switch format {
+	case FormatJpeg:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case FormatPng:
+		err = png.Encode(&buf, img)
+	case FormatGif:
+		err = gif.Encode(&buf, img, nil)
+	default:
+		return nil, fmt.Errorf("不支持编码的图片格式: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("图片编码失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// DetectFormat 检测图片数据的格式
+func DetectFormat(data []byte) (Format, bool) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", false
+	}
+	return Format(format), true
+}
+
+// fitSize 计算等比缩放后的尺寸, 只缩小不放大
+func fitSize(w, h, maxWidth, maxHeight int) (int, int) {
+	if w <= 0 || h <= 0 {
+		return w, h
+	}
+	scale := 1.0
+	if maxWidth > 0 && w > maxWidth {
+		scale = min(scale, float64(maxWidth)/float64(w))
+	}
+	if maxHeight > 0 && h > maxHeight {
+		scale = min(scale, float64(maxHeight)/float64(h))
+	}
+	if scale == 1.0 {
+		return w, h
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
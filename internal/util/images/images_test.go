@@ -0,0 +1,52 @@
+package images_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/images"
+)
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 600))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 600; y++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		maxWidth  int
+		maxHeight int
+		format    images.Format
+		wantW     int
+		wantH     int
+	}{
+		{name: "按宽度缩放", maxWidth: 200, format: images.FormatJpeg, wantW: 200, wantH: 300},
+		{name: "按高度缩放", maxWidth: 300, maxHeight: 300, format: images.FormatJpeg, wantW: 200, wantH: 300},
+		{name: "不放大", maxWidth: 800, format: images.FormatPng, wantW: 400, wantH: 600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := images.Resize(buf.Bytes(), tt.maxWidth, tt.maxHeight, tt.format, 80)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != tt.wantW || cfg.Height != tt.wantH || images.Format(format) != tt.format {
+				t.Errorf("Resize() = %dx%d %s, want %dx%d %s", cfg.Width, cfg.Height, format, tt.wantW, tt.wantH, tt.format)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package jsons
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSeg 路径中的一级
+type pathSeg struct {
+	key      string // 对象属性名
+	index    int    // 数组索引
+	isIndex  bool   // 是否为数组索引
+	wildcard bool   // 是否匹配数组所有元素 [*]
+}
+
+// Path 类 JSONPath 的路径表达式, 如 $.Items[*].MediaSources[0].Name
+//
+// 支持对象属性 (.key), 数组索引 ([0]) 以及数组通配 ([*])
+type Path struct {
+	raw  string
+	segs []pathSeg
+}
+
+// ParsePath 解析路径表达式
+func ParsePath(expr string) (Path, error) {
+	p := Path{raw: expr}
+	rest := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return Path{}, fmt.Errorf("路径 [%s] 存在空属性名", expr)
+			}
+			p.segs = append(p.segs, pathSeg{key: rest[:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return Path{}, fmt.Errorf("路径 [%s] 缺少 ]", expr)
+			}
+			inner := rest[1:end]
+			rest = rest[end+1:]
+			if inner == "*" {
+				p.segs = append(p.segs, pathSeg{wildcard: true})
+				continue
+			}
+			idx, err := strconv.Atoi(inner)
+			if err != nil || idx < 0 {
+				return Path{}, fmt.Errorf("路径 [%s] 存在非法的数组索引: [%s]", expr, inner)
+			}
+			p.segs = append(p.segs, pathSeg{index: idx, isIndex: true})
+		default:
+			// 允许省略开头的 .
+			rest = "." + rest
+		}
+	}
+	if len(p.segs) == 0 {
+		return Path{}, fmt.Errorf("路径 [%s] 不能为空", expr)
+	}
+	return p, nil
+}
+
+// String 返回原始路径表达式
+func (p Path) String() string {
+	return p.raw
+}
+
+// LastKey 路径最后一级为对象属性时, 返回属性名
+func (p Path) LastKey() (string, bool) {
+	last := p.segs[len(p.segs)-1]
+	if last.isIndex || last.wildcard {
+		return "", false
+	}
+	return last.key, true
+}
+
+// SetPath 将路径上已存在的节点以及父级对象中缺失的属性设置为新值, 返回修改的节点数
+//
+// newVal 每次调用都应该返回一个新的 item, 避免多个节点共用同一个对象
+func (i *Item) SetPath(p Path, newVal func() *Item) int {
+	count := 0
+	last := p.segs[len(p.segs)-1]
+	for _, parent := range i.pathParents(p) {
+		switch {
+		case parent.jType == JsonTypeObj && !last.isIndex && !last.wildcard:
+			parent.Put(last.key, newVal())
+			count++
+		case parent.jType == JsonTypeArr && last.wildcard:
+			for idx := range parent.arr {
+				parent.arr[idx] = newVal()
+				count++
+			}
+		case parent.jType == JsonTypeArr && last.isIndex && last.index < len(parent.arr):
+			parent.arr[last.index] = newVal()
+			count++
+		}
+	}
+	return count
+}
+
+// DelPath 删除路径上的节点, 返回删除的节点数
+func (i *Item) DelPath(p Path) int {
+	count := 0
+	last := p.segs[len(p.segs)-1]
+	for _, parent := range i.pathParents(p) {
+		switch {
+		case parent.jType == JsonTypeObj && !last.isIndex && !last.wildcard:
+			if _, ok := parent.obj[last.key]; ok {
+				parent.DelKey(last.key)
+				count++
+			}
+		case parent.jType == JsonTypeArr && last.wildcard:
+			count += len(parent.arr)
+			parent.arr = parent.arr[:0]
+		case parent.jType == JsonTypeArr && last.isIndex && last.index < len(parent.arr):
+			parent.DelIdx(last.index)
+			count++
+		}
+	}
+	return count
+}
+
+// RenamePath 将路径上的对象属性重命名为 newKey, 返回修改的节点数
+//
+// 路径最后一级必须为对象属性
+func (i *Item) RenamePath(p Path, newKey string) int {
+	key, ok := p.LastKey()
+	if !ok || key == newKey {
+		return 0
+	}
+	count := 0
+	for _, parent := range i.pathParents(p) {
+		if parent.jType != JsonTypeObj {
+			continue
+		}
+		if val, ok := parent.obj[key]; ok {
+			parent.DelKey(key)
+			parent.Put(newKey, val)
+			count++
+		}
+	}
+	return count
+}
+
+// RangePath 遍历路径上的所有节点
+func (i *Item) RangePath(p Path, callback func(value *Item)) {
+	last := p.segs[len(p.segs)-1]
+	for _, parent := range i.pathParents(p) {
+		for _, child := range parent.children(last) {
+			callback(child)
+		}
+	}
+}
+
+// pathParents 获取路径最后一级的所有父级节点
+func (i *Item) pathParents(p Path) []*Item {
+	nodes := []*Item{i}
+	for _, seg := range p.segs[:len(p.segs)-1] {
+		next := make([]*Item, 0, len(nodes))
+		for _, node := range nodes {
+			next = append(next, node.children(seg)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// children 获取节点在路径片段下的子节点
+func (i *Item) children(seg pathSeg) []*Item {
+	if i == nil {
+		return nil
+	}
+	switch {
+	case seg.wildcard && i.jType == JsonTypeArr:
+		return i.arr
+	case seg.isIndex && i.jType == JsonTypeArr && seg.index < len(i.arr):
+		return []*Item{i.arr[seg.index]}
+	case !seg.isIndex && !seg.wildcard && i.jType == JsonTypeObj:
+		if child, ok := i.obj[seg.key]; ok {
+			return []*Item{child}
+		}
+	}
+	return nil
+}
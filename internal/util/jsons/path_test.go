@@ -0,0 +1,96 @@
+package jsons_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{expr: "$.Items[*].MediaSources[0].Name"},
+		{expr: "Items[*].Name"},
+		{expr: "[0].Name"},
+		{expr: "$", wantErr: true},
+		{expr: "Items[", wantErr: true},
+		{expr: "Items[a]", wantErr: true},
+		{expr: "Items..Name", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := jsons.ParsePath(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathOps(t *testing.T) {
+	raw := `{"Items":[{"Name":"a","Path":"/x/a","Tags":["t1","t2"]},{"Name":"b","Path":"/x/b"}],"Total":2}`
+	mustPath := func(expr string) jsons.Path {
+		p, err := jsons.ParsePath(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name  string
+		op    func(item *jsons.Item) int
+		count int
+		want  string
+	}{
+		{
+			name: "设置属性",
+			op: func(item *jsons.Item) int {
+				return item.SetPath(mustPath("Items[*].Type"), func() *jsons.Item { return jsons.FromValue("Movie") })
+			},
+			count: 2,
+			want:  `{"Items":[{"Name":"a","Path":"/x/a","Tags":["t1","t2"],"Type":"Movie"},{"Name":"b","Path":"/x/b","Type":"Movie"}],"Total":2}`,
+		},
+		{
+			name:  "删除属性",
+			op:    func(item *jsons.Item) int { return item.DelPath(mustPath("$.Items[*].Path")) },
+			count: 2,
+			want:  `{"Items":[{"Name":"a","Tags":["t1","t2"]},{"Name":"b"}],"Total":2}`,
+		},
+		{
+			name:  "删除数组元素",
+			op:    func(item *jsons.Item) int { return item.DelPath(mustPath("Items[0].Tags[1]")) },
+			count: 1,
+			want:  `{"Items":[{"Name":"a","Path":"/x/a","Tags":["t1"]},{"Name":"b","Path":"/x/b"}],"Total":2}`,
+		},
+		{
+			name:  "重命名属性",
+			op:    func(item *jsons.Item) int { return item.RenamePath(mustPath("Total"), "TotalRecordCount") },
+			count: 1,
+			want:  `{"Items":[{"Name":"a","Path":"/x/a","Tags":["t1","t2"]},{"Name":"b","Path":"/x/b"}],"TotalRecordCount":2}`,
+		},
+		{
+			name:  "路径不存在",
+			op:    func(item *jsons.Item) int { return item.DelPath(mustPath("Foo[*].Bar")) },
+			count: 0,
+			want:  raw,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := jsons.New(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count := tt.op(item); count != tt.count {
+				t.Errorf("count = %d, want %d", count, tt.count)
+			}
+			if got := strings.TrimSpace(item.String()); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
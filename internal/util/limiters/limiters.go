@@ -0,0 +1,156 @@
+package limiters
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// idleExpired 桶闲置超过该时长后会被清理
+const idleExpired = time.Minute * 10
+
+// Bucket 令牌桶
+type Bucket struct {
+	// burst 桶容量
+	burst float64
+
+	// refill 每秒补充的令牌数
+	refill float64
+
+	// tokens 当前令牌数
+	tokens float64
+
+	// last 最后一次补充令牌的时间
+	last time.Time
+
+	mu sync.Mutex
+}
+
+// NewBucket 初始化一个满令牌的令牌桶
+func NewBucket(burst int, refill float64) *Bucket {
+	return &Bucket{
+		burst:  float64(burst),
+		refill: refill,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Take 尝试取出一个令牌
+//
+// 取出失败时, 第二个参数返回下一个令牌可用需要等待的时长
+func (b *Bucket) Take() (bool, time.Duration) {
+	return TakeAll(b)
+}
+
+// TakeAll 所有令牌桶都有可用令牌时, 才从每个桶中各取出一个令牌
+//
+// 为 nil 的桶不作限制, 取出失败时不消耗任何令牌, 并返回最长的等待时长,
+// 并发调用时需要以相同的顺序传入令牌桶
+func TakeAll(buckets ...*Bucket) (bool, time.Duration) {
+	now := time.Now()
+	var wait time.Duration
+	for _, b := range buckets {
+		if b == nil {
+			continue
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		wait = max(wait, b.refillAt(now))
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, b := range buckets {
+		if b != nil {
+			b.tokens--
+		}
+	}
+	return true, 0
+}
+
+// refillAt 补充令牌, 返回下一个令牌可用需要等待的时长, 调用方需持有锁
+func (b *Bucket) refillAt(now time.Time) time.Duration {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.refill)
+	b.last = now
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	if b.refill <= 0 {
+		return time.Hour
+	}
+	wait := (1 - b.tokens) / b.refill
+	return time.Duration(wait * float64(time.Second))
+}
+
+// idle 判断令牌桶是否已经闲置了指定时长
+func (b *Bucket) idle(d time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Since(b.last) > d
+}
+
+// Keyed 按 key 区分的令牌桶集合
+type Keyed struct {
+	// burst 桶容量
+	burst int
+
+	// refill 每秒补充的令牌数
+	refill float64
+
+	// buckets 存放所有 key 的令牌桶
+	buckets sync.Map
+
+	// lastClean 上一次清理闲置桶的时间
+	lastClean time.Time
+
+	mu sync.Mutex
+}
+
+// NewKeyed 初始化一个按 key 区分的令牌桶集合
+func NewKeyed(burst int, refill float64) *Keyed {
+	return &Keyed{burst: burst, refill: refill, lastClean: time.Now()}
+}
+
+// Take 尝试从 key 对应的令牌桶中取出一个令牌
+//
+// key 为空时, 不作限制
+func (k *Keyed) Take(key string) (bool, time.Duration) {
+	return TakeAll(k.Bucket(key))
+}
+
+// Bucket 获取 key 对应的令牌桶, 不存在时创建
+//
+// key 为空时返回 nil, 表示不作限制
+func (k *Keyed) Bucket(key string) *Bucket {
+	if key == "" {
+		return nil
+	}
+	k.cleanIdle()
+
+	b, ok := k.buckets.Load(key)
+	if !ok {
+		b, _ = k.buckets.LoadOrStore(key, NewBucket(k.burst, k.refill))
+	}
+	return b.(*Bucket)
+}
+
+// cleanIdle 定期清理闲置的令牌桶, 防止 key 无限增长
+func (k *Keyed) cleanIdle() {
+	k.mu.Lock()
+	if time.Since(k.lastClean) < idleExpired {
+		k.mu.Unlock()
+		return
+	}
+	k.lastClean = time.Now()
+	k.mu.Unlock()
+
+	k.buckets.Range(func(key, value any) bool {
+		if value.(*Bucket).idle(idleExpired) {
+			k.buckets.Delete(key)
+		}
+		return true
+	})
+}
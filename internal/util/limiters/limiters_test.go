@@ -0,0 +1,63 @@
+package limiters_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/limiters"
+)
+
+func TestBucketTake(t *testing.T) {
+	b := limiters.NewBucket(3, 1)
+	for i := 0; i < 3; i++ {
+		if ok, _ := b.Take(); !ok {
+			t.Fatalf("第 %d 次取令牌失败", i+1)
+		}
+	}
+
+	ok, wait := b.Take()
+	if ok {
+		t.Fatal("令牌耗尽后应该取令牌失败")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("等待时长异常: %v", wait)
+	}
+}
+
+func TestKeyedTake(t *testing.T) {
+	k := limiters.NewKeyed(1, 0.001)
+
+	if ok, _ := k.Take("a"); !ok {
+		t.Fatal("key a 首次取令牌应该成功")
+	}
+	if ok, _ := k.Take("a"); ok {
+		t.Fatal("key a 第二次取令牌应该失败")
+	}
+	if ok, _ := k.Take("b"); !ok {
+		t.Fatal("key b 与 key a 相互独立, 应该成功")
+	}
+	if ok, _ := k.Take(""); !ok {
+		t.Fatal("空 key 不作限制")
+	}
+}
+
+func TestTakeAll(t *testing.T) {
+	full, empty := limiters.NewBucket(2, 0.001), limiters.NewBucket(1, 0.001)
+	if ok, _ := empty.Take(); !ok {
+		t.Fatal("首次取令牌应该成功")
+	}
+
+	if ok, wait := limiters.TakeAll(full, empty); ok || wait <= 0 {
+		t.Fatalf("存在令牌耗尽的桶时应该失败, ok: %v, wait: %v", ok, wait)
+	}
+	// 失败时不消耗 full 的令牌
+	for i := 0; i < 2; i++ {
+		if ok, _ := full.Take(); !ok {
+			t.Fatalf("第 %d 次取令牌失败, 令牌被错误消耗", i+1)
+		}
+	}
+
+	if ok, _ := limiters.TakeAll(nil, limiters.NewBucket(1, 1)); !ok {
+		t.Fatal("nil 桶不作限制")
+	}
+}
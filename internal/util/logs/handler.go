@@ -0,0 +1,292 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+)
+
+// 日志输出格式
+const (
+	FormatConsole = "console" // 彩色控制台格式
+	FormatText    = "text"    // slog key=value 格式
+	FormatJson    = "json"    // slog json 格式
+)
+
+// keyConsoleLine 控制台格式下整行输出的内容, 结构化输出时忽略
+const keyConsoleLine = "_console"
+
+// Options 日志输出选项
+type Options struct {
+	// Format 输出格式
+	Format string
+	// Level 全局最低输出级别
+	Level slog.Level
+	// Levels 子系统的最低输出级别, 覆盖全局级别
+	Levels map[string]slog.Level
+	// Redact 是否对 api_key 等敏感信息脱敏
+	Redact bool
+	// Stdout 是否输出到控制台
+	Stdout bool
+	// File 日志文件, 为空则不输出到文件
+	File io.Writer
+}
+
+// Configure 按选项重新初始化日志输出
+//
+// 日志文件与当前使用的是同一路径时沿用已打开的文件, 否则在切换后关闭旧的日志文件
+func Configure(opts Options) error {
+	prev := current.Load()
+	if rw, ok := opts.File.(*RotateWriter); ok {
+		if old, ok := prev.file.(*RotateWriter); ok && old.Path == rw.Path {
+			opts.File = old
+		}
+	}
+
+	var handlers []slog.Handler
+	newHandler := func(w io.Writer, color bool) (slog.Handler, error) {
+		switch opts.Format {
+		case "", FormatConsole:
+			return newConsoleHandler(w, color), nil
+		case FormatText:
+			return slog.NewTextHandler(w, structuredOptions()), nil
+		case FormatJson:
+			return slog.NewJSONHandler(w, structuredOptions()), nil
+		}
+		return nil, fmt.Errorf("不支持的日志格式: [%s]", opts.Format)
+	}
+
+	if opts.Stdout {
+		h, err := newHandler(os.Stdout, true)
+		if err != nil {
+			return err
+		}
+		handlers = append(handlers, h)
+	}
+	if opts.File != nil {
+		h, err := newHandler(opts.File, false)
+		if err != nil {
+			return err
+		}
+		handlers = append(handlers, h)
+	}
+	if len(handlers) == 0 {
+		return errors.New("至少需要一个日志输出目标")
+	}
+
+	var handler slog.Handler = multiHandler(handlers)
+	if len(handlers) == 1 {
+		handler = handlers[0]
+	}
+	current.Store(&state{handler: handler, level: opts.Level, levels: opts.Levels, redact: opts.Redact, file: opts.File})
+
+	if prev.file != nil && prev.file != opts.File {
+		if closer, ok := prev.file.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
+// ParseLevel 解析日志级别名称
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "tip", "debug":
+		return LevelTip, nil
+	case "info":
+		return LevelInfo, nil
+	case "progress":
+		return LevelProgress, nil
+	case "success":
+		return LevelSuccess, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("不支持的日志级别: [%s]", name)
+}
+
+// levelName 日志级别名称
+func levelName(level slog.Level) string {
+	switch level {
+	case LevelTip:
+		return "TIP"
+	case LevelInfo:
+		return "INFO"
+	case LevelProgress:
+		return "PROGRESS"
+	case LevelSuccess:
+		return "SUCCESS"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return level.String()
+}
+
+// structuredOptions 结构化输出的 handler 选项
+func structuredOptions() *slog.HandlerOptions {
+	return &slog.HandlerOptions{
+		Level: LevelTip,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case keyConsoleLine:
+				return slog.Attr{}
+			case slog.LevelKey:
+				if level, ok := a.Value.Any().(slog.Level); ok {
+					return slog.String(slog.LevelKey, levelName(level))
+				}
+			case slog.MessageKey:
+				return slog.String(slog.MessageKey, StripAnsi(a.Value.String()))
+			}
+			return a
+		},
+	}
+}
+
+// consoleHandler 彩色控制台格式的 handler, 与旧版本的输出格式保持一致
+type consoleHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	color bool
+}
+
+func newConsoleHandler(w io.Writer, color bool) *consoleHandler {
+	return &consoleHandler{mu: new(sync.Mutex), w: w, color: color}
+}
+
+func (h *consoleHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var line string
+	var extra strings.Builder
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case keyConsoleLine:
+			line = a.Value.String()
+		case KeySubsystem:
+		default:
+			extra.WriteString(" " + a.String())
+		}
+		return true
+	})
+
+	if line == "" {
+		text := r.Message
+		switch r.Level {
+		case LevelInfo:
+			text = colors.ToBlue("[INFO] " + text)
+		case LevelSuccess:
+			text = colors.ToGreen("[SUCCESS] " + text)
+		case LevelWarn:
+			text = colors.ToYellow("[WARN] " + text)
+		case LevelError:
+			text = colors.ToRed("[ERROR] " + text)
+		case LevelTip:
+			text = colors.ToGray(text)
+		case LevelProgress:
+			text = colors.ToPurple(text)
+		}
+		line = r.Time.Format("2006-01-02 15:04:05") + " " + text + extra.String()
+	}
+	if !h.color {
+		line = StripAnsi(line)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line+"\n")
+	return err
+}
+
+func (h *consoleHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// multiHandler 将日志同时输出到多个 handler
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range m {
+		errs = append(errs, h.Handle(ctx, r.Clone()))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	res := make(multiHandler, len(m))
+	for i, h := range m {
+		res[i] = h.WithAttrs(attrs)
+	}
+	return res
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	res := make(multiHandler, len(m))
+	for i, h := range m {
+		res[i] = h.WithGroup(name)
+	}
+	return res
+}
+
+// ansiReg 匹配 ANSI 颜色控制字符
+var ansiReg = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// StripAnsi 去除字符串中的颜色控制字符
+func StripAnsi(s string) string {
+	if !strings.Contains(s, "\x1b[") {
+		return s
+	}
+	return ansiReg.ReplaceAllString(s, "")
+}
+
+// secretReg 匹配 uri 参数和请求头中的 api_key
+var secretReg = regexp.MustCompile(`(?i)((?:api_key|apikey|x-emby-token|x-mediabrowser-token)=)[^&\s"'\]]+|(token=")[^"]+`)
+
+// Redact 对字符串中的 api_key 脱敏
+func Redact(s string) string {
+	if !strings.ContainsRune(s, '=') {
+		return s
+	}
+	return secretReg.ReplaceAllString(s, "$1$2***")
+}
+
+// Mask 对敏感值脱敏, 只保留前 4 个字符
+func Mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 4 {
+		return "***"
+	}
+	return secret[:4] + "***"
+}
@@ -1,49 +1,158 @@
 package logs
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"time"
+)
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+// 日志级别, 在 slog 标准级别的基础上细分出 Tip, Progress 和 Success
+const (
+	LevelTip      = slog.LevelDebug
+	LevelInfo     = slog.LevelInfo
+	LevelProgress = slog.LevelInfo + 1
+	LevelSuccess  = slog.LevelInfo + 2
+	LevelWarn     = slog.LevelWarn
+	LevelError    = slog.LevelError
 )
 
+// KeySubsystem 日志所属子系统的属性名
+const KeySubsystem = "subsystem"
+
+// SubsystemAccess 请求日志的子系统名称
+const SubsystemAccess = "access"
+
+// state 日志输出状态
+type state struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+	redact  bool
+	file    io.Writer
+}
+
+// current 当前生效的日志输出状态, 未调用 Configure 时输出所有级别到控制台
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: newConsoleHandler(os.Stdout, true),
+		level:   LevelTip,
+		redact:  true,
+	})
+}
+
 // Info 输出蓝色 Info 日志
 func Info(format string, v ...any) {
-	s := fmt.Sprintf("[INFO] "+format, v...)
-	fmt.Println(now() + colors.ToBlue(s))
+	output(LevelInfo, format, v...)
 }
 
 // Success 输出绿色 Success 日志
 func Success(format string, v ...any) {
-	s := fmt.Sprintf("[SUCCESS] "+format, v...)
-	fmt.Println(now() + colors.ToGreen(s))
+	output(LevelSuccess, format, v...)
 }
 
 // Warn 输出黄色 Warn 日志
 func Warn(format string, v ...any) {
-	s := fmt.Sprintf("[WARN] "+format, v...)
-	fmt.Println(now() + colors.ToYellow(s))
+	output(LevelWarn, format, v...)
 }
 
 // Error 输出红色 Error 日志
 func Error(format string, v ...any) {
-	s := fmt.Sprintf("[ERROR] "+format, v...)
-	fmt.Println(now() + colors.ToRed(s))
+	output(LevelError, format, v...)
 }
 
 // Tip 输出灰色 Tip 日志
 func Tip(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	fmt.Println(now() + colors.ToGray(s))
+	output(LevelTip, format, v...)
 }
 
 // Progress 输出紫色 Progress 日志
 func Progress(format string, v ...any) {
-	s := fmt.Sprintf(format, v...)
-	fmt.Println(now() + colors.ToPurple(s))
+	output(LevelProgress, format, v...)
+}
+
+// Access 输出请求日志
+//
+// line 为控制台格式下输出的整行内容, args 为结构化输出时附加的属性
+func Access(line string, args ...any) {
+	s := current.Load()
+	if !s.enabled(LevelInfo, SubsystemAccess) {
+		return
+	}
+	args = append(args, slog.String(keyConsoleLine, line))
+	s.emit(LevelInfo, SubsystemAccess, "access", args...)
+}
+
+// output 按级别和调用方所属的子系统输出日志
+func output(level slog.Level, format string, v ...any) {
+	s := current.Load()
+	subsystem := callerSubsystem(3)
+	if !s.enabled(level, subsystem) {
+		return
+	}
+	s.emit(level, subsystem, fmt.Sprintf(format, v...))
+}
+
+// enabled 判断指定子系统的日志级别是否需要输出
+func (s *state) enabled(level slog.Level, subsystem string) bool {
+	min, ok := s.levels[subsystem]
+	if !ok {
+		min = s.level
+	}
+	return level >= min
+}
+
+// emit 构造日志记录并交给 handler 输出
+func (s *state) emit(level slog.Level, subsystem, msg string, args ...any) {
+	if s.redact {
+		msg = Redact(msg)
+		for i, arg := range args {
+			switch a := arg.(type) {
+			case string:
+				args[i] = Redact(a)
+			case slog.Attr:
+				if a.Value.Kind() == slog.KindString {
+					args[i] = slog.String(a.Key, Redact(a.Value.String()))
+				}
+			}
+		}
+	}
+	r := slog.NewRecord(time.Now(), level, msg, 0)
+	r.AddAttrs(slog.String(KeySubsystem, subsystem))
+	r.Add(args...)
+	s.handler.Handle(context.Background(), r)
+}
+
+// callerSubsystem 根据调用方的包名推断子系统名称
+//
+// 如 .../internal/service/emby.ProxyOrigin => emby
+func callerSubsystem(skip int) string {
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(skip+1, pcs) == 0 {
+		return ""
+	}
+	fn := runtime.FuncForPC(pcs[0])
+	if fn == nil {
+		return ""
+	}
+	return packageOf(fn.Name())
 }
 
-// now 返回当前时间戳
-func now() string {
-	return time.Now().Format("2006-01-02 15:04:05") + " "
+// packageOf 从函数全名中取出包名
+func packageOf(funcName string) string {
+	name := funcName
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		name = name[idx+1:]
+	}
+	if idx := strings.Index(name, "."); idx != -1 {
+		name = name[:idx]
+	}
+	return name
 }
@@ -0,0 +1,162 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRedact 测试 api_key 脱敏
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/emby/videos/1/stream?api_key=abcdef&Static=true", "/emby/videos/1/stream?api_key=***&Static=true"},
+		{"/Items/1/PlaybackInfo?X-Emby-Token=abcdef", "/Items/1/PlaybackInfo?X-Emby-Token=***"},
+		{`MediaBrowser Client="Emby Web", Token="abcdef"`, `MediaBrowser Client="Emby Web", Token="***"`},
+		{"ItemInfo{ApiKey: [abcd***]}", "ItemInfo{ApiKey: [abcd***]}"},
+		{"no secrets here", "no secrets here"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestPackageOf 测试从函数名推断子系统
+func TestPackageOf(t *testing.T) {
+	tests := map[string]string{
+		"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby.ProxyOrigin":      "emby",
+		"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache.RequestCacher.func1": "cache",
+		"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby.(*Foo).Bar":       "emby",
+		"main.main": "main",
+	}
+	for in, want := range tests {
+		if got := packageOf(in); got != want {
+			t.Errorf("packageOf(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// TestConfigureJson 测试 json 输出与子系统级别
+func TestConfigureJson(t *testing.T) {
+	old := current.Load()
+	defer current.Store(old)
+
+	var buf bytes.Buffer
+	err := Configure(Options{
+		Format: FormatJson,
+		Level:  LevelTip,
+		Levels: map[string]slog.Level{"logs": LevelWarn},
+		Redact: true,
+		File:   &buf,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Info("被过滤的日志")
+	Warn("请求失败: %s", "/videos/1/stream?api_key=abcdef")
+	Access("控制台格式", "status", 200, "uri", "/Items?api_key=abcdef")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("期望输出 2 行日志, 实际输出:\n%s", buf.String())
+	}
+
+	var warn map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &warn); err != nil {
+		t.Fatal(err)
+	}
+	if warn["level"] != "WARN" || warn[KeySubsystem] != "logs" || warn["msg"] != "请求失败: /videos/1/stream?api_key=***" {
+		t.Errorf("warn 日志不符合预期: %v", warn)
+	}
+
+	var access map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &access); err != nil {
+		t.Fatal(err)
+	}
+	if access[KeySubsystem] != SubsystemAccess || access["uri"] != "/Items?api_key=***" || access[keyConsoleLine] != nil {
+		t.Errorf("access 日志不符合预期: %v", access)
+	}
+}
+
+// TestConsoleHandler 测试控制台格式输出
+func TestConsoleHandler(t *testing.T) {
+	old := current.Load()
+	defer current.Store(old)
+
+	var buf bytes.Buffer
+	if err := Configure(Options{Level: LevelTip, File: &buf}); err != nil {
+		t.Fatal(err)
+	}
+	Success("同步完成")
+	Access("[ge2o] 200 | GET /")
+
+	out := buf.String()
+	if !strings.Contains(out, "[SUCCESS] 同步完成\n") || !strings.HasSuffix(out, "[ge2o] 200 | GET /\n") {
+		t.Errorf("控制台输出不符合预期:\n%s", out)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("文件输出不应包含颜色控制字符:\n%q", out)
+	}
+}
+
+// TestRotateWriter 测试日志文件轮转
+func TestRotateWriter(t *testing.T) {
+	dir := t.TempDir()
+	rw := &RotateWriter{Path: filepath.Join(dir, "app.log"), MaxSize: 10, MaxBackups: 1}
+	defer rw.Close()
+
+	for _, s := range []string{"0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		if _, err := rw.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := os.ReadFile(rw.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "ABCDEFGHIJ" {
+		t.Errorf("当前日志文件内容 = %q, want %q", content, "ABCDEFGHIJ")
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(backups) != 1 {
+		t.Errorf("备份文件数量 = %d, want 1", len(backups))
+	}
+}
+
+// TestConfigureFile 测试重新配置时沿用同一路径的日志文件, 并关闭不再使用的日志文件
+func TestConfigureFile(t *testing.T) {
+	old := current.Load()
+	defer current.Store(old)
+
+	dir := t.TempDir()
+	configure := func(name string) *RotateWriter {
+		rw := &RotateWriter{Path: filepath.Join(dir, name)}
+		if err := Configure(Options{Format: FormatText, Level: LevelTip, File: rw}); err != nil {
+			t.Fatal(err)
+		}
+		return rw
+	}
+
+	first := configure("app.log")
+	Info("first")
+	configure("app.log")
+	if current.Load().file != first {
+		t.Fatal("同一路径的日志文件应该沿用已打开的 writer")
+	}
+
+	configure("other.log")
+	if _, err := first.Write([]byte("x")); err == nil {
+		t.Error("切换日志文件后, 旧的日志文件应该被关闭")
+	}
+	current.Load().file.(*RotateWriter).Close()
+}
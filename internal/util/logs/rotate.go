@@ -0,0 +1,140 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateTimeFormat 轮转文件名中的时间格式
+const rotateTimeFormat = "20060102-150405.000"
+
+// RotateWriter 按大小轮转的日志文件
+//
+// 文件超过 MaxSize 时重命名为 name-时间.ext, 并按 MaxAge 和 MaxBackups 清理旧文件
+type RotateWriter struct {
+	// Path 日志文件路径
+	Path string
+	// MaxSize 单个文件的最大字节数, 0 表示不轮转
+	MaxSize int64
+	// MaxAge 旧文件的保留时长, 0 表示不按时间清理
+	MaxAge time.Duration
+	// MaxBackups 旧文件的保留数量, 0 表示不按数量清理
+	MaxBackups int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+}
+
+// Write 写入日志, 必要时先轮转文件
+func (rw *RotateWriter) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.closed {
+		return 0, os.ErrClosed
+	}
+	if rw.file == nil {
+		if err := rw.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rw.MaxSize > 0 && rw.size > 0 && rw.size+int64(len(p)) > rw.MaxSize {
+		if err := rw.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rw.file.Write(p)
+	rw.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件, 关闭后不能再写入
+func (rw *RotateWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.closed = true
+	if rw.file == nil {
+		return nil
+	}
+	err := rw.file.Close()
+	rw.file = nil
+	return err
+}
+
+// open 以追加模式打开日志文件
+func (rw *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(rw.Path), os.ModePerm); err != nil {
+		return fmt.Errorf("创建日志目录失败: %v", err)
+	}
+	file, err := os.OpenFile(rw.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取日志文件信息失败: %v", err)
+	}
+	rw.file, rw.size = file, stat.Size()
+	return nil
+}
+
+// rotate 将当前文件重命名为备份文件, 并打开新文件
+func (rw *RotateWriter) rotate() error {
+	if err := rw.file.Close(); err != nil {
+		return fmt.Errorf("关闭日志文件失败: %v", err)
+	}
+	rw.file = nil
+
+	ext := filepath.Ext(rw.Path)
+	prefix := strings.TrimSuffix(rw.Path, ext) + "-"
+	backup := prefix + time.Now().Format(rotateTimeFormat) + ext
+	if err := os.Rename(rw.Path, backup); err != nil {
+		return fmt.Errorf("轮转日志文件失败: %v", err)
+	}
+	rw.cleanBackups(prefix, ext)
+	return rw.open()
+}
+
+// cleanBackups 清理过期和超出数量的备份文件
+func (rw *RotateWriter) cleanBackups(prefix, ext string) {
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return
+	}
+	backups := make([]string, 0, len(matches))
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, prefix), ext)
+		if _, err := time.ParseInLocation(rotateTimeFormat, ts, time.Local); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	// 时间格式可直接按字典序排序, 新文件在前
+	slices.Sort(backups)
+	slices.Reverse(backups)
+
+	for i, b := range backups {
+		expired := false
+		if rw.MaxBackups > 0 && i >= rw.MaxBackups {
+			expired = true
+		}
+		if rw.MaxAge > 0 {
+			ts := strings.TrimSuffix(strings.TrimPrefix(b, prefix), ext)
+			t, _ := time.ParseInLocation(rotateTimeFormat, ts, time.Local)
+			if time.Since(t) > rw.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			os.Remove(b)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// 轻量的指标采集工具, 以 Prometheus 文本格式输出
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricType 指标类型
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// DefBuckets 默认的直方图分桶 (秒)
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// labelSep 拼接标签值时使用的分隔符
+const labelSep = "\xff"
+
+// collector 可输出指标的对象
+type collector interface {
+	// desc 指标名称, 说明, 类型
+	desc() (name, help string, typ metricType)
+	// collect 输出指标的所有样本
+	collect(w *bufio.Writer)
+}
+
+// Registry 指标注册中心
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]struct{}
+}
+
+// NewRegistry 初始化一个注册中心
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]struct{}{}}
+}
+
+// Default 默认的注册中心
+var Default = NewRegistry()
+
+// register 注册指标, 名称重复时 panic
+func (r *Registry) register(c collector) {
+	name, _, _ := c.desc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.names[name]; ok {
+		panic(fmt.Sprintf("指标 [%s] 重复注册", name))
+	}
+	r.names[name] = struct{}{}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	cs := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		name, help, typ := c.desc()
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, strings.ReplaceAll(help, "\n", " "))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", name, typ)
+		c.collect(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler 输出指标的 http 处理器
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	}
+}
+
+// series 一组标签值对应的样本
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// vec 带标签的指标
+type vec struct {
+	name, help string
+	typ        metricType
+	labels     []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func (v *vec) desc() (string, string, metricType) {
+	return v.name, v.help, v.typ
+}
+
+// with 获取标签值对应的样本, 在锁内执行 fn
+func (v *vec) with(labelValues []string, fn func(s *series)) {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("指标 [%s] 需要 %d 个标签值, 实际传递了 %d 个", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, labelSep)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: slices.Clone(labelValues)}
+		if v.typ == typeHistogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	fn(s)
+}
+
+func (v *vec) collect(w *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		s := v.series[key]
+		if v.typ != typeHistogram {
+			writeSample(w, v.name, v.labels, s.labelValues, s.value)
+			continue
+		}
+		labels := append(slices.Clone(v.labels), "le")
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, v.name+"_bucket", labels, append(slices.Clone(s.labelValues), formatFloat(upper)), float64(cumulative))
+		}
+		writeSample(w, v.name+"_bucket", labels, append(slices.Clone(s.labelValues), "+Inf"), float64(s.count))
+		writeSample(w, v.name+"_sum", v.labels, s.labelValues, s.sum)
+		writeSample(w, v.name+"_count", v.labels, s.labelValues, float64(s.count))
+	}
+}
+
+// CounterVec 只增不减的计数器
+type CounterVec struct{ v *vec }
+
+// Counter 注册一个计数器
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	v := &vec{name: name, help: help, typ: typeCounter, labels: labels, series: map[string]*series{}}
+	r.register(v)
+	return &CounterVec{v: v}
+}
+
+// Inc 计数加 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 delta, delta 不能为负数
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.v.with(labelValues, func(s *series) { s.value += delta })
+}
+
+// GaugeVec 可增可减的仪表盘
+type GaugeVec struct{ v *vec }
+
+// Gauge 注册一个仪表盘
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	v := &vec{name: name, help: help, typ: typeGauge, labels: labels, series: map[string]*series{}}
+	r.register(v)
+	return &GaugeVec{v: v}
+}
+
+// Set 设置当前值
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.v.with(labelValues, func(s *series) { s.value = value })
+}
+
+// Add 当前值增加 delta
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.v.with(labelValues, func(s *series) { s.value += delta })
+}
+
+// HistogramVec 直方图
+type HistogramVec struct{ v *vec }
+
+// Histogram 注册一个直方图, buckets 为空时使用 DefBuckets
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	v := &vec{name: name, help: help, typ: typeHistogram, labels: labels, buckets: buckets, series: map[string]*series{}}
+	r.register(v)
+	return &HistogramVec{v: v}
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	idx, _ := slices.BinarySearch(h.v.buckets, value)
+	h.v.with(labelValues, func(s *series) {
+		if idx < len(s.counts) {
+			s.counts[idx]++
+		}
+		s.sum += value
+		s.count++
+	})
+}
+
+// ObserveSince 记录从 start 到现在经过的秒数
+func (h *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// gaugeFunc 采集时才计算值的仪表盘
+type gaugeFunc struct {
+	name, help string
+	labels     []string
+	fn         func(set func(value float64, labelValues ...string))
+}
+
+func (g *gaugeFunc) desc() (string, string, metricType) {
+	return g.name, g.help, typeGauge
+}
+
+func (g *gaugeFunc) collect(w *bufio.Writer) {
+	g.fn(func(value float64, labelValues ...string) {
+		writeSample(w, g.name, g.labels, labelValues, value)
+	})
+}
+
+// GaugeFunc 注册一个采集时才计算值的仪表盘
+//
+// fn 中每调用一次 set 输出一个样本
+func (r *Registry) GaugeFunc(name, help string, labels []string, fn func(set func(value float64, labelValues ...string))) {
+	r.register(&gaugeFunc{name: name, help: help, labels: labels, fn: fn})
+}
+
+// NewCounter 在默认注册中心注册计数器
+func NewCounter(name, help string, labels ...string) *CounterVec {
+	return Default.Counter(name, help, labels...)
+}
+
+// NewGauge 在默认注册中心注册仪表盘
+func NewGauge(name, help string, labels ...string) *GaugeVec {
+	return Default.Gauge(name, help, labels...)
+}
+
+// NewHistogram 在默认注册中心注册直方图
+func NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.Histogram(name, help, buckets, labels...)
+}
+
+// NewGaugeFunc 在默认注册中心注册采集时计算值的仪表盘
+func NewGaugeFunc(name, help string, labels []string, fn func(set func(value float64, labelValues ...string))) {
+	Default.GaugeFunc(name, help, labels, fn)
+}
+
+// writeSample 输出一行样本
+func writeSample(w *bufio.Writer, name string, labels, labelValues []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			var lv string
+			if i < len(labelValues) {
+				lv = labelValues[i]
+			}
+			w.WriteString(label)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabel(lv))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// labelEscaper 标签值转义
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// formatFloat 格式化样本值
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestWriteText 测试 Prometheus 文本格式输出
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	counter := r.Counter("test_requests_total", "请求数", "route", "code")
+	gauge := r.Gauge("test_size_bytes", "大小")
+	hist := r.Histogram("test_duration_seconds", "耗时", []float64{0.1, 1}, "route")
+	r.GaugeFunc("test_entries", "条目数", []string{"space"}, func(set func(float64, ...string)) {
+		set(3, "images")
+	})
+
+	counter.Inc("all", "200")
+	counter.Add(2, "all", "200")
+	counter.Inc(`a"b`, "500")
+	counter.Add(-1, "all", "200")
+	gauge.Set(1024)
+	hist.Observe(0.05, "all")
+	hist.Observe(0.5, "all")
+	hist.Observe(5, "all")
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	wants := []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{route="a\"b",code="500"} 1` + "\n",
+		`test_requests_total{route="all",code="200"} 3` + "\n",
+		"test_size_bytes 1024\n",
+		`test_duration_seconds_bucket{route="all",le="0.1"} 1` + "\n",
+		`test_duration_seconds_bucket{route="all",le="1"} 2` + "\n",
+		`test_duration_seconds_bucket{route="all",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{route="all"} 5.55` + "\n",
+		`test_duration_seconds_count{route="all"} 3` + "\n",
+		"# TYPE test_entries gauge\n",
+		`test_entries{space="images"} 3` + "\n",
+	}
+	for _, want := range wants {
+		if !strings.Contains(out, want) {
+			t.Errorf("输出中缺少 %q, 实际输出:\n%s", want, out)
+		}
+	}
+}
+
+// TestRegisterDuplicate 测试重复注册
+func TestRegisterDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("dup_total", "重复")
+	defer func() {
+		if recover() == nil {
+			t.Error("重复注册指标时应当 panic")
+		}
+	}()
+	r.Gauge("dup_total", "重复")
+}
@@ -0,0 +1,221 @@
+package subtitles
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cue 一条字幕
+type cue struct {
+	start time.Duration
+	end   time.Duration
+	text  string
+}
+
+// assTagReg 匹配 ass 字幕中的样式标签, 如 {\an8\fs20}
+var assTagReg = regexp.MustCompile(`\{[^}]*\}`)
+
+// Convert 将字幕从 from 格式转换为 to 格式
+//
+// 支持 srt 与 vtt 互转, 以及将 ass 去除样式后转换为 vtt 或 srt
+func Convert(text string, from, to Format) (string, error) {
+	if from == to {
+		return text, nil
+	}
+
+	text = strings.ReplaceAll(strings.TrimPrefix(text, "\uFEFF"), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var cues []cue
+	switch from {
+	case FormatSrt, FormatVtt:
+		cues = parseBlocks(text)
+	case FormatAss:
+		cues = parseAss(text)
+	default:
+		return "", fmt.Errorf("不支持的源字幕格式: [%s]", from)
+	}
+
+	switch to {
+	case FormatVtt:
+		return writeVtt(cues), nil
+	case FormatSrt:
+		return writeSrt(cues), nil
+	}
+	return "", fmt.Errorf("不支持的目标字幕格式: [%s]", to)
+}
+
+// parseBlocks 解析 srt 或 vtt 字幕, 两者都以空行分隔字幕块
+func parseBlocks(text string) []cue {
+	cues := make([]cue, 0)
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timingIdx := slices.IndexFunc(lines, func(line string) bool {
+			return strings.Contains(line, "-->")
+		})
+		if timingIdx == -1 {
+			// vtt 的头部、NOTE、STYLE 等块不包含时间轴
+			continue
+		}
+
+		parts := strings.SplitN(lines[timingIdx], "-->", 2)
+		start, err1 := parseTimestamp(parts[0])
+		endFields := strings.Fields(parts[1])
+		if err1 != nil || len(endFields) == 0 {
+			continue
+		}
+		end, err2 := parseTimestamp(endFields[0])
+		if err2 != nil {
+			continue
+		}
+
+		cueText := strings.TrimSpace(strings.Join(lines[timingIdx+1:], "\n"))
+		if cueText == "" {
+			continue
+		}
+		cues = append(cues, cue{start: start, end: end, text: cueText})
+	}
+	return cues
+}
+
+// parseAss 解析 ass 字幕中的 Dialogue, 去除所有样式
+func parseAss(text string) []cue {
+	cues := make([]cue, 0)
+	inEvents := false
+	fields := []string{"layer", "start", "end", "style", "name", "marginl", "marginr", "marginv", "effect", "text"}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "format":
+			fields = fields[:0]
+			for _, f := range strings.Split(value, ",") {
+				fields = append(fields, strings.ToLower(strings.TrimSpace(f)))
+			}
+		case "dialogue":
+			values := strings.SplitN(value, ",", len(fields))
+			if len(values) != len(fields) {
+				continue
+			}
+			var c cue
+			var err error
+			for i, f := range fields {
+				v := strings.TrimSpace(values[i])
+				switch f {
+				case "start":
+					c.start, err = parseTimestamp(v)
+				case "end":
+					c.end, err = parseTimestamp(v)
+				case "text":
+					c.text = cleanAssText(values[i])
+				}
+				if err != nil {
+					break
+				}
+			}
+			if err != nil || c.text == "" {
+				continue
+			}
+			cues = append(cues, c)
+		}
+	}
+
+	slices.SortStableFunc(cues, func(a, b cue) int {
+		return int(a.start - b.start)
+	})
+	return cues
+}
+
+// cleanAssText 去除 ass 文本中的样式标签并转换换行符
+func cleanAssText(text string) string {
+	text = assTagReg.ReplaceAllString(text, "")
+	text = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// parseTimestamp 解析字幕时间戳
+//
+// 兼容 hh:mm:ss,mmm (srt), [hh:]mm:ss.mmm (vtt), h:mm:ss.cc (ass)
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	hms, frac, _ := strings.Cut(strings.ReplaceAll(s, ",", "."), ".")
+	parts := strings.Split(hms, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("非法的时间戳: %s", s)
+	}
+
+	var total time.Duration
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("非法的时间戳: %s", s)
+		}
+		total = total*60 + time.Duration(n)*time.Second
+	}
+
+	if frac != "" {
+		// 按小数处理, 兼容两位 (厘秒) 和三位 (毫秒)
+		if len(frac) > 3 {
+			frac = frac[:3]
+		}
+		n, err := strconv.Atoi(frac + strings.Repeat("0", 3-len(frac)))
+		if err != nil {
+			return 0, fmt.Errorf("非法的时间戳: %s", s)
+		}
+		total += time.Duration(n) * time.Millisecond
+	}
+	return total, nil
+}
+
+// formatTimestamp 格式化时间戳, sep 为秒与毫秒之间的分隔符
+func formatTimestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// writeVtt 输出 vtt 字幕
+func writeVtt(cues []cue) string {
+	sb := strings.Builder{}
+	sb.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		sb.WriteString(formatTimestamp(c.start, "."))
+		sb.WriteString(" --> ")
+		sb.WriteString(formatTimestamp(c.end, "."))
+		sb.WriteString("\n")
+		sb.WriteString(c.text)
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// writeSrt 输出 srt 字幕
+func writeSrt(cues []cue) string {
+	sb := strings.Builder{}
+	for i, c := range cues {
+		sb.WriteString(strconv.Itoa(i + 1))
+		sb.WriteString("\n")
+		sb.WriteString(formatTimestamp(c.start, ","))
+		sb.WriteString(" --> ")
+		sb.WriteString(formatTimestamp(c.end, ","))
+		sb.WriteString("\n")
+		sb.WriteString(c.text)
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package subtitles
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Format 字幕格式
+type Format string
+
+const (
+	FormatUnknown Format = ""
+	FormatSrt     Format = "srt"
+	FormatVtt     Format = "vtt"
+	FormatAss     Format = "ass"
+)
+
+// ContentTypes 字幕格式对应的响应类型
+var ContentTypes = map[Format]string{
+	FormatSrt: "application/x-subrip; charset=utf-8",
+	FormatVtt: "text/vtt; charset=utf-8",
+	FormatAss: "text/x-ssa; charset=utf-8",
+}
+
+// srtTimingReg 匹配 srt 的时间轴
+var srtTimingReg = regexp.MustCompile(`\d{1,2}:\d{2}:\d{2},\d{3}\s*-->`)
+
+// fallbackEncodings 非 UTF-8 字幕尝试的编码, common 判断双字节字符是否位于该编码的常用字符区
+var fallbackEncodings = []struct {
+	name   string
+	enc    encoding.Encoding
+	common func(lead, trail byte) bool
+}{
+	{"gbk", simplifiedchinese.GBK, gbkCommon},
+	{"big5", traditionalchinese.Big5, big5Common},
+	{"gb18030", simplifiedchinese.GB18030, gbkCommon},
+}
+
+// ToUTF8 检测字幕的编码并转换为 UTF-8, 同时返回检测到的编码名称
+//
+// 依次检测 BOM、UTF-8, 否则在常见中文编码中选择最合理的一个:
+// 以常用字符数减去解码错误数作为得分, 得分相同时优先靠前的编码
+//
+// GBK 与 Big5 的编码空间大量重叠, 互相解码时很少出错, 但得到的多为生僻字,
+// 因此不能只比较解码错误数
+func ToUTF8(data []byte) ([]byte, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return data[3:], "utf-8"
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decode(unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), data, "utf-16le")
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decode(unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), data, "utf-16be")
+	case utf8.Valid(data):
+		return data, "utf-8"
+	}
+
+	var best []byte
+	bestName, bestScore := "", 0
+	for _, fe := range fallbackEncodings {
+		res, err := fe.enc.NewDecoder().Bytes(data)
+		if err != nil {
+			continue
+		}
+		bad := bytes.Count(res, []byte(string(utf8.RuneError)))
+		score := countCommon(data, fe.common) - bad
+		if best == nil || score > bestScore {
+			best, bestName, bestScore = res, fe.name, score
+		}
+	}
+	if best == nil {
+		return data, "unknown"
+	}
+	return best, bestName
+}
+
+// countCommon 统计数据中位于常用字符区的双字节字符数量
+func countCommon(data []byte, common func(lead, trail byte) bool) int {
+	cnt := 0
+	for i := 0; i < len(data); i++ {
+		if data[i] < 0x80 || i+1 >= len(data) {
+			continue
+		}
+		if common(data[i], data[i+1]) {
+			cnt++
+		}
+		i++
+	}
+	return cnt
+}
+
+// gbkCommon 判断是否位于 GB2312 的符号区或一级汉字区
+func gbkCommon(lead, trail byte) bool {
+	if trail < 0xA1 || trail > 0xFE {
+		return false
+	}
+	return (lead >= 0xA1 && lead <= 0xA3) || (lead >= 0xB0 && lead <= 0xD7)
+}
+
+// big5Common 判断是否位于 Big5 的符号区或常用字区 (0xA140 ~ 0xC67E)
+func big5Common(lead, trail byte) bool {
+	if !(trail >= 0x40 && trail <= 0x7E) && !(trail >= 0xA1 && trail <= 0xFE) {
+		return false
+	}
+	return (lead >= 0xA1 && lead <= 0xC5) || (lead == 0xC6 && trail <= 0x7E)
+}
+
+// decode 使用指定编码解码, 失败时返回原始数据
+func decode(enc encoding.Encoding, data []byte, name string) ([]byte, string) {
+	res, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return data, "unknown"
+	}
+	return res, name
+}
+
+// DetectFormat 根据字幕内容检测字幕格式
+func DetectFormat(text string) Format {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(text, "\uFEFF"))
+	switch {
+	case strings.HasPrefix(trimmed, "WEBVTT"):
+		return FormatVtt
+	case strings.Contains(trimmed, "[Script Info]"), strings.Contains(trimmed, "[Events]"):
+		return FormatAss
+	case srtTimingReg.MatchString(trimmed):
+		return FormatSrt
+	}
+	return FormatUnknown
+}
+
+// FormatOfName 根据文件名后缀获取字幕格式, 如 Stream.vtt
+func FormatOfName(name string) Format {
+	switch strings.ToLower(strings.TrimPrefix(path.Ext(name), ".")) {
+	case "srt", "subrip":
+		return FormatSrt
+	case "vtt", "webvtt":
+		return FormatVtt
+	case "ass", "ssa":
+		return FormatAss
+	}
+	return FormatUnknown
+}
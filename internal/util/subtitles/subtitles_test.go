@@ -0,0 +1,117 @@
+package subtitles_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/subtitles"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+const srtSample = "1\r\n00:00:01,000 --> 00:00:02,500\r\n你好\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\n世界\r\n"
+
+// big5Sample 繁体字幕, 按 GBK 解码同样不会出错, 只是得到的都是生僻字
+const big5Sample = "1\r\n00:00:01,000 --> 00:00:02,500\r\n對不起\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\n沒有問題 電影\r\n"
+
+func TestToUTF8(t *testing.T) {
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(srtSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	big5, err := traditionalchinese.Big5.NewEncoder().String(big5Sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantText string
+		wantEnc  string
+	}{
+		{name: "utf-8", data: []byte(srtSample), wantText: srtSample, wantEnc: "utf-8"},
+		{name: "utf-8 bom", data: append([]byte{0xEF, 0xBB, 0xBF}, srtSample...), wantText: srtSample, wantEnc: "utf-8"},
+		{name: "gbk", data: []byte(gbk), wantText: srtSample, wantEnc: "gbk"},
+		{name: "big5", data: []byte(big5), wantText: big5Sample, wantEnc: "big5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, enc := subtitles.ToUTF8(tt.data)
+			if enc != tt.wantEnc || string(res) != tt.wantText {
+				t.Errorf("ToUTF8() = (%q, %s), want (%q, %s)", res, enc, tt.wantText, tt.wantEnc)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want subtitles.Format
+	}{
+		{name: "srt", text: srtSample, want: subtitles.FormatSrt},
+		{name: "vtt", text: "\uFEFFWEBVTT\n\n00:01.000 --> 00:02.000\nhi\n", want: subtitles.FormatVtt},
+		{name: "ass", text: "[Script Info]\nTitle: test\n", want: subtitles.FormatAss},
+		{name: "unknown", text: "hello", want: subtitles.FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subtitles.DetectFormat(tt.text); got != tt.want {
+				t.Errorf("DetectFormat() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	ass := strings.Join([]string{
+		"[Script Info]",
+		"ScriptType: v4.00+",
+		"",
+		"[Events]",
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text",
+		`Dialogue: 0,0:00:03.00,0:00:04.00,Default,,0,0,0,,{\an8}世界, 你好`,
+		`Dialogue: 0,0:00:01.00,0:00:02.50,Default,,0,0,0,,{\fs20}你好\N第二行`,
+	}, "\n")
+
+	tests := []struct {
+		name string
+		text string
+		from subtitles.Format
+		to   subtitles.Format
+		want string
+	}{
+		{
+			name: "srt to vtt",
+			text: srtSample, from: subtitles.FormatSrt, to: subtitles.FormatVtt,
+			want: "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\n你好\n\n00:00:03.000 --> 00:00:04.000\n世界\n\n",
+		},
+		{
+			name: "vtt to srt",
+			text: "WEBVTT\n\nNOTE 注释\n\n00:01.000 --> 00:02.500 align:start\n你好\n",
+			from: subtitles.FormatVtt, to: subtitles.FormatSrt,
+			want: "1\n00:00:01,000 --> 00:00:02,500\n你好\n\n",
+		},
+		{
+			name: "ass to vtt",
+			text: ass, from: subtitles.FormatAss, to: subtitles.FormatVtt,
+			want: "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\n你好\n第二行\n\n00:00:03.000 --> 00:00:04.000\n世界, 你好\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := subtitles.Convert(tt.text, tt.from, tt.to)
+			if err != nil || got != tt.want {
+				t.Errorf("Convert() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := subtitles.Convert(srtSample, subtitles.FormatSrt, subtitles.FormatAss); err == nil {
+		t.Error("不支持转换为 ass")
+	}
+}
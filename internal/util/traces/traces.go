@@ -0,0 +1,137 @@
+// 链路追踪, 通过 OTLP 协议将 span 导出到本地 collector
+//
+// 未启用时使用 otel 默认的空实现, 创建 span 几乎没有开销
+package traces
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// TracerName 创建 span 使用的 tracer 名称
+const TracerName = "github.com/AmbitiousJun/go-emby2openlist"
+
+// Options 链路追踪配置
+type Options struct {
+	// Endpoint OTLP/HTTP 导出地址, 如 http://localhost:4318/v1/traces, 为空表示不启用
+	Endpoint string
+	// Headers 导出请求附加的请求头, 如 collector 的鉴权信息
+	Headers map[string]string
+	// ServiceName 上报的服务名称
+	ServiceName string
+	// SampleRatio 采样比例 0~1
+	SampleRatio float64
+}
+
+var (
+	// mu 保护 provider
+	mu sync.Mutex
+	// provider 当前生效的 TracerProvider, 未启用时为空
+	provider *sdktrace.TracerProvider
+)
+
+// Configure 应用链路追踪配置, 重复调用时会关闭旧的导出器
+func Configure(opts Options) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if provider != nil {
+		provider.Shutdown(context.Background())
+		provider = nil
+	}
+
+	if opts.Endpoint == "" {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+		return nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(opts.Endpoint),
+		otlptracehttp.WithHeaders(opts.Headers),
+	)
+	if err != nil {
+		return fmt.Errorf("创建 OTLP 导出器失败: %v", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", opts.ServiceName),
+	))
+	if err != nil {
+		return fmt.Errorf("创建服务信息失败: %v", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return nil
+}
+
+// Shutdown 导出剩余的 span 并关闭导出器
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		return nil
+	}
+	err := provider.Shutdown(ctx)
+	provider = nil
+	return err
+}
+
+// Start 创建一个内部 span, ctx 为空时作为根 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer 从请求头中提取上游的追踪信息, 创建一个服务端 span
+func StartServer(ctx context.Context, header http.Header, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	return otel.Tracer(TracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClient 创建一个客户端 span, 并将追踪信息写入请求头
+func StartClient(ctx context.Context, header http.Header, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := otel.Tracer(TracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+	return ctx, span
+}
+
+// End 结束 span, err 不为空时标记为失败
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// SetStatusCode 记录 http 响应码, 5xx 标记为失败
+func SetStatusCode(span trace.Span, code int) {
+	span.SetAttributes(attribute.Int("http.response.status_code", code))
+	if code >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(code))
+	}
+}
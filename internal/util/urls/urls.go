@@ -1,11 +1,11 @@
 package urls
 
 import (
-	"log"
 	"net/url"
 	"path/filepath"
 	"strings"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 )
 
@@ -69,7 +69,7 @@ func AppendArgs(rawUrl string, kvs ...string) string {
 
 	u, err := url.Parse(rawUrl)
 	if err != nil {
-		log.Printf("AppendUrlArgs 转换 rawUrl 时出现异常: %v", err)
+		logs.Warn("AppendUrlArgs 转换 rawUrl 时出现异常: %v", err)
 		return rawUrl
 	}
 
@@ -0,0 +1,177 @@
+// 访问日志, 记录每个请求的用户、资源以及重定向目标, 用于与 CDN 对账
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 访问日志格式
+const (
+	FormatCombined = "combined" // Apache Combined 格式, 末尾追加扩展字段
+	FormatJson     = "json"     // 每行一个 json 对象
+)
+
+// 存放在 gin 上下文中的访问日志字段
+const (
+	ginKeyItemId        = "accessLogItemId"
+	ginKeyMediaSourceId = "accessLogMediaSourceId"
+	ginKeyCdn           = "accessLogCdn"
+	ginKeyCdnPath       = "accessLogCdnPath"
+)
+
+// Entry 一条访问日志
+type Entry struct {
+	Time          time.Time `json:"time"`
+	ClientIp      string    `json:"client_ip"`
+	UserId        string    `json:"user_id,omitempty"`
+	ItemId        string    `json:"item_id,omitempty"`
+	MediaSourceId string    `json:"media_source_id,omitempty"`
+	Route         string    `json:"route"`
+	Method        string    `json:"method"`
+	Uri           string    `json:"uri"`
+	Proto         string    `json:"proto"`
+	Status        int       `json:"status"`
+	Bytes         int       `json:"bytes"`
+	LatencyMs     int64     `json:"latency_ms"`
+	Referer       string    `json:"referer,omitempty"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Cdn           string    `json:"cdn,omitempty"`
+	CdnPath       string    `json:"cdn_path,omitempty"`
+}
+
+// SetItem 记录请求对应的 item id 和 MediaSourceId, 覆盖从 uri 中解析的结果
+func SetItem(c *gin.Context, itemId, mediaSourceId string) {
+	if c == nil {
+		return
+	}
+	if strs.AllNotEmpty(itemId) {
+		c.Set(ginKeyItemId, itemId)
+	}
+	if strs.AllNotEmpty(mediaSourceId) {
+		c.Set(ginKeyMediaSourceId, mediaSourceId)
+	}
+}
+
+// SetRedirect 记录重定向到的 CDN 名称以及 CDN 上的资源路径 (不含签名)
+func SetRedirect(c *gin.Context, cdn, cdnPath string) {
+	if c == nil {
+		return
+	}
+	c.Set(ginKeyCdn, cdn)
+	c.Set(ginKeyCdnPath, cdnPath)
+}
+
+// Middleware 访问日志中间件, 请求处理完成后写入一条访问日志
+//
+// routeKey 为路由匹配结果在 gin 上下文中的 key
+func Middleware(w io.Writer, format, routeKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		e := newEntry(c, start, routeKey)
+		var line []byte
+		if format == FormatCombined {
+			line = []byte(e.Combined() + "\n")
+		} else {
+			bytes, err := json.Marshal(e)
+			if err != nil {
+				logs.Warn("访问日志序列化失败: %v", err)
+				return
+			}
+			line = append(bytes, '\n')
+		}
+		if _, err := w.Write(line); err != nil {
+			logs.Warn("访问日志写入失败: %v", err)
+		}
+	}
+}
+
+// userPathReg 从请求路径中提取用户 id
+var userPathReg = regexp.MustCompile(`(?i)/users/([^/?]+)`)
+
+// itemPathReg 从请求路径中提取 item id
+var itemPathReg = regexp.MustCompile(`(?i)/(?:items|videos|audio)/([^/?]+)/`)
+
+// newEntry 根据请求上下文生成访问日志
+func newEntry(c *gin.Context, start time.Time, routeKey string) Entry {
+	e := Entry{
+		Time:      start,
+		ClientIp:  c.ClientIP(),
+		Route:     c.GetString(routeKey),
+		Method:    c.Request.Method,
+		Uri:       logs.Redact(c.Request.RequestURI),
+		Proto:     c.Request.Proto,
+		Status:    c.Writer.Status(),
+		Bytes:     max(c.Writer.Size(), 0),
+		LatencyMs: time.Since(start).Milliseconds(),
+		Referer:   c.Request.Referer(),
+		UserAgent: c.Request.UserAgent(),
+		Cdn:       c.GetString(ginKeyCdn),
+		CdnPath:   c.GetString(ginKeyCdnPath),
+	}
+
+	e.UserId = queryIgnoreCase(c, "UserId")
+	if matches := userPathReg.FindStringSubmatch(c.Request.URL.Path); len(matches) > 1 {
+		e.UserId = matches[1]
+	}
+
+	e.ItemId = c.GetString(ginKeyItemId)
+	if e.ItemId == "" {
+		if matches := itemPathReg.FindStringSubmatch(c.Request.URL.Path); len(matches) > 1 && matches[1] != "counts" {
+			e.ItemId = matches[1]
+		}
+	}
+
+	e.MediaSourceId = c.GetString(ginKeyMediaSourceId)
+	if e.MediaSourceId == "" {
+		e.MediaSourceId = queryIgnoreCase(c, "MediaSourceId")
+	}
+	return e
+}
+
+// queryIgnoreCase 忽略大小写获取 query 参数
+func queryIgnoreCase(c *gin.Context, key string) string {
+	for k, values := range c.Request.URL.Query() {
+		if strings.EqualFold(k, key) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// Combined 以 Apache Combined 格式输出, 末尾追加扩展字段
+//
+//	ip - user [time] "method uri proto" status bytes "referer" "ua" route item source cdn "cdn_path" latency_ms
+func (e Entry) Combined() string {
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %d "%s" "%s" %s %s %s %s "%s" %d`,
+		e.ClientIp, dash(e.UserId), e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		e.Method, e.Uri, e.Proto, e.Status, e.Bytes,
+		quote(e.Referer), quote(e.UserAgent),
+		dash(e.Route), dash(e.ItemId), dash(e.MediaSourceId), dash(e.Cdn), quote(e.CdnPath),
+		e.LatencyMs,
+	)
+}
+
+// dash 空字段输出为 -
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, " ", "%20")
+}
+
+// quote 转义双引号字段中的引号
+func quote(s string) string {
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
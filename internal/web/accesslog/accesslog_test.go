@@ -0,0 +1,67 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serve 使用访问日志中间件处理一个请求, 返回写入的日志
+func serve(t *testing.T, format, uri string, handler gin.HandlerFunc) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("matchRoute", "resource-stream") })
+	r.Use(Middleware(&buf, format, "matchRoute"))
+	r.Any("/*vars", handler)
+
+	req := httptest.NewRequest(http.MethodGet, uri, nil)
+	req.Header.Set("User-Agent", "Infuse")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return buf.String()
+}
+
+// TestMiddlewareJson 测试 json 格式的重定向日志
+func TestMiddlewareJson(t *testing.T) {
+	out := serve(t, FormatJson, "/emby/videos/123/stream.mkv?MediaSourceId=ms1&UserId=u1&api_key=secret", func(c *gin.Context) {
+		SetRedirect(c, "main", "/series/a.mkv")
+		c.Redirect(http.StatusFound, "https://cdn.example.com/series/a.mkv?sign=xxx")
+	})
+
+	var e Entry
+	if err := json.Unmarshal([]byte(out), &e); err != nil {
+		t.Fatalf("解析访问日志失败: %v, 日志: %s", err, out)
+	}
+	if e.UserId != "u1" || e.ItemId != "123" || e.MediaSourceId != "ms1" || e.Route != "resource-stream" {
+		t.Errorf("访问日志字段不符合预期: %+v", e)
+	}
+	if e.Status != http.StatusFound || e.Cdn != "main" || e.CdnPath != "/series/a.mkv" {
+		t.Errorf("重定向字段不符合预期: %+v", e)
+	}
+	if strings.Contains(e.Uri, "secret") {
+		t.Errorf("uri 中的 api_key 未脱敏: %s", e.Uri)
+	}
+}
+
+// TestMiddlewareCombined 测试 combined 格式
+func TestMiddlewareCombined(t *testing.T) {
+	out := serve(t, FormatCombined, "/emby/Users/u1/Items/456", func(c *gin.Context) {
+		SetItem(c, "789", "")
+		c.String(http.StatusOK, "ok")
+	})
+
+	for _, want := range []string{
+		` - u1 [`,
+		`"GET /emby/Users/u1/Items/456 HTTP/1.1" 200 2 "" "Infuse" resource-stream 789 - - ""`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("combined 日志中缺少 %q, 实际: %s", want, out)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package web
+
+import (
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/dashboard"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listenAdmin 在管理端口上监听运维接口
+//
+// /metrics 供 Prometheus 抓取, 不作鉴权; 管理端口异常不影响代理服务, 只输出错误日志
+func listenAdmin() {
+	r := newAdminEngine()
+	addr := config.C().Admin.Addr
+	logs.Info("在地址【%s】上启动管理服务", addr)
+	if err := r.Run(addr); err != nil {
+		logs.Error("管理服务异常: %v", err)
+	}
+}
+
+// newAdminEngine 初始化管理端口的路由
+//
+// 管理面板和播放统计报表只在配置了 admin.password 时注册, 并且需要登录后才能访问
+func newAdminEngine() *gin.Engine {
+	r := newEngine()
+	r.Use(gin.Recovery())
+	r.GET("/metrics", gin.WrapF(metrics.Default.Handler()))
+	if config.C().Admin.DashboardEnabled() {
+		initAnalyticsRoutes(r.Group("", dashboard.AuthChecker()))
+		dashboard.Register(r)
+		logs.Info("管理面板地址: http://%s/ui/", config.C().Admin.Addr)
+	} else {
+		logs.Tip("未配置 admin.password, 不启用管理面板和播放统计报表")
+	}
+	return r
+}
@@ -0,0 +1,38 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+// TestAdminAnalyticsAuth 测试播放统计报表只在配置了密码时提供, 并且需要登录
+func TestAdminAnalyticsAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     int
+	}{
+		{name: "未配置密码", password: "", want: http.StatusNotFound},
+		{name: "未登录", password: "secret", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := new(config.Network)
+			if err := network.Init(); err != nil {
+				t.Fatal(err)
+			}
+			old := config.C()
+			config.Set(&config.Config{Admin: &config.Admin{Enable: true, Password: tt.password}, Network: network})
+			t.Cleanup(func() { config.Set(old) })
+
+			w := httptest.NewRecorder()
+			newAdminEngine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/analytics/users", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/analytics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// initAnalyticsRoutes 注册播放统计报表接口
+//
+// 所有接口支持 days 参数指定统计最近多少天, 默认 7 天
+func initAnalyticsRoutes(r gin.IRouter) {
+	g := r.Group("/api/analytics")
+	g.GET("/top-items", analyticsReport(func(s *analytics.Store, since time.Time, c *gin.Context) (any, error) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		return s.TopItems(since, limit)
+	}))
+	g.GET("/users", analyticsReport(func(s *analytics.Store, since time.Time, _ *gin.Context) (any, error) {
+		return s.UserWatchTime(since)
+	}))
+	g.GET("/cdns", analyticsReport(func(s *analytics.Store, since time.Time, _ *gin.Context) (any, error) {
+		return s.CdnTraffic(since)
+	}))
+	g.GET("/errors", analyticsReport(func(s *analytics.Store, since time.Time, _ *gin.Context) (any, error) {
+		return s.CdnErrors(since)
+	}))
+}
+
+// analyticsReport 解析统计时间范围, 并以 json 返回报表
+func analyticsReport(report func(s *analytics.Store, since time.Time, c *gin.Context) (any, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		store, err := analytics.Default()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days 参数必须为正整数"})
+			return
+		}
+		since := time.Now().AddDate(0, 0, -days)
+
+		res, err := report(store, since, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"days": days, "since": since, "data": res})
+	}
+}
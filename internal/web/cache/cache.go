@@ -4,17 +4,16 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"regexp"
 	"strings"
 	"time"
 
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/encrypts"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/urls"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
 
 	"github.com/gin-gonic/gin"
 )
@@ -44,22 +43,25 @@ var CacheKeyIgnoreParams = map[string]struct{}{
 	"Via": {}, "Forwarded-For": {}, "X-From-Cdn": {},
 }
 
+// CacheKeyTagGinKey 附加到 cache key 中的标识在 gin 上下文中的 key
+//
+// 用于区分请求参数完全一致, 但响应结果与客户端相关的请求
+const CacheKeyTagGinKey = "cacheKeyTag"
+
 // CacheableRouteMarker 缓存白名单
-// 只有匹配上正则表达式的路由才会被缓存
+// 只有白名单中的路由才会被缓存
 func CacheableRouteMarker() gin.HandlerFunc {
-	cacheablePatterns := []*regexp.Regexp{
-		regexp.MustCompile(constant.Reg_PlaybackInfo),
-		regexp.MustCompile(constant.Reg_VideoSubtitles),
-		regexp.MustCompile(constant.Reg_ResourceStream),
-		regexp.MustCompile(constant.Reg_ItemDownload),
-		regexp.MustCompile(constant.Reg_ItemSyncDownload),
+	cacheableRoutes := map[routes.Id]struct{}{
+		routes.PlaybackInfo:     {},
+		routes.VideoSubtitles:   {},
+		routes.ResourceStream:   {},
+		routes.ItemDownload:     {},
+		routes.ItemSyncDownload: {},
 	}
 
 	return func(c *gin.Context) {
-		for _, pattern := range cacheablePatterns {
-			if pattern.MatchString(c.Request.RequestURI) {
-				return
-			}
+		if _, ok := cacheableRoutes[routes.IdOf(c)]; ok {
+			return
 		}
 		c.Header(HeaderKeyExpired, "-1")
 	}
@@ -83,6 +85,10 @@ func RequestCacher() gin.HandlerFunc {
 
 		// 3 尝试获取缓存
 		if rc, ok := getCache(cacheKey); ok {
+			recordLookup(rc.header.space, true)
+			for _, hook := range hitHooks {
+				hook(c, rc.header.meta)
+			}
 			if https.IsRedirectCode(rc.code) {
 				// 适配重定向请求
 				c.Redirect(rc.code, rc.header.header.Get("Location"))
@@ -101,6 +107,7 @@ func RequestCacher() gin.HandlerFunc {
 
 		// 5 执行请求处理器
 		c.Next()
+		recordLookup(c.Writer.Header().Get(HeaderKeySpace), false)
 
 		// 6 不缓存错误请求
 		if https.IsErrorStatus(c.Writer.Status()) {
@@ -114,6 +121,7 @@ func RequestCacher() gin.HandlerFunc {
 			space:    header.Get(HeaderKeySpace),
 			spaceKey: header.Get(HeaderKeySpaceKey),
 			header:   header.Clone(),
+			meta:     metaOf(c),
 		}
 		defer header.Del(HeaderKeyExpired)
 		defer header.Del(HeaderKeySpace)
@@ -181,6 +189,6 @@ func calcCacheKey(c *gin.Context) (string, error) {
 		c.Request.URL.RawQuery, "",
 	)
 
-	hash := encrypts.Md5Hash(method + uriNoArgs + preEnc)
+	hash := encrypts.Md5Hash(method + uriNoArgs + preEnc + c.GetString(CacheKeyTagGinKey))
 	return hash, nil
 }
@@ -31,7 +31,7 @@ var currentCacheSize int64 = 0
 // DefaultExpired 默认的请求过期时间
 //
 // 可通过设置 "Expired" 响应头进行覆盖
-var DefaultExpired = func() time.Duration { return config.C.Cache.ExpiredDuration() }
+var DefaultExpired = func() time.Duration { return config.C().Cache.ExpiredDuration() }
 
 // cacheMap 存放缓存数据的 map
 var cacheMap = sync.Map{}
@@ -46,6 +46,9 @@ var preCacheChan = make(chan *respCache, MaxCacheNum)
 // cacheHandleWaitGroup 允许等待预缓存通道处理完毕后再获取数据
 var cacheHandleWaitGroup = sync.WaitGroup{}
 
+// purgeChan 清空缓存请求通道, 清空完成后通过传入的通道返回清空的缓存数量
+var purgeChan = make(chan chan int)
+
 func init() {
 	go loopMaintainCache()
 }
@@ -88,6 +91,20 @@ func loopMaintainCache() {
 		}
 	}
 
+	// purgeAll 清空所有缓存
+	purgeAll := func() int {
+		cnt := 0
+		cacheMap.Range(func(key, value any) bool {
+			rc := value.(*respCache)
+			cacheMap.Delete(key)
+			delSpaceCache(rc.header.space, rc.header.spaceKey)
+			cnt++
+			return true
+		})
+		currentCacheSize = 0
+		return cnt
+	}
+
 	timer := time.NewTicker(time.Second * 10)
 	defer timer.Stop()
 	for {
@@ -95,12 +112,21 @@ func loopMaintainCache() {
 		case rc := <-preCacheChan:
 			putrespCache(rc)
 			cacheHandleWaitGroup.Done()
+		case done := <-purgeChan:
+			done <- purgeAll()
 		case <-timer.C:
 			cleanCache()
 		}
 	}
 }
 
+// Purge 清空所有缓存, 返回清空的缓存数量
+func Purge() int {
+	done := make(chan int, 1)
+	purgeChan <- done
+	return <-done
+}
+
 // getCache 根据 cacheKey 获取缓存
 func getCache(cacheKey string) (*respCache, bool) {
 	if c, ok := cacheMap.Load(cacheKey); ok {
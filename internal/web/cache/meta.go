@@ -0,0 +1,39 @@
+package cache
+
+import "github.com/gin-gonic/gin"
+
+// metaGinKey 需要随缓存一起保存的元数据在 gin 上下文中的 key
+const metaGinKey = "cacheMeta"
+
+// hitHooks 命中缓存时的回调
+var hitHooks []func(c *gin.Context, meta map[string]string)
+
+// SetMeta 记录需要随缓存一起保存的元数据
+//
+// 命中缓存时处理器不会执行, 处理器中记录的信息 (如重定向到的 CDN) 可以通过元数据保存,
+// 再由 OnHit 注册的回调恢复
+func SetMeta(c *gin.Context, key, value string) {
+	if c == nil {
+		return
+	}
+	meta, ok := c.Get(metaGinKey)
+	if !ok {
+		meta = map[string]string{}
+		c.Set(metaGinKey, meta)
+	}
+	meta.(map[string]string)[key] = value
+}
+
+// OnHit 注册命中缓存时的回调, 回调在响应之前执行, 需要在 init 中注册
+func OnHit(fn func(c *gin.Context, meta map[string]string)) {
+	hitHooks = append(hitHooks, fn)
+}
+
+// metaOf 取出请求记录的元数据
+func metaOf(c *gin.Context) map[string]string {
+	meta, ok := c.Get(metaGinKey)
+	if !ok {
+		return nil
+	}
+	return meta.(map[string]string)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"sort"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/strs"
+)
+
+// noSpace 不属于任何缓存空间的缓存在指标中的空间名称
+const noSpace = "none"
+
+// cacheLookups 各缓存空间的命中情况
+var cacheLookups = metrics.NewCounter("ge2o_cache_lookups_total", "按缓存空间统计的缓存命中情况", "space", "result")
+
+func init() {
+	metrics.NewGaugeFunc("ge2o_cache_size_bytes", "按缓存空间统计的缓存响应体大小", []string{"space"}, func(set func(float64, ...string)) {
+		sizes, _ := spaceStats()
+		for space, size := range sizes {
+			set(float64(size), space)
+		}
+	})
+	metrics.NewGaugeFunc("ge2o_cache_entries", "按缓存空间统计的缓存数量", []string{"space"}, func(set func(float64, ...string)) {
+		_, counts := spaceStats()
+		for space, count := range counts {
+			set(float64(count), space)
+		}
+	})
+}
+
+// recordLookup 记录一次缓存查找结果
+func recordLookup(space string, hit bool) {
+	if strs.AnyEmpty(space) {
+		space = noSpace
+	}
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.Inc(space, result)
+}
+
+// SpaceStat 缓存空间统计信息
+type SpaceStat struct {
+	Space   string `json:"space"`
+	Entries int    `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// Stats 统计各缓存空间的缓存数量和响应体大小, 按空间名称排序
+func Stats() []SpaceStat {
+	sizes, counts := spaceStats()
+	res := make([]SpaceStat, 0, len(counts))
+	for space, cnt := range counts {
+		res = append(res, SpaceStat{Space: space, Entries: cnt, Bytes: sizes[space]})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Space < res[j].Space })
+	return res
+}
+
+// spaceStats 统计各缓存空间的响应体大小和缓存数量
+func spaceStats() (sizes map[string]int64, counts map[string]int) {
+	sizes, counts = map[string]int64{}, map[string]int{}
+	cacheMap.Range(func(_, value any) bool {
+		rc := value.(*respCache)
+		space := rc.header.space
+		if strs.AnyEmpty(space) {
+			space = noSpace
+		}
+		sizes[space] += int64(len(rc.body))
+		counts[space]++
+		return true
+	})
+	return
+}
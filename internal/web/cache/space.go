@@ -29,6 +29,7 @@ func GetSpaceCache(space, spaceKey string) (RespCache, bool) {
 	}
 	s := getSpace(space)
 	rc, ok := getSpaceCache(s, spaceKey)
+	recordLookup(space, ok)
 	if !ok {
 		return nil, false
 	}
@@ -17,7 +17,10 @@ type respCacheWriter struct {
 }
 
 func (rcw *respCacheWriter) Write(b []byte) (int, error) {
-	rcw.body.Write(b)
+	// 已标记为不缓存的响应 (如流式传输), 无需暂存响应体
+	if rcw.Header().Get(HeaderKeyExpired) != "-1" {
+		rcw.body.Write(b)
+	}
 	return rcw.ResponseWriter.Write(b)
 }
 
@@ -45,10 +48,11 @@ type respCache struct {
 
 // respHeader 记录特定请求的缓存参数
 type respHeader struct {
-	expired  string      // 过期时间
-	space    string      // 缓存空间名称
-	spaceKey string      // 缓存空间 key
-	header   http.Header // 原始请求的克隆请求头
+	expired  string            // 过期时间
+	space    string            // 缓存空间名称
+	spaceKey string            // 缓存空间 key
+	header   http.Header       // 原始请求的克隆请求头
+	meta     map[string]string // 处理器记录的元数据, 命中缓存时恢复
 }
 
 // Code 响应码
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"net/url"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// customRules 将配置中的自定义路由转换为路由规则
+func customRules() [][2]any {
+	rs := make([][2]any, 0, len(config.C().Routes))
+	for _, rr := range config.C().Routes {
+		rs = append(rs, [2]any{rr.Pattern, customRouteHandler(rr)})
+	}
+	return rs
+}
+
+const (
+	// customRewrittenKey 请求路径已被自定义路由改写的标记在 gin 上下文中的 key
+	customRewrittenKey = "customRewritten"
+	// customMatchedKey 请求由自定义路由处理的标记在 gin 上下文中的 key
+	customMatchedKey = "customMatched"
+)
+
+// customRewriter 自定义路由改写中间件
+//
+// 在路由标记中间件之前执行, 第一个匹配的自定义路由为 rewrite 时改写请求路径,
+// 保证限流、鉴权、缓存等中间件看到的都是改写后的路由
+func customRewriter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uri := c.Request.RequestURI
+		for _, rr := range config.C().Routes {
+			if !rr.Match(uri) {
+				continue
+			}
+			if rr.Action != config.RouteActionRewrite {
+				return
+			}
+
+			newUri := rr.RewriteUri(uri)
+			u, err := url.ParseRequestURI(newUri)
+			if err != nil {
+				logs.Warn("自定义路由 [%s] 改写路径失败: %s, %v, 回源处理", rr.Pattern, newUri, err)
+				return
+			}
+			logs.Tip("自定义路由改写路径: %s => %s", uri, newUri)
+			c.Request.URL.Path = u.Path
+			c.Request.URL.RawPath = u.RawPath
+			c.Request.URL.RawQuery = u.RawQuery
+			c.Request.RequestURI = newUri
+			c.Set(MatchRouteKey, rr.Pattern)
+			c.Set(customRewrittenKey, true)
+			return
+		}
+	}
+}
+
+// customRouteHandler 根据自定义路由的处理动作生成处理器
+func customRouteHandler(rr *config.RouteRule) func(*gin.Context) {
+	switch rr.Action {
+	case config.RouteActionRedirectStrm:
+		return emby.Redirect2OpenlistLink
+	case config.RouteActionReject:
+		return func(c *gin.Context) {
+			logs.Warn("自定义路由 [%s] 拒绝请求: %s", rr.Pattern, c.Request.RequestURI)
+			c.AbortWithStatus(rr.Status)
+		}
+	case config.RouteActionStatic:
+		return func(c *gin.Context) {
+			for key, value := range rr.Headers {
+				c.Header(key, value)
+			}
+			c.Data(rr.Status, rr.ContentType, []byte(rr.Body))
+		}
+	case config.RouteActionRewrite:
+		// 路径改写由 customRewriter 中间件提前完成, 执行到这里说明改写失败, 回源处理
+		return emby.ProxyOrigin
+	default:
+		return emby.ProxyOrigin
+	}
+}
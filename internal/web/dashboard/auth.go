@@ -0,0 +1,121 @@
+package dashboard
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/limiters"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookie 登录会话 cookie 名称
+const SessionCookie = "e2o_admin_session"
+
+// SessionTtl 登录会话有效期
+const SessionTtl = 12 * time.Hour
+
+// secret 签名会话使用的密钥, 每次启动随机生成, 重启后需要重新登录
+var secret = func() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("生成管理面板会话密钥失败: " + err.Error())
+	}
+	return b
+}()
+
+// loginLimiter 按客户端 ip 限制登录尝试次数, 允许连续尝试 5 次, 之后每分钟 1 次
+var loginLimiter = limiters.NewKeyed(5, 1.0/60)
+
+// checkPassword 校验登录用户名和密码
+func checkPassword(username, password string) bool {
+	a := config.C().Admin
+	userOk := subtle.ConstantTimeCompare([]byte(username), []byte(a.Username)) == 1
+	passOk := subtle.ConstantTimeCompare([]byte(password), []byte(a.Password)) == 1
+	return userOk && passOk
+}
+
+// signSession 生成在 expire 时刻过期的会话值, 格式: 过期时间戳.签名
+func signSession(expire time.Time) string {
+	ts := strconv.FormatInt(expire.Unix(), 10)
+	return ts + "." + sign(ts)
+}
+
+// verifySession 校验会话值的签名和有效期
+func verifySession(value string, now time.Time) bool {
+	ts, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	if !hmac.Equal([]byte(sig), []byte(sign(ts))) {
+		return false
+	}
+	expire, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	return now.Unix() < expire
+}
+
+// sign 使用会话密钥和当前密码对数据签名, 修改密码后旧会话自动失效
+func sign(data string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(config.C().Admin.Username + "\n" + config.C().Admin.Password + "\n"))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// login 校验用户名密码, 通过后下发会话 cookie
+func login(c *gin.Context) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误"})
+		return
+	}
+	if ok, wait := loginLimiter.Take(c.ClientIP()); !ok {
+		logs.Warn("管理面板登录尝试过于频繁, ip: %s", c.ClientIP())
+		c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "登录尝试过于频繁, 请稍后重试"})
+		return
+	}
+	if !checkPassword(req.Username, req.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户名或密码错误"})
+		return
+	}
+	expire := time.Now().Add(SessionTtl)
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookie, signSession(expire), int(SessionTtl.Seconds()), "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{"expire": expire})
+}
+
+// logout 清除会话 cookie
+func logout(c *gin.Context) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookie, "", -1, "/", "", false, true)
+	c.Status(http.StatusNoContent)
+}
+
+// AuthChecker 校验登录会话, 未登录的请求返回 401
+func AuthChecker() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, err := c.Cookie(SessionCookie)
+		if err != nil || !verifySession(value, time.Now()) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未登录或登录已过期"})
+			return
+		}
+		c.Next()
+	}
+}
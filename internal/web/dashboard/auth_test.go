@@ -0,0 +1,134 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/limiters"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupAdmin 设置测试使用的管理面板账号
+func setupAdmin(t *testing.T) {
+	t.Helper()
+	old := config.C()
+	config.Set(&config.Config{Admin: &config.Admin{Enable: true, Username: "admin", Password: "secret"}})
+	t.Cleanup(func() { config.Set(old) })
+}
+
+// TestVerifySession 测试会话签名校验
+func TestVerifySession(t *testing.T) {
+	setupAdmin(t)
+	now := time.Now()
+	valid := signSession(now.Add(time.Hour))
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"有效会话", valid, true},
+		{"已过期", signSession(now.Add(-time.Second)), false},
+		{"篡改过期时间", "9999999999" + valid[strings.Index(valid, "."):], false},
+		{"缺少签名", "9999999999", false},
+		{"空值", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifySession(tt.value, now); got != tt.want {
+				t.Errorf("verifySession(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	// 修改密码后旧会话失效
+	config.C().Admin.Password = "changed"
+	if verifySession(valid, now) {
+		t.Error("修改密码后旧会话仍然有效")
+	}
+}
+
+// TestLogin 测试登录后才能访问数据接口
+func TestLogin(t *testing.T) {
+	setupAdmin(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r)
+
+	do := func(method, path, body string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodGet, "/api/dashboard/localtree", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("未登录访问接口, 响应码: %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/api/dashboard/login", `{"username":"admin","password":"wrong"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("密码错误登录, 响应码: %d", w.Code)
+	}
+
+	w := do(http.MethodPost, "/api/dashboard/login", `{"username":"admin","password":"secret"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("登录失败, 响应码: %d, 响应: %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 || cookies[0].Name != SessionCookie || !cookies[0].HttpOnly {
+		t.Fatalf("登录后未下发会话 cookie: %v", cookies)
+	}
+	if w := do(http.MethodGet, "/api/dashboard/localtree", "", cookies...); w.Code != http.StatusOK {
+		t.Errorf("登录后访问接口, 响应码: %d", w.Code)
+	}
+
+	if w := do(http.MethodGet, "/ui/", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "app.js") {
+		t.Errorf("管理面板页面加载失败, 响应码: %d", w.Code)
+	}
+}
+
+// TestLoginLimit 测试同一 ip 频繁尝试登录时被限制
+func TestLoginLimit(t *testing.T) {
+	setupAdmin(t)
+	old := loginLimiter
+	loginLimiter = limiters.NewKeyed(2, 0.001)
+	t.Cleanup(func() { loginLimiter = old })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	Register(r)
+
+	login := func(ip, password string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/dashboard/login", strings.NewReader(`{"username":"admin","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":34567"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	tests := []struct {
+		name     string
+		ip       string
+		password string
+		want     int
+	}{
+		{"第 1 次错误密码", "203.0.113.7", "wrong", http.StatusUnauthorized},
+		{"第 2 次错误密码", "203.0.113.7", "wrong", http.StatusUnauthorized},
+		{"超出次数后正确密码也被限制", "203.0.113.7", "secret", http.StatusTooManyRequests},
+		{"其他 ip 不受影响", "198.51.100.9", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := login(tt.ip, tt.password); got != tt.want {
+			t.Errorf("%s: 响应码 = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// 管理面板, 内嵌在程序中的网页, 通过管理端口访问
+//
+// 配置了 admin.password 才会启用, 所有数据接口都需要先登录
+package dashboard
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/analytics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed static
+var staticFs embed.FS
+
+// Register 在管理端口上注册管理面板的页面和接口
+func Register(r gin.IRouter) {
+	sub, err := fs.Sub(staticFs, "static")
+	if err != nil {
+		panic("加载管理面板页面失败: " + err.Error())
+	}
+	r.GET("/ui", func(c *gin.Context) { c.Redirect(http.StatusFound, "/ui/") })
+	r.StaticFS("/ui/", http.FS(sub))
+
+	g := r.Group("/api/dashboard")
+	g.POST("/login", login)
+	g.POST("/logout", logout)
+
+	a := g.Group("", AuthChecker())
+	a.GET("/sessions", sessions)
+	a.GET("/redirects", func(c *gin.Context) { c.JSON(http.StatusOK, emby.RecentRedirects()) })
+	a.GET("/cdns", func(c *gin.Context) { c.JSON(http.StatusOK, emby.CdnHealths()) })
+	a.GET("/cache", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"enable": config.C().Cache.Enable, "spaces": cache.Stats()})
+	})
+	a.GET("/localtree", func(c *gin.Context) { c.JSON(http.StatusOK, localtree.CurrentStatus()) })
+	a.GET("/config", func(c *gin.Context) { c.JSON(http.StatusOK, summarize(config.C())) })
+
+	a.POST("/localtree/sync", triggerSync)
+	a.POST("/cache/purge", purgeCache)
+	a.POST("/config/reload", reloadConfig)
+}
+
+// sessions 返回进行中的播放会话, 未启用播放统计时返回空列表
+func sessions(c *gin.Context) {
+	store, err := analytics.Default()
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"enable": false, "sessions": []analytics.Session{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enable": true, "sessions": store.Active()})
+}
+
+// triggerSync 手动触发本地目录树同步
+func triggerSync(c *gin.Context) {
+	if err := localtree.TriggerSync(); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, localtree.ErrDisabled) {
+			code = http.StatusConflict
+		}
+		c.JSON(code, gin.H{"error": err.Error()})
+		return
+	}
+	logs.Info("管理面板触发本地目录树同步")
+	c.JSON(http.StatusAccepted, gin.H{"message": "已触发同步"})
+}
+
+// purgeCache 清空所有响应缓存
+func purgeCache(c *gin.Context) {
+	n := cache.Purge()
+	logs.Info("管理面板清空缓存, 共 %d 条", n)
+	c.JSON(http.StatusOK, gin.H{"purged": n})
+}
+
+// reloadConfig 重新加载配置文件
+func reloadConfig(c *gin.Context) {
+	if err := config.ReloadLogged("管理面板请求"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "配置已重新加载"})
+}
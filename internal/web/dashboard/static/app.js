@@ -0,0 +1,250 @@
+'use strict';
+
+// 数据刷新间隔, 单位: 毫秒
+const REFRESH_INTERVAL = 5000;
+const API = '/api/dashboard';
+
+let timer = null;
+
+// api 调用管理接口, 未登录时切换到登录页
+async function api(path, options = {}) {
+  const resp = await fetch(API + path, {
+    credentials: 'same-origin',
+    headers: { 'Content-Type': 'application/json' },
+    ...options,
+  });
+  if (resp.status === 401) {
+    showLogin();
+    throw new Error('未登录');
+  }
+  const data = resp.status === 204 ? null : await resp.json();
+  if (!resp.ok) {
+    throw new Error((data && data.error) || resp.statusText);
+  }
+  return data;
+}
+
+function el(tag, attrs = {}, ...children) {
+  const e = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs)) {
+    if (k === 'class') e.className = v;
+    else e.setAttribute(k, v);
+  }
+  for (const c of children) {
+    e.append(c instanceof Node ? c : String(c ?? ''));
+  }
+  return e;
+}
+
+// renderTable 渲染表格, columns 为 [表头, 取值函数] 列表
+function renderTable(id, columns, rows, emptyText = '暂无数据') {
+  const table = document.getElementById(id);
+  table.replaceChildren();
+  if (!rows || rows.length === 0) {
+    table.append(el('tr', {}, el('td', { class: 'empty' }, emptyText)));
+    return;
+  }
+  table.append(el('tr', {}, ...columns.map(([title]) => el('th', {}, title))));
+  for (const row of rows) {
+    table.append(el('tr', {}, ...columns.map(([, get, cls]) => {
+      const v = get(row);
+      return el('td', cls ? { class: cls } : {}, v);
+    })));
+  }
+}
+
+function renderDl(id, pairs) {
+  const dl = el('dl');
+  for (const [k, v] of pairs) {
+    dl.append(el('dt', {}, k), el('dd', {}, v));
+  }
+  document.getElementById(id).replaceChildren(dl);
+}
+
+function fmtTime(t) {
+  if (!t) return '-';
+  return new Date(t).toLocaleString();
+}
+
+function fmtBytes(n) {
+  if (!n) return '0 B';
+  const units = ['B', 'KB', 'MB', 'GB', 'TB'];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) { n /= 1024; i++; }
+  return n.toFixed(i === 0 ? 0 : 1) + ' ' + units[i];
+}
+
+function fmtTicks(ticks) {
+  const s = Math.floor((ticks || 0) / 1e7);
+  const h = Math.floor(s / 3600), m = Math.floor(s % 3600 / 60), sec = s % 60;
+  return [h, m, sec].map((x) => String(x).padStart(2, '0')).join(':');
+}
+
+function fmtDuration(ns) {
+  return ((ns || 0) / 1e9).toFixed(1) + 's';
+}
+
+function yesNo(b) {
+  return b ? '是' : '否';
+}
+
+async function loadSessions() {
+  const data = await api('/sessions');
+  const empty = data.enable ? '暂无播放' : '未启用播放统计 (analytics.enable)';
+  renderTable('sessions', [
+    ['用户', (s) => s.user_id || '-'],
+    ['条目', (s) => s.item_id],
+    ['版本', (s) => s.version || s.media_source_id || '-'],
+    ['CDN', (s) => s.cdn || '-'],
+    ['进度', (s) => fmtTicks(s.position_ticks) + ' / ' + fmtTicks(s.run_time_ticks)],
+    ['开始', (s) => fmtTime(s.start)],
+    ['最近上报', (s) => fmtTime(s.last_seen)],
+  ], data.sessions, empty);
+}
+
+async function loadCdns() {
+  const data = await api('/cdns');
+  renderTable('cdns', [
+    ['名称', (h) => h.cdn || '(未匹配)'],
+    ['状态', (h) => el('span', { class: 'status-' + h.status }, h.status)],
+    ['重定向', (h) => h.redirects],
+    ['错误', (h) => h.errors],
+    ['最近错误', (h) => h.last_error ? fmtTime(h.last_error) + ' ' + h.last_error_msg : '-', 'wrap'],
+  ], data);
+}
+
+async function loadCache() {
+  const data = await api('/cache');
+  renderTable('cache', [
+    ['空间', (s) => s.space || '(默认)'],
+    ['条目数', (s) => s.entries],
+    ['大小', (s) => fmtBytes(s.bytes)],
+  ], data.spaces, data.enable ? '暂无缓存' : '未启用缓存 (cache.enable)');
+}
+
+async function loadLocaltree() {
+  const s = await api('/localtree');
+  const box = document.getElementById('localtree');
+  if (!s.enable) {
+    box.replaceChildren(el('p', { class: 'empty' }, '未启用本地目录树 (openlist.local-tree-gen.enable)'));
+    return;
+  }
+  const pairs = [
+    ['状态', s.running ? '同步中' : '空闲'],
+    ['进度', s.running ? el('progress', { max: s.total || 1, value: s.finish }) : '-'],
+    ['任务', s.finish + ' / ' + s.total],
+    ['上次开始', fmtTime(s.last_start)],
+    ['上次耗时', fmtDuration(s.last_duration)],
+    ['文件总数', s.last_files],
+    ['新增 / 删除', s.last_added + ' / ' + s.last_deleted],
+  ];
+  if (s.last_error) {
+    pairs.push(['上次错误', el('span', { class: 'error' }, s.last_error)]);
+  }
+  renderDl('localtree', pairs);
+}
+
+async function loadConfig() {
+  const c = await api('/config');
+  const pairs = [
+    ['Emby', c.emby_host],
+    ['OpenList', c.openlist_host || '-'],
+    ['缓存', c.cache_enable ? '启用, ' + c.cache_expired : '未启用'],
+    ['本地目录树', yesNo(c.local_tree)],
+    ['限流', yesNo(c.rate_limit)],
+    ['访问日志', yesNo(c.access_log)],
+    ['播放统计', yesNo(c.analytics)],
+    ['链路追踪', yesNo(c.trace)],
+    ['SSL', yesNo(c.ssl)],
+  ];
+  for (const cdn of c.cdns || []) {
+    const flags = [cdn.type];
+    if (cdn.one_time_token) flags.push('一次性链接');
+    if (cdn.proxy_stream) flags.push('代理回传');
+    pairs.push(['CDN ' + cdn.name, cdn.base + ' (' + flags.join(', ') + ')\n' + (cdn.mappings || []).join('\n')]);
+  }
+  renderDl('config', pairs);
+  document.querySelectorAll('#config dd').forEach((dd) => { dd.style.whiteSpace = 'pre-line'; });
+}
+
+async function loadRedirects() {
+  const data = await api('/redirects');
+  renderTable('redirects', [
+    ['时间', (r) => fmtTime(r.time)],
+    ['CDN', (r) => r.cdn],
+    ['类型', (r) => r.kind],
+    ['客户端', (r) => r.client_ip],
+    ['远程路径', (r) => r.remote_path, 'wrap'],
+  ], data);
+}
+
+async function refresh() {
+  const results = await Promise.allSettled([
+    loadSessions(), loadCdns(), loadCache(), loadLocaltree(), loadConfig(), loadRedirects(),
+  ]);
+  const failed = results.find((r) => r.status === 'rejected');
+  if (failed && failed.reason.message !== '未登录') {
+    showMessage('刷新失败: ' + failed.reason.message, true);
+  }
+}
+
+function showMessage(text, isError = false) {
+  const m = document.getElementById('message');
+  m.textContent = text;
+  m.className = isError ? 'error' : '';
+}
+
+function showLogin() {
+  clearInterval(timer);
+  timer = null;
+  document.getElementById('app').hidden = true;
+  document.getElementById('login').hidden = false;
+}
+
+function showApp() {
+  document.getElementById('login').hidden = true;
+  document.getElementById('app').hidden = false;
+  refresh();
+  if (!timer) timer = setInterval(refresh, REFRESH_INTERVAL);
+}
+
+document.getElementById('login-form').addEventListener('submit', async (e) => {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  const errBox = document.getElementById('login-error');
+  errBox.textContent = '';
+  try {
+    await api('/login', {
+      method: 'POST',
+      body: JSON.stringify({ username: form.get('username'), password: form.get('password') }),
+    });
+    e.target.reset();
+    showApp();
+  } catch (err) {
+    errBox.textContent = err.message === '未登录' ? '用户名或密码错误' : err.message;
+  }
+});
+
+document.getElementById('logout').addEventListener('click', async () => {
+  await api('/logout', { method: 'POST' }).catch(() => {});
+  showLogin();
+});
+
+document.querySelectorAll('button[data-action]').forEach((btn) => {
+  btn.addEventListener('click', async () => {
+    if (btn.dataset.confirm && !confirm(btn.dataset.confirm)) return;
+    btn.disabled = true;
+    try {
+      const data = await api('/' + btn.dataset.action, { method: 'POST' });
+      showMessage(data.message || ('已清空 ' + data.purged + ' 条缓存'));
+      refresh();
+    } catch (err) {
+      showMessage(btn.textContent + '失败: ' + err.message, true);
+    } finally {
+      btn.disabled = false;
+    }
+  });
+});
+
+// 通过任意数据接口判断是否已登录
+api('/config').then(showApp).catch(() => showLogin());
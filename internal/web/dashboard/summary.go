@@ -0,0 +1,60 @@
+package dashboard
+
+import (
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+)
+
+// configSummary 管理面板展示的配置摘要, 不包含密钥等敏感信息
+type configSummary struct {
+	EmbyHost     string       `json:"emby_host"`
+	OpenlistHost string       `json:"openlist_host"`
+	Cdns         []cdnSummary `json:"cdns"`
+	CacheEnable  bool         `json:"cache_enable"`
+	CacheExpired string       `json:"cache_expired"`
+	LocalTree    bool         `json:"local_tree"`
+	RateLimit    bool         `json:"rate_limit"`
+	AccessLog    bool         `json:"access_log"`
+	Analytics    bool         `json:"analytics"`
+	Trace        bool         `json:"trace"`
+	Ssl          bool         `json:"ssl"`
+}
+
+// cdnSummary CDN 配置摘要
+type cdnSummary struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Base         string   `json:"base"`
+	Mappings     []string `json:"mappings"`
+	OneTimeToken bool     `json:"one_time_token"`
+	ProxyStream  bool     `json:"proxy_stream"`
+}
+
+// summarize 生成配置摘要
+func summarize(c *config.Config) configSummary {
+	s := configSummary{
+		EmbyHost:     c.Emby.Host,
+		OpenlistHost: c.Openlist.Host,
+		CacheEnable:  c.Cache.Enable,
+		CacheExpired: c.Cache.ExpiredDuration().String(),
+		LocalTree:    c.Openlist.LocalTreeGen.Enable,
+		RateLimit:    c.RateLimit.Enable,
+		AccessLog:    c.AccessLog.Enable,
+		Analytics:    c.Analytics.Enable,
+		Trace:        c.Trace.Enable,
+		Ssl:          c.Ssl.Enable,
+	}
+	for _, cdn := range c.Emby.Strm.Cdns {
+		cs := cdnSummary{
+			Name:         cdn.Name,
+			Type:         string(cdn.Type),
+			Base:         cdn.Base,
+			OneTimeToken: cdn.OneTimeToken,
+			ProxyStream:  cdn.ProxyStream,
+		}
+		for _, m := range cdn.PathMappings {
+			cs.Mappings = append(cs.Mappings, m.LocalPrefix+" => "+m.RemotePrefix)
+		}
+		s.Cdns = append(s.Cdns, cs)
+	}
+	return s
+}
@@ -6,12 +6,33 @@ import (
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
 	"github.com/gin-gonic/gin"
 )
 
 // MatchRouteKey 存储在 gin 上下文的路由匹配字段
 const MatchRouteKey = "matchRoute"
 
+// routeMarker 路由标记中间件
+//
+// 请求进入时只匹配一次内置路由, 将路由标识存放到 gin 上下文, 供后续中间件和处理器使用
+func routeMarker() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		markRoute(c)
+	}
+}
+
+// markRoute 匹配内置路由, 并将结果存放到 gin 上下文
+func markRoute(c *gin.Context) (*routes.Route, bool) {
+	route, ok := routes.Match(c.Request.RequestURI)
+	if !ok {
+		return nil, false
+	}
+	c.Set(routes.GinKey, route.Id)
+	c.Set(MatchRouteKey, route.Pattern)
+	return route, true
+}
+
 // globalDftHandler 全局默认兜底的请求处理器
 func globalDftHandler(c *gin.Context) {
 	if c.Request.Method == http.MethodHead {
@@ -19,16 +40,51 @@ func globalDftHandler(c *gin.Context) {
 		return
 	}
 
-	// 依次匹配路由规则, 找到其他的处理器
-	for _, rule := range rules {
+	if dispatchCustom(c) {
+		return
+	}
+	dispatchBuiltin(c)
+}
+
+// dispatchCustom 依次匹配自定义路由规则, 匹配成功返回 true
+func dispatchCustom(c *gin.Context) bool {
+	if c.GetBool(customRewrittenKey) {
+		// 已经改写过路径, 直接交由内置路由处理
+		return false
+	}
+	rules := customRouteRules.Load()
+	if rules == nil {
+		return false
+	}
+	for _, rule := range *rules {
 		reg := rule[0].(*regexp.Regexp)
 		if reg.MatchString(c.Request.RequestURI) {
 			c.Set(MatchRouteKey, reg.String())
+			c.Set(customMatchedKey, true)
 			c.Set(constant.RouteSubMatchGinKey, reg.FindStringSubmatch(c.Request.RequestURI))
 			rule[1].(gin.HandlerFunc)(c)
+			return true
+		}
+	}
+	return false
+}
+
+// dispatchBuiltin 根据路由标记中间件的匹配结果, 找到对应的内置处理器
+//
+// 上下文中没有匹配结果时 (如请求路径被改写), 重新匹配一次
+func dispatchBuiltin(c *gin.Context) {
+	var route *routes.Route
+	if id := routes.IdOf(c); id != "" {
+		route = routes.Get(id)
+	}
+	if route == nil {
+		var ok bool
+		if route, ok = markRoute(c); !ok {
 			return
 		}
 	}
+	c.Set(constant.RouteSubMatchGinKey, route.SubMatches(c.Request.RequestURI))
+	builtinHandlers[route.Id](c)
 }
 
 // compileRules 编译路由的正则表达式
@@ -7,6 +7,7 @@ import (
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/https"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
 	"github.com/gin-gonic/gin"
 )
@@ -19,17 +20,27 @@ func CustomLogger(port string) gin.HandlerFunc {
 		c.Next()
 
 		// 记录日志
-		fmt.Printf("%s %s | %s | %s | %s | %s %s | %s %s\n",
+		latency, status, route := time.Since(start), c.Writer.Status(), c.GetString(MatchRouteKey)
+		line := fmt.Sprintf("%s %s | %s | %s | %s | %s %s | %s %s",
 			colors.ToYellow("[ge2o:"+constant.CurrentVersion+"]"),
 			start.Format("2006-01-02 15:04:05"),
-			colorStatusCode(c.Writer.Status()),
-			time.Since(start),
+			colorStatusCode(status),
+			latency,
 			c.ClientIP(),
 			colors.ToBlue(port),
-			colors.ToBlue(c.GetString(MatchRouteKey)),
+			colors.ToBlue(route),
 			colors.ToBlue(c.Request.Method),
 			c.Request.RequestURI,
 		)
+		logs.Access(line,
+			"status", status,
+			"latency", latency,
+			"ip", c.ClientIP(),
+			"port", port,
+			"route", route,
+			"method", c.Request.Method,
+			"uri", c.Request.RequestURI,
+		)
 	}
 }
 
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/metrics"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	// requestsTotal 各路由的请求数
+	requestsTotal = metrics.NewCounter("ge2o_http_requests_total", "按匹配路由统计的请求数", "route", "method", "code")
+	// requestDuration 各路由的请求耗时
+	requestDuration = metrics.NewHistogram("ge2o_http_request_duration_seconds", "按匹配路由统计的请求耗时", nil, "route")
+)
+
+// metricMethods 指标中单独统计的请求方法, 其余方法统一记为 OTHER
+var metricMethods = map[string]struct{}{
+	http.MethodGet: {}, http.MethodHead: {}, http.MethodPost: {}, http.MethodPut: {},
+	http.MethodPatch: {}, http.MethodDelete: {}, http.MethodOptions: {},
+}
+
+// requestMetrics 请求指标采集中间件
+//
+// 需要放在路由标记中间件之后, 以取得匹配的路由
+func requestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := metricRoute(c)
+		requestsTotal.Inc(route, metricMethod(c.Request.Method), strconv.Itoa(c.Writer.Status()))
+		requestDuration.ObserveSince(start, route)
+	}
+}
+
+// metricRoute 获取指标的路由标签
+//
+// 只使用内置路由标识, 自定义路由统一记为 custom, 防止标签数量随配置无限增长
+func metricRoute(c *gin.Context) string {
+	if c.GetBool(customMatchedKey) {
+		return "custom"
+	}
+	if id := routes.IdOf(c); id != "" {
+		return string(id)
+	}
+	return "unknown"
+}
+
+// metricMethod 获取指标的请求方法标签, 防止客户端使用任意方法制造大量标签
+func metricMethod(method string) string {
+	if _, ok := metricMethods[method]; ok {
+		return method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/limiters"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitGroup 一组限流预算, 分别按客户端 ip 和 api_key 计数
+type limitGroup struct {
+	byIp     *limiters.Keyed
+	byApiKey *limiters.Keyed
+}
+
+// newLimitGroup 根据预算配置初始化限流组, 预算不限制时返回 nil
+func newLimitGroup(b *config.RateLimitBudget) *limitGroup {
+	if b == nil || b.Unlimited() {
+		return nil
+	}
+	return &limitGroup{
+		byIp:     limiters.NewKeyed(b.Burst, b.Refill),
+		byApiKey: limiters.NewKeyed(b.Burst, b.Refill),
+	}
+}
+
+// take 客户端 ip 和 api_key 都有可用令牌时才放行, 被拒绝时不消耗任何一方的令牌
+func (lg *limitGroup) take(ip, apiKey string) (bool, time.Duration) {
+	if lg == nil {
+		return true, 0
+	}
+	return limiters.TakeAll(lg.byIp.Bucket(ip), lg.byApiKey.Bucket(apiKey))
+}
+
+// limitLogs 限流日志的输出频率, 同一 ip 每分钟最多输出一条, 防止大量请求被拒绝时刷屏
+var limitLogs = limiters.NewKeyed(1, 1.0/60)
+
+// limitGroups 播放资源接口、图片接口与其余接口的限流组
+type limitGroups struct {
+	stream, images, def *limitGroup
+}
+
+// currentLimits 当前生效的限流组, 预算配置重新加载时整体替换
+var currentLimits atomic.Pointer[limitGroups]
+
+// resetLimits 根据配置重新初始化限流组, 已消耗的令牌会被重置
+func resetLimits(rl *config.RateLimit) {
+	currentLimits.Store(&limitGroups{
+		stream: newLimitGroup(rl.Stream),
+		images: newLimitGroup(rl.Images),
+		def:    newLimitGroup(rl.Default),
+	})
+}
+
+// rateLimiter 请求限流中间件
+//
+// 播放资源接口、图片接口与其余接口使用相互独立的预算,
+// 超出预算的请求返回 429, 并通过 Retry-After 告知客户端重试时间
+//
+// HTTP 与 HTTPS 服务共用同一组预算
+var rateLimiter = sync.OnceValue(func() gin.HandlerFunc {
+	resetLimits(config.C().RateLimit)
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if config.C().Network.IsTrusted(ip) {
+			return
+		}
+
+		groups := currentLimits.Load()
+		group := groups.def
+		uri := c.Request.RequestURI
+		switch routes.IdOf(c) {
+		case routes.ResourceStream:
+			group = groups.stream
+		case routes.Images:
+			group = groups.images
+		}
+
+		ok, wait := group.take(ip, emby.RequestApiKey(c))
+		if ok {
+			return
+		}
+
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		if ok, _ := limitLogs.Take(ip); ok {
+			logs.Warn("请求被限流, ip: %s, uri: %s, 建议重试间隔: %ds, 一分钟内不再输出该 ip 的限流日志", ip, uri, retryAfter)
+		}
+		c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		c.String(http.StatusTooManyRequests, "请求过于频繁, 请稍后重试")
+		c.Abort()
+	}
+})
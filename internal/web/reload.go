@@ -0,0 +1,27 @@
+package web
+
+import (
+	"reflect"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+)
+
+func init() {
+	config.OnReload(func(old, c *config.Config) {
+		// 自定义路由在请求时按顺序匹配, 直接整体替换
+		loadCustomRules()
+
+		// 限流预算变化时重建限流组
+		orl, nrl := old.RateLimit, c.RateLimit
+		if currentLimits.Load() == nil || !nrl.Enable {
+			return
+		}
+		if !reflect.DeepEqual(orl.Stream, nrl.Stream) ||
+			!reflect.DeepEqual(orl.Images, nrl.Images) ||
+			!reflect.DeepEqual(orl.Default, nrl.Default) {
+			resetLimits(nrl)
+			logs.Info("限流预算已更新")
+		}
+	})
+}
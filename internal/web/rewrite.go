@@ -0,0 +1,130 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/jsons"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRewriteSize 允许改写的最大响应大小, 超出则原样透传
+const maxRewriteSize = 10 * 1024 * 1024
+
+// rewriteWriter 暂存 json 响应体的响应器, 处理器执行完毕后再统一回写
+//
+// 首次写入响应时根据响应头判断是否暂存, 不是 json 或超出大小限制的响应直接透传给客户端
+type rewriteWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+	// decided 是否已经根据响应头判断过
+	decided bool
+	// buffering 是否正在暂存响应体
+	buffering bool
+}
+
+// decide 根据响应头判断是否需要暂存响应体
+func (rw *rewriteWriter) decide() {
+	if rw.decided {
+		return
+	}
+	rw.decided = true
+	header := rw.Header()
+	rw.buffering = rw.Status() == http.StatusOK &&
+		strings.Contains(header.Get("Content-Type"), "json") &&
+		header.Get("Content-Encoding") == ""
+}
+
+// passThrough 放弃暂存, 将已暂存的内容回写给客户端, 后续写入直接透传
+func (rw *rewriteWriter) passThrough() error {
+	rw.buffering = false
+	if rw.body.Len() == 0 {
+		return nil
+	}
+	_, err := rw.ResponseWriter.Write(rw.body.Bytes())
+	rw.body.Reset()
+	return err
+}
+
+func (rw *rewriteWriter) Write(b []byte) (int, error) {
+	rw.decide()
+	if rw.buffering && rw.body.Len()+len(b) > maxRewriteSize {
+		logs.Warn("响应大小超出改写限制, 原样透传")
+		if err := rw.passThrough(); err != nil {
+			return 0, err
+		}
+	}
+	if !rw.buffering {
+		return rw.ResponseWriter.Write(b)
+	}
+	return rw.body.Write(b)
+}
+
+func (rw *rewriteWriter) WriteString(s string) (int, error) {
+	return rw.Write([]byte(s))
+}
+
+// WriteHeaderNow 暂存期间不向客户端回写响应头
+func (rw *rewriteWriter) WriteHeaderNow() {
+	rw.decide()
+	if !rw.buffering {
+		rw.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Flush 暂存期间不向客户端刷新数据
+func (rw *rewriteWriter) Flush() {
+	rw.decide()
+	if !rw.buffering {
+		rw.ResponseWriter.Flush()
+	}
+}
+
+// responseRewriter 按配置的改写规则修改 emby 返回的 json 响应
+func responseRewriter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rules []*config.ResponseRewrite
+		for _, rr := range config.C().Emby.ResponseRewrites {
+			if rr.Matches(c.Request.Method, c.Request.RequestURI) {
+				rules = append(rules, rr)
+			}
+		}
+		if len(rules) == 0 {
+			return
+		}
+
+		// 需要读取明文响应
+		c.Request.Header.Del("Accept-Encoding")
+		rw := &rewriteWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = rw
+		c.Next()
+		c.Writer = rw.ResponseWriter
+		if !rw.buffering {
+			return
+		}
+		if rw.body.Len() == 0 {
+			c.Writer.WriteHeaderNow()
+			return
+		}
+
+		body := rw.body.Bytes()
+		if item, err := jsons.New(string(body)); err != nil {
+			logs.Warn("响应改写失败, 无法解析 json: %v", err)
+		} else {
+			for _, rr := range rules {
+				if count := rr.Apply(item); count > 0 {
+					logs.Tip("响应改写规则 [%s] 修改了 %d 个节点", rr.Name, count)
+				}
+			}
+			body = item.Bytes()
+		}
+
+		c.Writer.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		c.Writer.Write(body)
+	}
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestResponseRewriter 测试只暂存并改写 json 响应, 其余响应直接透传
+func TestResponseRewriter(t *testing.T) {
+	rr := &config.ResponseRewrite{Name: "test", Route: `.*`, Ops: []*config.RewriteOp{{Op: config.RewriteOpDelete, Path: "Path"}}}
+	if err := rr.Init(); err != nil {
+		t.Fatal(err)
+	}
+	old := config.C()
+	config.Set(&config.Config{Emby: &config.Emby{ResponseRewrites: []*config.ResponseRewrite{rr}}})
+	t.Cleanup(func() { config.Set(old) })
+
+	large := `{"Path":"` + strings.Repeat("a", maxRewriteSize) + `"}`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		flushed     bool
+		wantBody    string
+	}{
+		{name: "改写 json", contentType: "application/json", body: `{"Name":"a","Path":"/mnt/a"}`, wantBody: `{"Name":"a"}`},
+		{name: "透传非 json", contentType: "video/mp4", body: "0123456789", flushed: true, wantBody: "0123456789"},
+		{name: "超出大小透传", contentType: "application/json", body: large, wantBody: large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(responseRewriter())
+			r.GET("/", func(c *gin.Context) {
+				c.Header("Content-Type", tt.contentType)
+				c.Status(http.StatusOK)
+				c.Writer.Write([]byte(tt.body[:len(tt.body)/2]))
+				c.Writer.Flush()
+				c.Writer.Write([]byte(tt.body[len(tt.body)/2:]))
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := strings.TrimSpace(w.Body.String()); got != tt.wantBody {
+				t.Errorf("响应长度 %d, want %d, 响应前缀: %.40s", len(got), len(tt.wantBody), got)
+			}
+			if w.Flushed != tt.flushed {
+				t.Errorf("Flushed = %v, want %v", w.Flushed, tt.flushed)
+			}
+		})
+	}
+}
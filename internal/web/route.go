@@ -1,69 +1,100 @@
 package web
 
 import (
-	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"fmt"
+	"sync/atomic"
+
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
 
 	"github.com/gin-gonic/gin"
 )
 
-// rules 预定义路由拦截规则, 以及相应的处理器
+// customRouteRules 自定义路由规则, 以及相应的处理器, 优先于内置路由匹配
 //
-// 每个规则为一个切片, 参数分别是: 正则表达式, 处理器
-var rules [][2]any
+// 每个规则为一个切片, 参数分别是: 正则表达式, 处理器, 配置重新加载时整体替换
+var customRouteRules atomic.Pointer[[][2]any]
+
+// builtinHandlers 内置路由对应的处理器, 匹配顺序见 routes.Builtins
+var builtinHandlers map[routes.Id]gin.HandlerFunc
 
 func initRulePatterns() {
 	logs.Info("正在初始化路由规则...")
-	rules = compileRules([][2]any{
+	builtinHandlers = map[routes.Id]gin.HandlerFunc{
 		// websocket
-		{constant.Reg_Socket, emby.ProxySocket()},
+		routes.Socket: emby.ProxySocket(),
 
 		// PlaybackInfo 接口
-		{constant.Reg_PlaybackInfo, emby.TransferPlaybackInfo},
+		routes.PlaybackInfo: emby.TransferPlaybackInfo,
 
 		// 播放停止时, 辅助请求 Progress 记录进度
-		{constant.Reg_PlayingStopped, emby.PlayingStoppedHelper},
+		routes.PlayingStopped: emby.PlayingStoppedHelper,
 		// 拦截无效的进度报告
-		{constant.Reg_PlayingProgress, emby.PlayingProgressHelper},
+		routes.PlayingProgress: emby.PlayingProgressHelper,
 
 		// 自定义媒体库数量统计
-		{constant.Reg_ItemsCounts, emby.HandleItemsCounts},
+		routes.ItemsCounts: emby.HandleItemsCounts,
 
 		// Items 接口
-		{constant.Reg_UserItems, emby.LoadCacheItems},
+		routes.UserItems: emby.LoadCacheItems,
+		// 媒体库虚拟化, 隐藏、重命名、合并媒体库
+		routes.UserViews:        emby.ProxyUserViews,
+		routes.UserLibraryItems: emby.ProxyLibraryItems,
+		routes.UserSearchItems:  emby.ProxySearchItems,
+		routes.SearchHints:      emby.ProxySearchItems,
+		routes.UserResumeItems:  emby.ProxyResumeItems,
 		// 代理 Items 并添加转码版本信息
-		{constant.Reg_UserEpisodeItems, emby.ProxyAddItemsPreviewInfo},
+		routes.UserEpisodeItems: emby.ProxyAddItemsPreviewInfo,
 		// 代理 Latest 接口, 解码媒体的 Path 字段
-		{constant.Reg_UserLatestItems, emby.ProxyLatestItems},
+		routes.UserLatestItems: emby.ProxyLatestItems,
 
 		// 字幕长时间缓存
-		{constant.Reg_VideoSubtitles, emby.ProxySubtitles},
+		routes.VideoSubtitles: emby.ProxySubtitles,
 
 		// 资源重定向到直链
-		{constant.Reg_ResourceStream, emby.Redirect2OpenlistLink},
+		routes.ResourceStream: emby.Redirect2OpenlistLink,
 		// 处理 original 资源
-		{constant.Reg_ResourceOriginal, emby.ProxyOriginalResource},
+		routes.ResourceOriginal: emby.ProxyOriginalResource,
 
 		// 资源下载, 重定向到直链
-		{constant.Reg_ItemDownload, emby.Redirect2OpenlistLink},
-		{constant.Reg_ItemSyncDownload, emby.HandleSyncDownload},
+		routes.ItemDownload:     emby.Redirect2OpenlistLink,
+		routes.ItemSyncDownload: emby.HandleSyncDownload,
 
 		// 处理图片请求
-		{constant.Reg_Images, emby.HandleImages},
+		routes.Images: emby.HandleImages,
 
 		// web cors 处理
-		{constant.Reg_VideoModWebDefined, emby.ChangeBaseVideoModuleCorsDefined},
+		routes.VideoModWebDefined: emby.ChangeBaseVideoModuleCorsDefined,
+
+		// 代理签发的一次性链接
+		routes.ProxyLink: emby.ServeLinkToken,
 
 		// 根路径重定向到首页
-		{constant.Reg_Root, emby.ProxyRoot},
+		routes.Root: emby.ProxyRoot,
 
 		// 其余资源走重定向回源
-		{constant.Reg_All, emby.ProxyOrigin},
-	})
+		routes.All: emby.ProxyOrigin,
+	}
+	for _, r := range routes.Builtins {
+		if _, ok := builtinHandlers[r.Id]; !ok {
+			panic(fmt.Sprintf("内置路由 [%s] 缺少处理器", r.Id))
+		}
+	}
+
+	loadCustomRules()
 	logs.Success("路由规则初始化完成")
 }
 
+// loadCustomRules 编译配置中的自定义路由规则, 替换当前生效的规则
+func loadCustomRules() {
+	rs := compileRules(customRules())
+	customRouteRules.Store(&rs)
+	if len(rs) > 0 {
+		logs.Info("已加载 %d 条自定义路由规则", len(rs))
+	}
+}
+
 // initRoutes 初始化路由
 func initRoutes(r *gin.Engine) {
 	r.Any("/*vars", globalDftHandler)
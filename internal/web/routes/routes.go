@@ -0,0 +1,254 @@
+package routes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GinKey 路由匹配结果存放在 gin 上下文中的 key
+const GinKey = "routeId"
+
+// Id 路由标识
+type Id string
+
+const (
+	Socket             Id = "socket"
+	PlaybackInfo       Id = "playback-info"
+	PlayingStopped     Id = "playing-stopped"
+	PlayingProgress    Id = "playing-progress"
+	ItemsCounts        Id = "items-counts"
+	UserItems          Id = "user-items"
+	UserViews          Id = "user-views"
+	UserLibraryItems   Id = "user-library-items"
+	UserEpisodeItems   Id = "user-episode-items"
+	UserLatestItems    Id = "user-latest-items"
+	UserResumeItems    Id = "user-resume-items"
+	UserSearchItems    Id = "user-search-items"
+	SearchHints        Id = "search-hints"
+	VideoSubtitles     Id = "video-subtitles"
+	ResourceStream     Id = "resource-stream"
+	ResourceOriginal   Id = "resource-original"
+	ItemDownload       Id = "item-download"
+	ItemSyncDownload   Id = "item-sync-download"
+	Images             Id = "images"
+	VideoModWebDefined Id = "video-mod-web-defined"
+	ProxyLink          Id = "proxy-link"
+	Root               Id = "root"
+	All                Id = "all"
+)
+
+// Route 路由规则
+type Route struct {
+	// Id 路由标识
+	Id Id
+	// Pattern 匹配规范化 uri 的正则, 规范化 uri 为小写且去除了 /emby 前缀
+	Pattern string
+	// Segments 规范化 uri 的第一级路径, 为空表示可能出现在任意路径下
+	Segments []string
+	// Raw 是否匹配未去除前缀的小写 uri
+	Raw bool
+
+	// reg 编译后的正则
+	reg *regexp.Regexp
+}
+
+// Builtins 内置路由规则, 按顺序匹配
+var Builtins = []Route{
+	{Id: Socket, Pattern: `^/(socket|embywebsocket)`, Segments: []string{"socket", "embywebsocket"}},
+	{Id: PlaybackInfo, Pattern: `^/items/[^?]*/playbackinfo`, Segments: []string{"items"}},
+	{Id: PlayingStopped, Pattern: `^/sessions/playing/stopped`, Segments: []string{"sessions"}},
+	{Id: PlayingProgress, Pattern: `^/sessions/playing/progress`, Segments: []string{"sessions"}},
+	{Id: ItemsCounts, Pattern: `^/items/counts($|\?)`, Segments: []string{"items"}},
+	{Id: UserItems, Pattern: `^/users/[^/?]+/items/\d+($|\?)`, Segments: []string{"users"}},
+	{Id: UserViews, Pattern: `^/(users/[^/?]+/views|userviews)($|\?)`, Segments: []string{"users", "userviews"}},
+	{Id: UserLibraryItems, Pattern: `^/users/[^/?]+/items\?(.*&)?parentid=`, Segments: []string{"users"}},
+	{Id: UserSearchItems, Pattern: `^/users/[^/?]+/items\?(.*&)?searchterm=`, Segments: []string{"users"}},
+	{Id: UserEpisodeItems, Pattern: `^/users/[^/?]+/items\?.*includeitemtypes=(episode|movie)`, Segments: []string{"users"}},
+	{Id: UserLatestItems, Pattern: `^/users/[^/?]+/items/latest($|\?)`, Segments: []string{"users"}},
+	{Id: UserResumeItems, Pattern: `^/users/[^/?]+/items/resume($|\?)`, Segments: []string{"users"}},
+	{Id: SearchHints, Pattern: `^/search/hints($|\?)`, Segments: []string{"search"}},
+	{Id: VideoSubtitles, Pattern: `^/videos/[^?]*/subtitles`, Segments: []string{"videos"}},
+	{Id: ResourceStream, Pattern: `^/(videos|audio)/[^?]*/(stream|universal)(\.\w+)?($|\?)`, Segments: []string{"videos", "audio"}},
+	{Id: ResourceOriginal, Pattern: `^/(videos|audio)/[^?]*/original(\.\w+)?($|\?)`, Segments: []string{"videos", "audio"}},
+	{Id: ItemDownload, Pattern: `^/items/\d+/download($|\?)`, Segments: []string{"items"}},
+	{Id: ItemSyncDownload, Pattern: `^/sync/jobitems/\d+/file($|\?)`, Segments: []string{"sync"}},
+	{Id: Images, Pattern: `^(?:/[^?]*)?/images(/|$|\?)`},
+	{Id: VideoModWebDefined, Pattern: `^/web/modules/htmlvideoplayer/plugin\.js`, Segments: []string{"web"}, Raw: true},
+	{Id: ProxyLink, Pattern: `^/ge2o/link/[0-9a-f]+($|\?)`, Segments: []string{"ge2o"}, Raw: true},
+	{Id: Root, Pattern: `^/$`, Segments: []string{""}, Raw: true},
+	{Id: All, Pattern: `.*`},
+}
+
+// stripPrefixes 规范化时去除的路径前缀
+var stripPrefixes = []string{"/emby", "/mediabrowser"}
+
+// Matcher 预编译的路由匹配器
+//
+// uri 只做一次小写转换和前缀去除, 再根据第一级路径找到候选规则, 按原有顺序依次匹配
+type Matcher struct {
+	// index 第一级路径 => 候选规则
+	index map[string][]*Route
+	// fallback 第一级路径不在索引中时的候选规则
+	fallback []*Route
+	// byId 路由标识 => 规则
+	byId map[Id]*Route
+}
+
+// NewMatcher 编译路由规则
+func NewMatcher(rs []Route) (*Matcher, error) {
+	compiled := make([]*Route, 0, len(rs))
+	segments := map[string]struct{}{}
+	for _, r := range rs {
+		reg, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("路由 [%s] 正则编译失败: %v", r.Id, err)
+		}
+		r.reg = reg
+		compiled = append(compiled, &r)
+		for _, seg := range r.Segments {
+			segments[seg] = struct{}{}
+		}
+	}
+
+	m := &Matcher{index: make(map[string][]*Route, len(segments)), byId: make(map[Id]*Route, len(compiled))}
+	for _, r := range compiled {
+		m.byId[r.Id] = r
+		if len(r.Segments) == 0 {
+			m.fallback = append(m.fallback, r)
+		}
+	}
+	for seg := range segments {
+		for _, r := range compiled {
+			if len(r.Segments) == 0 || containsSeg(r.Segments, seg) {
+				m.index[seg] = append(m.index[seg], r)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Match 匹配请求 uri, 返回第一个匹配的路由规则
+func (m *Matcher) Match(uri string) (*Route, bool) {
+	lower, norm := Normalize(uri)
+	for _, r := range m.candidates(norm) {
+		if r.match(lower, norm) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// Matches 判断请求 uri 是否匹配指定的路由规则, 不考虑规则之间的顺序
+func (m *Matcher) Matches(id Id, uri string) bool {
+	lower, norm := Normalize(uri)
+	for _, r := range m.candidates(norm) {
+		if r.Id == id {
+			return r.match(lower, norm)
+		}
+	}
+	return false
+}
+
+// Get 根据路由标识获取规则
+func (m *Matcher) Get(id Id) *Route {
+	return m.byId[id]
+}
+
+// candidates 根据第一级路径获取候选规则
+func (m *Matcher) candidates(norm string) []*Route {
+	if rs, ok := m.index[firstSegment(norm)]; ok {
+		return rs
+	}
+	return m.fallback
+}
+
+// match 判断规则是否匹配
+func (r *Route) match(lower, norm string) bool {
+	if r.Raw {
+		return r.reg.MatchString(lower)
+	}
+	return r.reg.MatchString(norm)
+}
+
+// SubMatches 获取规则在 uri 上的分组匹配结果
+func (r *Route) SubMatches(uri string) []string {
+	lower, norm := Normalize(uri)
+	if r.Raw {
+		return r.reg.FindStringSubmatch(lower)
+	}
+	return r.reg.FindStringSubmatch(norm)
+}
+
+// Normalize 规范化请求 uri, 返回小写 uri 以及去除 /emby 等前缀后的 uri
+func Normalize(uri string) (lower, norm string) {
+	lower = strings.ToLower(uri)
+	for _, prefix := range stripPrefixes {
+		if !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		rest := lower[len(prefix):]
+		if rest == "" || rest[0] == '?' {
+			return lower, "/" + rest
+		}
+		if rest[0] == '/' {
+			return lower, rest
+		}
+	}
+	return lower, lower
+}
+
+// firstSegment 获取规范化 uri 的第一级路径
+func firstSegment(norm string) string {
+	seg := strings.TrimPrefix(norm, "/")
+	if idx := strings.IndexAny(seg, "/?"); idx != -1 {
+		seg = seg[:idx]
+	}
+	return seg
+}
+
+// containsSeg 判断路径列表中是否包含指定路径
+func containsSeg(segs []string, seg string) bool {
+	for _, s := range segs {
+		if s == seg {
+			return true
+		}
+	}
+	return false
+}
+
+// builtin 内置路由匹配器
+var builtin = func() *Matcher {
+	m, err := NewMatcher(Builtins)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}()
+
+// Match 使用内置规则匹配请求 uri
+func Match(uri string) (*Route, bool) {
+	return builtin.Match(uri)
+}
+
+// Matches 判断请求 uri 是否匹配指定的内置规则
+func Matches(id Id, uri string) bool {
+	return builtin.Matches(id, uri)
+}
+
+// Get 根据路由标识获取内置规则
+func Get(id Id) *Route {
+	return builtin.Get(id)
+}
+
+// IdOf 获取请求匹配到的路由标识
+func IdOf(c *gin.Context) Id {
+	if c == nil {
+		return ""
+	}
+	id, _ := c.Get(GinKey)
+	res, _ := id.(Id)
+	return res
+}
@@ -0,0 +1,164 @@
+package routes
+
+import (
+	"regexp"
+	"testing"
+)
+
+// TestMatch 测试内置路由匹配
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want Id
+	}{
+		{"/embywebsocket?api_key=1", Socket},
+		{"/emby/Items/123/PlaybackInfo?UserId=1", PlaybackInfo},
+		{"/Items/123/PlaybackInfo", PlaybackInfo},
+		{"/emby/Sessions/Playing/Stopped", PlayingStopped},
+		{"/emby/Sessions/Playing/Progress", PlayingProgress},
+		{"/emby/Items/Counts?UserId=1", ItemsCounts},
+		{"/emby/Users/abc/Items/123?X-Emby-Token=1", UserItems},
+		{"/emby/Users/abc/Views", UserViews},
+		{"/UserViews?UserId=abc", UserViews},
+		{"/emby/Users/abc/Items?ParentId=1&Limit=10", UserLibraryItems},
+		{"/emby/Users/abc/Items?IncludeItemTypes=Episode", UserEpisodeItems},
+		{"/emby/Users/abc/Items/Latest?Limit=16", UserLatestItems},
+		{"/emby/Users/abc/Items/Resume?Limit=12", UserResumeItems},
+		{"/emby/Users/abc/Items?SearchTerm=abc&IncludeItemTypes=Movie", UserSearchItems},
+		{"/emby/Users/abc/Items?ParentId=1&SearchTerm=abc", UserLibraryItems},
+		{"/emby/Search/Hints?SearchTerm=abc", SearchHints},
+		{"/emby/Videos/1/1/Subtitles/2/0/Stream.srt", VideoSubtitles},
+		{"/emby/videos/123/stream.mkv?MediaSourceId=1", ResourceStream},
+		{"/Audio/123/universal?api_key=1", ResourceStream},
+		{"/emby/Videos/123/original.mp4", ResourceOriginal},
+		{"/emby/Items/123/Download?api_key=1", ItemDownload},
+		{"/emby/Sync/JobItems/123/File", ItemSyncDownload},
+		{"/emby/Items/123/Images/Primary?tag=1", Images},
+		{"/emby/Users/abc/Images/Primary", Images},
+		{"/Images/Remote?imageUrl=http%3A%2F%2Fexample.com%2Fa.jpg", Images},
+		{"/emby/Images/Remote", Images},
+		{"/web/modules/htmlvideoplayer/plugin.js", VideoModWebDefined},
+		{"/ge2o/link/abc123", ProxyLink},
+		{"/", Root},
+		{"/emby/", All},
+		{"/emby/Users/abc/Items?EnableImages=true", All},
+		{"/emby/System/Info", All},
+		{"/embyfoo/Items/1/PlaybackInfo", All},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			route, ok := Match(tt.uri)
+			if !ok {
+				t.Fatalf("Match(%q) 未匹配任何路由", tt.uri)
+			}
+			if route.Id != tt.want {
+				t.Errorf("Match(%q) = %s, want %s", tt.uri, route.Id, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalize 测试 uri 规范化
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"/emby/Items/1", "/items/1"},
+		{"/emby", "/"},
+		{"/emby?a=1", "/?a=1"},
+		{"/MediaBrowser/Users/1", "/users/1"},
+		{"/embywebsocket", "/embywebsocket"},
+		{"/Items/1", "/items/1"},
+	}
+
+	for _, tt := range tests {
+		if _, got := Normalize(tt.uri); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+// TestMatches 测试指定路由匹配
+func TestMatches(t *testing.T) {
+	uri := "/emby/Users/abc/Items?ParentId=1&IncludeItemTypes=Episode"
+	if id := mustMatch(t, uri); id != UserLibraryItems {
+		t.Fatalf("Match(%q) = %s, want %s", uri, id, UserLibraryItems)
+	}
+	if !Matches(UserEpisodeItems, uri) {
+		t.Errorf("Matches(%s, %q) = false, want true", UserEpisodeItems, uri)
+	}
+	if Matches(ResourceStream, uri) {
+		t.Errorf("Matches(%s, %q) = true, want false", ResourceStream, uri)
+	}
+}
+
+func mustMatch(t *testing.T, uri string) Id {
+	t.Helper()
+	route, ok := Match(uri)
+	if !ok {
+		t.Fatalf("Match(%q) 未匹配任何路由", uri)
+	}
+	return route.Id
+}
+
+// benchUris 性能测试使用的请求 uri
+var benchUris = []string{
+	"/emby/Items/123456/PlaybackInfo?UserId=abcdef&StartTimeTicks=0&IsPlayback=true&AutoOpenLiveStream=true&MaxStreamingBitrate=140000000&X-Emby-Client=Emby%20Web&X-Emby-Device-Name=Chrome&X-Emby-Token=abcdef",
+	"/emby/videos/123456/stream.mkv?DeviceId=abc&MediaSourceId=mediasource_123&Static=true&PlaySessionId=abc&api_key=abcdef",
+	"/emby/Items/123456/Images/Primary?maxHeight=300&maxWidth=200&tag=abcdef&quality=90",
+	"/emby/Users/abcdef/Items?SortBy=SortName&SortOrder=Ascending&IncludeItemTypes=Series&Recursive=true&Fields=BasicSyncInfo&StartIndex=0&ParentId=123&EnableImageTypes=Primary&Limit=50",
+	"/emby/Sessions/Playing/Progress?X-Emby-Client=Emby%20Web&X-Emby-Token=abcdef",
+	"/emby/System/Info?X-Emby-Client=Emby%20Web&X-Emby-Device-Name=Chrome&X-Emby-Token=abcdef",
+}
+
+// legacyPatterns 改造前按顺序逐个匹配的路由正则
+var legacyPatterns = []string{
+	`(?i)^/.*(socket|embywebsocket)`,
+	`(?i)^/.*items/.*/playbackinfo\??`,
+	`(?i)^/.*sessions/playing/stopped`,
+	`(?i)^/.*sessions/playing/progress`,
+	`(?i)^/.*items/counts($|\?)`,
+	`(?i)^/.*users/.*/items/\d+($|\?)`,
+	`(?i)^/.*(users/[^/]+/views|userviews)($|\?)`,
+	`(?i)^/.*users/[^/]+/items\?(.*&)?parentid=`,
+	`(?i)^/.*users/.*/items\?.*includeitemtypes=(episode|movie)`,
+	`(?i)^/.*users/.*/items/latest($|\?)`,
+	`(?i)^/.*videos/.*/subtitles`,
+	`(?i)^/.*(videos|audio)/.*/(stream|universal)(\.\w+)?\??`,
+	`(?i)^/.*(videos|audio)/.*/original(\.\w+)?\??`,
+	`(?i)^/.*items/\d+/download($|\?)`,
+	`(?i)^/.*sync/jobitems/\d+/file($|\?)`,
+	`(?i)^/.*images`,
+	`(?i)^/web/modules/htmlvideoplayer/plugin.js`,
+	`(?i)^/ge2o/link/[0-9a-f]+($|\?)`,
+	`(?i)^/$`,
+	`.*`,
+}
+
+// BenchmarkLegacyScan 改造前的顺序正则匹配
+func BenchmarkLegacyScan(b *testing.B) {
+	regs := make([]*regexp.Regexp, 0, len(legacyPatterns))
+	for _, p := range legacyPatterns {
+		regs = append(regs, regexp.MustCompile(p))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		uri := benchUris[i%len(benchUris)]
+		for _, reg := range regs {
+			if reg.MatchString(uri) {
+				break
+			}
+		}
+	}
+}
+
+// BenchmarkMatch 预编译匹配器
+func BenchmarkMatch(b *testing.B) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Match(benchUris[i%len(benchUris)])
+	}
+}
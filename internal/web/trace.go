@@ -0,0 +1,34 @@
+package web
+
+import (
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/routes"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestTracer 链路追踪中间件, 为每个请求创建服务端 span
+//
+// 需要放在路由标记中间件之后, 以匹配的路由作为 span 名称,
+// 后续处理器通过 c.Request.Context() 创建子 span
+func requestTracer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := string(routes.IdOf(c))
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := traces.StartServer(c.Request.Context(), c.Request.Header, c.Request.Method+" "+route,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", c.Request.URL.Path),
+			attribute.String("client.address", c.ClientIP()),
+		)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		traces.SetStatusCode(span, c.Writer.Status())
+		span.End()
+	}
+}
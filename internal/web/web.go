@@ -2,26 +2,34 @@ package web
 
 import (
 	"crypto/tls"
+	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/emby"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/accesslog"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/cache"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Listen 监听指定端口
+// Listen 监听指定端口, 任意一个服务异常退出时返回错误
 func Listen() error {
 	initRulePatterns()
+	if config.C().Network.TrustAllProxies() {
+		logs.Warn("未配置 network.trusted-cidrs, 将信任所有来源传递的客户端 ip, 客户端可以伪造 X-Forwarded-For 绕过限流和 ip 绑定, 建议配置为反向代理和局域网所在网段")
+	}
+	if config.C().Admin.Enable {
+		go listenAdmin()
+	}
 
 	errChanHTTP, errChanHTTPS := make(chan error, 1), make(chan error, 1)
-	if !config.C.Ssl.Enable {
+	if !config.C().Ssl.Enable {
 		go listenHTTP(errChanHTTP)
-	} else if config.C.Ssl.SinglePort {
+	} else if config.C().Ssl.SinglePort {
 		go listenHTTPS(errChanHTTPS)
 	} else {
 		go listenHTTP(errChanHTTP)
@@ -30,30 +38,52 @@ func Listen() error {
 
 	select {
 	case err := <-errChanHTTP:
-		log.Fatal("http 服务异常: ", err)
+		return fmt.Errorf("http 服务异常: %v", err)
 	case err := <-errChanHTTPS:
-		log.Fatal("https 服务异常: ", err)
+		return fmt.Errorf("https 服务异常: %v", err)
 	}
-	return nil
 }
 
 // initRouter 初始化路由引擎
 func initRouter(r *gin.Engine) {
+	r.Use(emby.InternalClientIpRestorer())
 	r.Use(referrerPolicySetter())
+	r.Use(customRewriter())
+	r.Use(routeMarker())
+	r.Use(requestTracer())
+	r.Use(requestMetrics())
+	if al := config.C().AccessLog; al.Enable {
+		r.Use(accesslog.Middleware(al.Writer(), al.Format, MatchRouteKey))
+	}
+	if config.C().RateLimit.Enable {
+		r.Use(rateLimiter())
+	}
 	r.Use(emby.ApiKeyChecker())
 	r.Use(emby.DownloadStrategyChecker())
-	if config.C.Cache.Enable {
+	if config.C().Cache.Enable {
 		r.Use(cache.CacheableRouteMarker())
+		r.Use(emby.CacheKeyTagger())
 		r.Use(cache.RequestCacher())
 	}
+	// 改写规则支持热重载, 始终注册, 没有匹配的规则时直接放行
+	r.Use(responseRewriter())
 	initRoutes(r)
 }
 
+// newEngine 初始化路由引擎, 只信任可信网段中的代理传递的客户端 ip
+func newEngine() *gin.Engine {
+	r := gin.New()
+	if err := r.SetTrustedProxies(config.C().Network.TrustedProxies()); err != nil {
+		log.Fatal("设置可信代理失败: ", err)
+	}
+	return r
+}
+
 // listenHTTP 在指定端口上监听 http 服务
 //
 // 出现错误时, 会写入 errChan 中
 func listenHTTP(errChan chan error) {
-	r := gin.New()
+	r := newEngine()
 	r.Use(gin.Recovery())
 	r.Use(CustomLogger(webport.HTTP))
 	r.Use(func(c *gin.Context) {
@@ -70,7 +100,7 @@ func listenHTTP(errChan chan error) {
 //
 // 出现错误时, 会写入 errChan 中
 func listenHTTPS(errChan chan error) {
-	r := gin.New()
+	r := newEngine()
 	r.Use(gin.Recovery())
 	r.Use(CustomLogger(webport.HTTPS))
 	r.Use(func(c *gin.Context) {
@@ -78,7 +108,7 @@ func listenHTTPS(errChan chan error) {
 	})
 	initRouter(r)
 	logs.Info("在端口【%s】上启动 HTTPS 服务", webport.HTTPS)
-	ssl := config.C.Ssl
+	ssl := config.C().Ssl
 
 	srv := &http.Server{
 		Addr:    "0.0.0.0:" + webport.HTTPS,
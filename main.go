@@ -1,20 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
+	"syscall"
+	"time"
 
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/cli"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/config"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/constant"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/analytics"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/service/openlist/localtree"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/logs/colors"
+	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/util/traces"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web"
 	"github.com/AmbitiousJun/go-emby2openlist/v2/internal/web/webport"
 	"github.com/gin-gonic/gin"
@@ -22,7 +29,14 @@ import (
 
 var ginMode = gin.DebugMode
 
+// shutdownTimeout 程序退出前清理资源的超时时间
+const shutdownTimeout = 5 * time.Second
+
 func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1], os.Args[2:]))
+	}
+
 	go func() { http.ListenAndServe(":60360", nil) }()
 
 	dataRoot := parseFlag()
@@ -33,15 +47,45 @@ func main() {
 
 	printBanner()
 
+	if err := config.Watch(); err != nil {
+		logs.Warn("配置文件热重载不可用: %v", err)
+	}
+
 	logs.Info("正在初始化本地目录树模块...")
 	if err := localtree.Init(); err != nil {
 		log.Fatal(colors.ToRed(err.Error()))
 	}
 
+	if err := analytics.Init(); err != nil {
+		log.Fatal(colors.ToRed(err.Error()))
+	}
+
 	logs.Info("正在启动服务...")
 	gin.SetMode(ginMode)
-	if err := web.Listen(); err != nil {
+	errChan := make(chan error, 1)
+	go func() { errChan <- web.Listen() }()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-errChan:
+		shutdown()
 		log.Fatal(colors.ToRed(err.Error()))
+	case sig := <-sigChan:
+		logs.Info("收到 %v 信号, 正在退出...", sig)
+		shutdown()
+	}
+}
+
+// shutdown 程序退出前, 上报剩余的链路追踪数据, 并写入等待中的播放统计
+func shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := traces.Shutdown(ctx); err != nil {
+		logs.Warn("关闭链路追踪失败: %v", err)
+	}
+	if err := analytics.Close(); err != nil {
+		logs.Warn("关闭播放统计失败: %v", err)
 	}
 }
 